@@ -0,0 +1,124 @@
+package api
+
+import (
+	"fmt"
+	"gotracker/auth"
+	"gotracker/commands"
+	"net/http"
+	"time"
+)
+
+// Session is the result of login and register. The token is sent in the
+// Authorization header of the other requests, as "Bearer <token>".
+//
+//	{"token": "gtk_...", "expires_at": "2024-02-01T12:00:00Z", "user": {"id": 1, "username": "john", ...}}
+type Session struct {
+	Token     string        `json:"token"`
+	ExpiresAt time.Time     `json:"expires_at"`
+	User      commands.User `json:"user"`
+}
+
+// LoginTokenName names the tokens given by login and register, which have the admin scope
+const LoginTokenName = "login"
+
+// command runs a command line as the user of the request and returns its result
+func (s *Server) command(ctx *commands.Context, words ...string) (interface{}, error) {
+	result, err := commands.NewRegistry(ctx).Run(words)
+	if err != nil {
+		return nil, err
+	}
+	return result.Data, nil
+}
+
+// issue creates a token for the user of the request
+func (s *Server) issue(ctx *commands.Context) (Session, error) {
+	token, hash, err := auth.NewToken()
+	if err != nil {
+		return Session{}, &Error{Status: http.StatusInternalServerError, Message: fmt.Sprintf("failed to create token: %v", err)}
+	}
+	expiresAt := time.Now().Add(s.TokenTTL).UTC().Truncate(time.Second)
+	if _, err := s.Store.CreateAPIToken(ctx.Session.User.ID, LoginTokenName, hash, []string{auth.ScopeAdmin}, expiresAt); err != nil {
+		return Session{}, err
+	}
+	user, err := s.command(ctx, "whoami")
+	if err != nil {
+		return Session{}, err
+	}
+	return Session{Token: token, ExpiresAt: expiresAt, User: user.(commands.User)}, nil
+}
+
+// login checks the password of a user, with the lockout of the CLI, and returns a token
+func login(s *Server, ctx *commands.Context, r *http.Request, route route, fields map[string]string) (interface{}, error) {
+	ctx.ReadPassword = func(prompt string) (string, error) {
+		return fields["password"], nil
+	}
+	if err := commands.Login(ctx, fields["login"]); err != nil {
+		if statusOf(err) == http.StatusInternalServerError {
+			return nil, err
+		}
+		return nil, &Error{Status: http.StatusUnauthorized, Message: err.Error()}
+	}
+	return s.issue(ctx)
+}
+
+// register creates a user with the password of the request and returns a token
+func register(s *Server, ctx *commands.Context, r *http.Request, route route, fields map[string]string) (interface{}, error) {
+	ctx.ReadPassword = func(prompt string) (string, error) {
+		return fields["password"], nil
+	}
+	if _, err := s.run(ctx, route, fields); err != nil {
+		return nil, err
+	}
+	return s.issue(ctx)
+}
+
+// passwordReset sets the password of a user with a reset token and returns a token
+func passwordReset(s *Server, ctx *commands.Context, r *http.Request, route route, fields map[string]string) (interface{}, error) {
+	if fields["token"] == "" {
+		return nil, s.errorf(ctx, http.StatusBadRequest, "missing field '%s'", "token")
+	}
+	ctx.ReadPassword = func(prompt string) (string, error) {
+		return fields["password"], nil
+	}
+	if _, err := s.run(ctx, route, fields); err != nil {
+		return nil, err
+	}
+	return s.issue(ctx)
+}
+
+// logout revokes the token of the request and closes its session
+func logout(s *Server, ctx *commands.Context, r *http.Request, route route, fields map[string]string) (interface{}, error) {
+	token, _ := bearerToken(r)
+	s.Sessions.Close(ctx.Session)
+	return nil, s.Store.DeleteAPIToken(auth.HashToken(token))
+}
+
+// updatableFields returns the fields of PATCH /me: the subcommands of update
+// taking the new value of the field they are named after
+func updatableFields() []field {
+	registry := commands.NewRegistry(&commands.Context{Session: &commands.Session{}})
+	update, _ := registry.Lookup("update")
+	var fields []field
+	for _, sub := range update.Subcommands {
+		if len(sub.Args) == 1 && sub.Args[0].Name == sub.Name {
+			fields = append(fields, field{Name: sub.Name, Type: sub.Args[0].Type, Usage: sub.Summary})
+		}
+	}
+	return fields
+}
+
+// updateMe runs the update subcommand of each field of the request, in the
+// order of the fields, and returns the updated user
+func updateMe(s *Server, ctx *commands.Context, r *http.Request, route route, fields map[string]string) (interface{}, error) {
+	registry := commands.NewRegistry(ctx)
+	for _, param := range route.params {
+		value, ok := fields[param.Name]
+		if !ok {
+			continue
+		}
+		if _, err := registry.Run([]string{"update", param.Name, "--", value}); err != nil {
+			return nil, err
+		}
+	}
+	return s.command(ctx, "whoami")
+}
@@ -0,0 +1,241 @@
+package api
+
+import (
+	"gotracker/cli"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version is the version of the API in its OpenAPI document
+const Version = "1.0.0"
+
+// object is a JSON object of the OpenAPI document
+type object = map[string]interface{}
+
+// OpenAPI returns the OpenAPI 3 document of the API, generated from the
+// routes, the arguments and flags of their commands and their results
+func OpenAPI() object {
+	g := &generator{schemas: object{}}
+	g.schemas["Error"] = g.structSchema(reflect.TypeOf(ErrorBody{}))
+	paths := object{
+		"/openapi.json": object{
+			"get": object{
+				"summary":     "Get this OpenAPI document",
+				"operationId": "get_openapi",
+				"security":    []interface{}{},
+				"responses": object{
+					"200": object{"description": "The OpenAPI document", "content": jsonContent(object{"type": "object"})},
+				},
+			},
+		},
+	}
+	for _, route := range routes {
+		path, ok := paths[route.Path].(object)
+		if !ok {
+			path = object{}
+			paths[route.Path] = path
+		}
+		path[strings.ToLower(route.Method)] = g.operation(route)
+	}
+	return object{
+		"openapi": "3.0.3",
+		"info": object{
+			"title":       "GoTracker API",
+			"version":     Version,
+			"description": "Food, weight and activity tracking. Login with POST /login and send the token as 'Authorization: Bearer <token>', or use a personal access token of 'token create' limited to its scopes: history:read, log:write, meals:write or admin. Error messages follow the Accept-Language header, en or fr.",
+		},
+		"servers":  []interface{}{object{"url": Prefix}},
+		"security": []interface{}{object{"bearerAuth": []interface{}{}}},
+		"paths":    paths,
+		"components": object{
+			"securitySchemes": object{"bearerAuth": object{"type": "http", "scheme": "bearer"}},
+			"schemas":         g.schemas,
+		},
+	}
+}
+
+// generator builds the schemas of the results, each struct once under components
+type generator struct {
+	schemas object
+}
+
+// operation returns the OpenAPI operation of a route
+func (g *generator) operation(route route) object {
+	operation := object{
+		"summary":     route.Summary,
+		"operationId": operationID(route),
+	}
+	var parameters []interface{}
+	properties := object{}
+	var required []string
+	for _, param := range route.params {
+		schema := object{"type": jsonType(param.Type)}
+		inPath := strings.Contains(route.Path, "{"+param.Name+"}")
+		switch {
+		case inPath || route.Method == http.MethodGet || route.Method == http.MethodDelete:
+			in := "query"
+			if inPath {
+				in = "path"
+			}
+			parameter := object{"name": param.Name, "in": in, "required": param.Required || inPath, "schema": schema}
+			if param.Usage != "" {
+				parameter["description"] = param.Usage
+			}
+			parameters = append(parameters, parameter)
+		default:
+			if param.Usage != "" {
+				schema["description"] = param.Usage
+			}
+			properties[param.Name] = schema
+			if param.Required {
+				required = append(required, param.Name)
+			}
+		}
+	}
+	if route.Paginate {
+		parameters = append(parameters,
+			object{"name": "page", "in": "query", "schema": object{"type": "integer", "minimum": 1, "default": 1}},
+			object{"name": "per_page", "in": "query", "schema": object{"type": "integer", "minimum": 1, "maximum": MaxPerPage, "default": DefaultPerPage}},
+		)
+	}
+	if len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+	if len(properties) > 0 {
+		body := object{"type": "object", "properties": properties, "additionalProperties": false}
+		if len(required) > 0 {
+			body["required"] = required
+		}
+		operation["requestBody"] = object{"required": len(required) > 0, "content": jsonContent(body)}
+	}
+	if route.Public {
+		operation["security"] = []interface{}{}
+	}
+	if route.Scope != "" {
+		operation["description"] = "Needs a token with the scope '" + route.Scope + "' or admin."
+		operation["x-scope"] = route.Scope
+	}
+
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := object{"description": http.StatusText(status)}
+	if route.Result != nil {
+		schema := g.schema(reflect.TypeOf(route.Result))
+		if route.Paginate {
+			schema = object{
+				"type": "object",
+				"properties": object{
+					"items":    schema,
+					"page":     object{"type": "integer"},
+					"per_page": object{"type": "integer"},
+					"total":    object{"type": "integer"},
+				},
+				"required": []string{"items", "page", "per_page", "total"},
+			}
+		}
+		success["content"] = jsonContent(schema)
+	}
+	operation["responses"] = object{
+		strconv.Itoa(status): success,
+		"default":            object{"description": "Error", "content": jsonContent(object{"$ref": "#/components/schemas/Error"})},
+	}
+	return operation
+}
+
+// schema returns the schema of a type, a reference for the structs
+func (g *generator) schema(t reflect.Type) object {
+	switch t.Kind() {
+	case reflect.Ptr:
+		schema := g.schema(t.Elem())
+		if _, isRef := schema["$ref"]; isRef {
+			return object{"allOf": []interface{}{schema}, "nullable": true}
+		}
+		schema["nullable"] = true
+		return schema
+	case reflect.Struct:
+		if t == reflect.TypeOf(time.Time{}) {
+			return object{"type": "string", "format": "date-time"}
+		}
+		if _, ok := g.schemas[t.Name()]; !ok {
+			g.schemas[t.Name()] = object{}
+			g.schemas[t.Name()] = g.structSchema(t)
+		}
+		return object{"$ref": "#/components/schemas/" + t.Name()}
+	case reflect.Slice, reflect.Array:
+		return object{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return object{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.String:
+		return object{"type": "string"}
+	case reflect.Bool:
+		return object{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return object{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return object{"type": "number"}
+	}
+	return object{}
+}
+
+// structSchema returns the schema of the JSON fields of a struct, the fields
+// without omitempty are required
+func (g *generator) structSchema(t reflect.Type) object {
+	properties := object{}
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = g.schema(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	schema := object{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// jsonType returns the JSON type of the values of an argument type
+func jsonType(argType cli.ArgType) string {
+	switch argType {
+	case cli.Int:
+		return "integer"
+	case cli.Float:
+		return "number"
+	case cli.Bool:
+		return "boolean"
+	}
+	return "string"
+}
+
+// jsonContent returns the content of a JSON body with the given schema
+func jsonContent(schema object) object {
+	return object{"application/json": object{"schema": schema}}
+}
+
+// operationID names an operation after its method and path, e.g. get_meals_meal_id
+func operationID(route route) string {
+	id := strings.ToLower(route.Method)
+	for _, part := range strings.FieldsFunc(route.Path, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_')
+	}) {
+		id += "_" + part
+	}
+	return id
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"gotracker/commands"
+	"gotracker/i18n"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// fields returns the fields of a request by name: the path values, then the
+// members of the JSON body, then the query parameters. Fields the route does
+// not take are refused, as are missing required fields and the query of the
+// BodyOnly routes.
+func (s *Server) fields(ctx *commands.Context, r *http.Request, route route) (map[string]string, error) {
+	fields := make(map[string]string)
+	for name, values := range r.URL.Query() {
+		if route.Paginate && (name == "page" || name == "per_page") {
+			continue
+		}
+		if route.BodyOnly {
+			return nil, s.errorf(ctx, http.StatusBadRequest, "the field '%s' must be sent in the JSON body, not in the query", name)
+		}
+		fields[name] = values[len(values)-1]
+	}
+	body, err := s.body(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range body {
+		fields[name] = value
+	}
+	for _, param := range route.params {
+		if value := r.PathValue(param.Name); value != "" {
+			fields[param.Name] = value
+		}
+	}
+
+	for name := range fields {
+		if _, ok := route.param(name); !ok {
+			return nil, s.errorf(ctx, http.StatusBadRequest, "unknown field '%s'", name)
+		}
+	}
+	for _, param := range route.params {
+		if _, ok := fields[param.Name]; param.Required && !ok {
+			return nil, s.errorf(ctx, http.StatusBadRequest, "missing field '%s'", param.Name)
+		}
+	}
+	return fields, nil
+}
+
+// body returns the members of the JSON object of the request body, nil when
+// there is no body. The members must be strings, numbers, booleans or null,
+// null members are left out.
+func (s *Server) body(ctx *commands.Context, r *http.Request) (map[string]string, error) {
+	data, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, maxBodySize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, s.errorf(ctx, http.StatusRequestEntityTooLarge, "the request body is larger than %d bytes", maxBodySize)
+		}
+		return nil, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		return nil, s.errorf(ctx, http.StatusUnsupportedMediaType, "the request body must be JSON, with the Content-Type application/json")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var members map[string]interface{}
+	if err := decoder.Decode(&members); err != nil || members == nil || decoder.More() {
+		return nil, s.errorf(ctx, http.StatusBadRequest, "the request body must be a JSON object")
+	}
+	body := make(map[string]string, len(members))
+	for name, member := range members {
+		switch value := member.(type) {
+		case nil:
+		case string:
+			body[name] = value
+		case json.Number:
+			body[name] = value.String()
+		case bool:
+			if value {
+				body[name] = "true"
+			} else {
+				body[name] = "false"
+			}
+		default:
+			return nil, s.errorf(ctx, http.StatusBadRequest, "the field '%s' must be a string, a number or a boolean", name)
+		}
+	}
+	return body, nil
+}
+
+// language returns the first supported language of the Accept-Language
+// header of a request, empty to use the preference of the user
+func language(r *http.Request) i18n.Lang {
+	for _, tag := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, _, _ = strings.Cut(tag, ";")
+		if lang, err := i18n.Parse(strings.TrimSpace(tag)); err == nil {
+			return lang
+		}
+	}
+	return ""
+}
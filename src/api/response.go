@@ -0,0 +1,145 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gotracker/cli"
+	"gotracker/commands"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/lib/pq"
+)
+
+// Default and largest number of entries of a page
+const (
+	DefaultPerPage = 50
+	MaxPerPage     = 200
+)
+
+// Error is an error answered with an HTTP status
+type Error struct {
+	Status  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// errorf returns an error answered with status, the message is in the language of the request
+func (s *Server) errorf(ctx *commands.Context, status int, format string, a ...interface{}) error {
+	return &Error{Status: status, Message: ctx.Sprintf(format, a...)}
+}
+
+// ErrorBody is the body of the errors.
+//
+//	{"error": "missing field 'quantity'"}
+type ErrorBody struct {
+	Error string `json:"error"`
+}
+
+// Page is a page of a list, total counts the entries of all the pages.
+//
+//	{"items": [...], "page": 1, "per_page": 50, "total": 120}
+type Page struct {
+	Items   interface{} `json:"items"`
+	Page    int         `json:"page"`
+	PerPage int         `json:"per_page"`
+	Total   int         `json:"total"`
+}
+
+// writeJSON writes data as the JSON body of a response
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	if status == http.StatusNoContent {
+		w.WriteHeader(status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(data)
+}
+
+// fail answers a request with an error. The internal errors are logged and
+// answered with a generic message, the others with their message in the
+// language of the request.
+func (s *Server) fail(w http.ResponseWriter, r *http.Request, ctx *commands.Context, err error) {
+	status := statusOf(err)
+	message := ctx.Translate(err.Error())
+	if status == http.StatusInternalServerError {
+		fmt.Fprintf(s.Log, "%s %s: %v\n", r.Method, r.URL.Path, err)
+		message = ctx.Translate("internal server error")
+	}
+	if status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="gotracker"`)
+	}
+	writeJSON(w, status, ErrorBody{Error: message})
+}
+
+// statusOf returns the HTTP status of an error. The commands refuse invalid
+// requests with plain errors, the errors of the database and the network are
+// internal.
+func statusOf(err error) int {
+	var apiErr *Error
+	var usageErr *cli.UsageError
+	var unknownErr *cli.UnknownCommandError
+	var pqErr *pq.Error
+	var netErr *net.OpError
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr.Status
+	case errors.As(err, &usageErr), errors.As(err, &unknownErr):
+		return http.StatusBadRequest
+	case errors.Is(err, commands.ErrNotLoggedIn), errors.Is(err, commands.ErrInvalidCredentials), errors.Is(err, commands.ErrInvalidResetToken):
+		return http.StatusUnauthorized
+	case errors.Is(err, commands.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return http.StatusServiceUnavailable
+	case errors.As(err, &pqErr), errors.As(err, &netErr), errors.Is(err, driver.ErrBadConn), errors.Is(err, sql.ErrConnDone):
+		return http.StatusInternalServerError
+	}
+	return http.StatusBadRequest
+}
+
+// paginate returns the page of a list asked by the page and per_page query parameters
+func (s *Server) paginate(ctx *commands.Context, r *http.Request, data interface{}) (interface{}, error) {
+	page, err := queryInt(r, "page", 1)
+	if err != nil || page < 1 {
+		return nil, s.errorf(ctx, http.StatusBadRequest, "invalid page '%s', expected a number from 1", r.URL.Query().Get("page"))
+	}
+	perPage, err := queryInt(r, "per_page", DefaultPerPage)
+	if err != nil || perPage < 1 || perPage > MaxPerPage {
+		return nil, s.errorf(ctx, http.StatusBadRequest, "invalid per_page '%s', expected a number from 1 to %d", r.URL.Query().Get("per_page"), MaxPerPage)
+	}
+
+	list := reflect.ValueOf(data)
+	if list.Kind() != reflect.Slice {
+		return nil, &Error{Status: http.StatusInternalServerError, Message: fmt.Sprintf("cannot paginate a %T", data)}
+	}
+	start := min((page-1)*perPage, list.Len())
+	end := min(start+perPage, list.Len())
+	items := reflect.MakeSlice(list.Type(), 0, end-start)
+	items = reflect.AppendSlice(items, list.Slice(start, end))
+	return Page{Items: items.Interface(), Page: page, PerPage: perPage, Total: list.Len()}, nil
+}
+
+// queryInt returns the integer query parameter of a request, fallback when it is not set
+func queryInt(r *http.Request, name string, fallback int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(value)
+}
@@ -0,0 +1,207 @@
+package api
+
+import (
+	"gotracker/auth"
+	"gotracker/cli"
+	"gotracker/commands"
+	"net/http"
+	"strings"
+)
+
+// route maps a method and a path to a command of the table. The arguments
+// and flags of the command are the fields of the request, taken from the
+// path, the JSON body and the query. Run replaces running the command for
+// the routes doing more, Fields lists the fields they take besides the ones
+// of the command. The token of the request needs Scope, any token is enough
+// when it is empty. The fields of a BodyOnly route, which take passwords or
+// tokens, are only read from the body, the URLs being kept in the logs.
+type route struct {
+	Method   string
+	Path     string
+	Command  string
+	Summary  string      // Summary of the routes without command
+	Fields   []field     // Fields of Run besides the ones of the command
+	Result   interface{} // Example of the result, for the OpenAPI document
+	Status   int         // Status of a success, 200 when 0
+	Paginate bool        // The result is a list split in pages
+	Public   bool        // Served without a token
+	BodyOnly bool        // The fields are refused in the query
+	Scope    string      // Scope the token of the request needs
+	Run      func(s *Server, ctx *commands.Context, r *http.Request, route route, fields map[string]string) (interface{}, error)
+
+	params []field // Fields of the command followed by Fields
+}
+
+// fieldKind tells how a field is passed to the command of a route
+type fieldKind int
+
+const (
+	argField   fieldKind = iota // A positional argument of the command
+	flagField                   // A flag of the command
+	extraField                  // Not passed to the command, read by Run
+)
+
+// field is a field of a request
+type field struct {
+	Name     string
+	Type     cli.ArgType
+	Required bool
+	Usage    string
+	kind     fieldKind
+}
+
+// routes are the routes of the API, under Prefix
+var routes = []route{
+	{Method: "POST", Path: "/login", Summary: "Login with a username or email and a password and get a token", Fields: []field{
+		{Name: "login", Required: true, Usage: "Username or email"},
+		{Name: "password", Required: true},
+	}, Result: Session{}, Public: true, BodyOnly: true, Run: login},
+	{Method: "POST", Path: "/users", Command: "register", Fields: []field{{Name: "password", Required: true}}, Result: Session{}, Status: http.StatusCreated, Public: true, BodyOnly: true, Run: register},
+	{Method: "POST", Path: "/password-reset", Command: "reset", Fields: []field{{Name: "password", Required: true}}, Result: Session{}, Public: true, BodyOnly: true, Run: passwordReset},
+	{Method: "POST", Path: "/logout", Summary: "Revoke the token of the request", Status: http.StatusNoContent, Run: logout},
+
+	{Method: "GET", Path: "/me", Command: "whoami", Result: commands.User{}, Scope: auth.ScopeReadHistory},
+	{Method: "PATCH", Path: "/me", Summary: "Update the information of the logged in user", Fields: updatableFields(), Result: commands.User{}, Run: updateMe, Scope: auth.ScopeAdmin},
+
+	{Method: "GET", Path: "/weights", Command: "history weight", Result: []commands.Weight{}, Paginate: true, Scope: auth.ScopeReadHistory},
+	{Method: "POST", Path: "/weights", Command: "report weight", Result: commands.Weight{}, Status: http.StatusCreated, Scope: auth.ScopeWriteLog},
+	{Method: "GET", Path: "/imc", Command: "history imc", Result: []commands.IMCEntry{}, Paginate: true, Scope: auth.ScopeReadHistory},
+	{Method: "POST", Path: "/imc", Command: "report imc", Result: commands.IMC{}, Status: http.StatusCreated, Scope: auth.ScopeWriteLog},
+	{Method: "GET", Path: "/bodyfat", Command: "history bodyfat", Result: []commands.BodyFat{}, Paginate: true, Scope: auth.ScopeReadHistory},
+	{Method: "POST", Path: "/bodyfat", Command: "report bodyfat", Result: commands.BodyFat{}, Status: http.StatusCreated, Scope: auth.ScopeWriteLog},
+	{Method: "GET", Path: "/trend/weight", Command: "trend weight", Result: commands.WeightTrend{}, Scope: auth.ScopeReadHistory},
+
+	{Method: "GET", Path: "/food-history", Command: "history food", Result: []commands.FoodEntry{}, Paginate: true, Scope: auth.ScopeReadHistory},
+	{Method: "POST", Path: "/food-history", Command: "add food", Result: commands.FoodEntry{}, Status: http.StatusCreated, Scope: auth.ScopeWriteLog},
+	{Method: "PATCH", Path: "/food-history/{entry_id}", Command: "update food", Result: commands.FoodEntry{}, Scope: auth.ScopeWriteLog},
+	{Method: "DELETE", Path: "/food-history/{entry_id}", Command: "delete food", Result: commands.DeletedEntry{}, Scope: auth.ScopeWriteLog},
+	{Method: "POST", Path: "/undo", Command: "undo", Result: commands.Undone{}, Scope: auth.ScopeWriteLog},
+	{Method: "GET", Path: "/trash", Command: "trash list", Result: []commands.TrashItem{}, Paginate: true, Scope: auth.ScopeReadHistory},
+	{Method: "POST", Path: "/trash/{id}/restore", Command: "trash restore", Result: commands.TrashItem{}, Scope: auth.ScopeWriteLog},
+	{Method: "DELETE", Path: "/trash", Command: "trash purge", Result: commands.PurgedTrash{}, Scope: auth.ScopeAdmin},
+	{Method: "GET", Path: "/day-log", Command: "today", Result: commands.DayLog{}, Scope: auth.ScopeReadHistory},
+	{Method: "GET", Path: "/summary", Command: "summary", Result: commands.Summary{}, Scope: auth.ScopeReadHistory},
+
+	{Method: "GET", Path: "/meals", Command: "list meal", Result: []commands.Meal{}, Paginate: true, Scope: auth.ScopeReadHistory},
+	{Method: "POST", Path: "/meals", Command: "create meal", Result: commands.Meal{}, Status: http.StatusCreated, Scope: auth.ScopeManageMeals},
+	{Method: "GET", Path: "/meals/{meal_id}", Command: "show meal", Result: commands.MealDetails{}, Scope: auth.ScopeReadHistory},
+	{Method: "POST", Path: "/meals/{meal_id}/foods", Command: "link food_to_meal", Result: commands.MealFood{}, Status: http.StatusCreated, Scope: auth.ScopeManageMeals},
+	{Method: "DELETE", Path: "/meals/{meal_id}", Command: "delete meal", Result: commands.TrashItem{}, Scope: auth.ScopeManageMeals},
+	{Method: "POST", Path: "/meals/{meal_id}/log", Command: "add meal", Result: []commands.FoodEntry{}, Status: http.StatusCreated, Scope: auth.ScopeWriteLog},
+	{Method: "GET", Path: "/days", Command: "list day", Result: []commands.DayPreset{}, Paginate: true, Scope: auth.ScopeReadHistory},
+	{Method: "POST", Path: "/days", Command: "create day", Result: commands.DayPreset{}, Status: http.StatusCreated, Scope: auth.ScopeManageMeals},
+	{Method: "GET", Path: "/days/{day_id}", Command: "show day", Result: commands.DayDetails{}, Scope: auth.ScopeReadHistory},
+	{Method: "POST", Path: "/days/{day_id}/meals", Command: "link meal_to_day", Result: commands.DayMeal{}, Status: http.StatusCreated, Scope: auth.ScopeManageMeals},
+	{Method: "DELETE", Path: "/days/{day_id}", Command: "delete day", Result: commands.TrashItem{}, Scope: auth.ScopeManageMeals},
+	{Method: "POST", Path: "/days/{day_id}/log", Command: "add day", Result: []commands.FoodEntry{}, Status: http.StatusCreated, Scope: auth.ScopeWriteLog},
+
+	{Method: "GET", Path: "/targets", Command: "target show", Result: []commands.NutrientTarget{}, Scope: auth.ScopeReadHistory},
+	{Method: "PUT", Path: "/targets/{nutrient}", Command: "target set", Result: commands.NutrientTarget{}, Scope: auth.ScopeManageMeals},
+
+	{Method: "GET", Path: "/exercise", Command: "history exercise", Result: []commands.ExerciseEntry{}, Paginate: true, Scope: auth.ScopeReadHistory},
+	{Method: "POST", Path: "/exercise", Command: "log exercise", Result: commands.ExerciseEntry{}, Status: http.StatusCreated, Scope: auth.ScopeWriteLog},
+	{Method: "GET", Path: "/activities", Command: "list activity", Result: []commands.Activity{}, Scope: auth.ScopeReadHistory},
+	{Method: "GET", Path: "/water", Command: "history water", Result: []commands.WaterEntry{}, Paginate: true, Scope: auth.ScopeReadHistory},
+	{Method: "POST", Path: "/water", Command: "log water", Result: commands.WaterEntry{}, Status: http.StatusCreated, Scope: auth.ScopeWriteLog},
+
+	{Method: "GET", Path: "/foods", Command: "search food", Result: []commands.FoodMatch{}, Paginate: true, Scope: auth.ScopeReadHistory},
+	{Method: "GET", Path: "/foods/{food_id}", Command: "details", Result: commands.FoodDetails{}, Scope: auth.ScopeReadHistory},
+
+	{Method: "GET", Path: "/tokens", Command: "token list", Result: []commands.Token{}, Paginate: true, Scope: auth.ScopeAdmin},
+	{Method: "POST", Path: "/tokens", Command: "token create", Result: commands.Token{}, Status: http.StatusCreated, Scope: auth.ScopeAdmin},
+	{Method: "DELETE", Path: "/tokens/{token_id}", Command: "token revoke", Result: commands.RevokedToken{}, Scope: auth.ScopeAdmin},
+	{Method: "GET", Path: "/ssh-keys", Command: "sshkey list", Result: []commands.SSHKey{}, Paginate: true, Scope: auth.ScopeAdmin},
+	{Method: "POST", Path: "/ssh-keys", Command: "sshkey add", Result: commands.SSHKey{}, Status: http.StatusCreated, Scope: auth.ScopeAdmin},
+	{Method: "DELETE", Path: "/ssh-keys/{key_id}", Command: "sshkey remove", Result: commands.RemovedSSHKey{}, Scope: auth.ScopeAdmin},
+	{Method: "GET", Path: "/sessions", Command: "sessions", Result: []commands.SessionInfo{}, Scope: auth.ScopeAdmin},
+	{Method: "GET", Path: "/audit", Command: "audit", Result: []commands.AuditEntry{}, Paginate: true, Scope: auth.ScopeAdmin},
+
+	{Method: "GET", Path: "/clients", Command: "client list", Result: []commands.CoachLink{}, Paginate: true, Scope: auth.ScopeReadHistory},
+	{Method: "POST", Path: "/clients", Command: "client invite", Result: commands.CoachInvitation{}, Status: http.StatusCreated, Scope: auth.ScopeAdmin},
+	{Method: "DELETE", Path: "/clients/{client}", Command: "client remove", Result: commands.CoachInvitation{}, Scope: auth.ScopeAdmin},
+	{Method: "GET", Path: "/clients/{client}/summary", Command: "client summary", Result: commands.Summary{}, Scope: auth.ScopeReadHistory},
+	{Method: "GET", Path: "/clients/{client}/day-log", Command: "client today", Result: commands.DayLog{}, Scope: auth.ScopeReadHistory},
+	{Method: "GET", Path: "/clients/{client}/targets", Command: "client targets", Result: []commands.NutrientTarget{}, Scope: auth.ScopeReadHistory},
+	{Method: "GET", Path: "/clients/{client}/food-history", Command: "client history food", Result: []commands.FoodEntry{}, Paginate: true, Scope: auth.ScopeReadHistory},
+	{Method: "GET", Path: "/clients/{client}/weights", Command: "client history weight", Result: []commands.Weight{}, Paginate: true, Scope: auth.ScopeReadHistory},
+	{Method: "GET", Path: "/clients/{client}/imc", Command: "client history imc", Result: []commands.IMCEntry{}, Paginate: true, Scope: auth.ScopeReadHistory},
+	{Method: "GET", Path: "/clients/{client}/bodyfat", Command: "client history bodyfat", Result: []commands.BodyFat{}, Paginate: true, Scope: auth.ScopeReadHistory},
+	{Method: "GET", Path: "/clients/{client}/exercise", Command: "client history exercise", Result: []commands.ExerciseEntry{}, Paginate: true, Scope: auth.ScopeReadHistory},
+	{Method: "GET", Path: "/clients/{client}/water", Command: "client history water", Result: []commands.WaterEntry{}, Paginate: true, Scope: auth.ScopeReadHistory},
+	{Method: "GET", Path: "/coaches", Command: "coach list", Result: []commands.CoachLink{}, Paginate: true, Scope: auth.ScopeReadHistory},
+	{Method: "POST", Path: "/coaches/{coach}/accept", Command: "coach accept", Result: commands.CoachInvitation{}, Scope: auth.ScopeAdmin},
+	{Method: "DELETE", Path: "/coaches/{coach}", Command: "coach revoke", Result: commands.CoachInvitation{}, Scope: auth.ScopeAdmin},
+
+	{Method: "GET", Path: "/admin/users", Command: "admin users", Result: []commands.Account{}, Paginate: true, Scope: auth.ScopeAdmin},
+	{Method: "PUT", Path: "/admin/users/{user}/role", Command: "admin role", Result: commands.Role{}, Scope: auth.ScopeAdmin},
+	{Method: "POST", Path: "/admin/users/{user}/unlock", Command: "admin unlock", Result: commands.Unlocked{}, Scope: auth.ScopeAdmin},
+	{Method: "POST", Path: "/admin/users/{user}/reset", Command: "admin reset", Result: commands.ResetToken{}, Status: http.StatusCreated, Scope: auth.ScopeAdmin},
+}
+
+func init() {
+	// The commands are only resolved to read their arguments and flags
+	registry := commands.NewRegistry(&commands.Context{Session: &commands.Session{}})
+	for i := range routes {
+		route := &routes[i]
+		if route.Command != "" {
+			command, _, _, err := registry.Resolve(strings.Fields(route.Command))
+			if err != nil {
+				panic("api: route " + route.Method + " " + route.Path + ": " + err.Error())
+			}
+			if route.Summary == "" {
+				route.Summary = command.Summary
+			}
+			for _, arg := range command.Args {
+				route.params = append(route.params, field{Name: arg.Name, Type: arg.Type, Required: !arg.Optional, kind: argField})
+			}
+			for _, flag := range command.Flags {
+				route.params = append(route.params, field{Name: flag.Name, Type: flag.Type, Usage: flag.Usage, kind: flagField})
+			}
+		}
+		for _, extra := range route.Fields {
+			extra.kind = extraField
+			route.params = append(route.params, extra)
+		}
+	}
+}
+
+// param returns the field of the route with the given name
+func (route route) param(name string) (field, bool) {
+	for _, param := range route.params {
+		if param.Name == name {
+			return param, true
+		}
+	}
+	return field{}, false
+}
+
+// words returns the command line running the command of the route with the
+// fields of a request. The flags come first, the arguments follow a -- so
+// that values starting with dashes are not taken for flags.
+func (s *Server) words(ctx *commands.Context, route route, fields map[string]string) ([]string, error) {
+	words := strings.Fields(route.Command)
+	for _, param := range route.params {
+		if value, ok := fields[param.Name]; ok && param.kind == flagField {
+			words = append(words, "--"+param.Name+"="+value)
+		}
+	}
+	words = append(words, "--")
+	missing := ""
+	for _, param := range route.params {
+		if param.kind != argField {
+			continue
+		}
+		value, ok := fields[param.Name]
+		if !ok {
+			if missing == "" {
+				missing = param.Name
+			}
+			continue
+		}
+		if missing != "" {
+			return nil, s.errorf(ctx, http.StatusBadRequest, "missing field '%s'", missing)
+		}
+		words = append(words, value)
+	}
+	return words, nil
+}
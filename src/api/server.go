@@ -0,0 +1,219 @@
+// Package api serves the tracker as a JSON REST API. Each route runs a
+// command of the table with the fields of the request as its arguments and
+// flags, and answers with the JSON result of the command, the same as
+// --output json.
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gotracker/auth"
+	"gotracker/commands"
+	"gotracker/fdcnal"
+	suser "gotracker/structs"
+	"gotracker/web"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Prefix is the path all the routes of the API are under
+const Prefix = "/api/v1"
+
+// DefaultTokenTTL is how long the tokens given by login and register are valid
+const DefaultTokenTTL = 30 * 24 * time.Hour
+
+// SessionIdle is how long the session of a token is kept without requests.
+// The active date and undo of a token are forgotten with its session.
+const SessionIdle = 30 * time.Minute
+
+// maxBodySize is the largest request body accepted, in bytes
+const maxBodySize = 1 << 20
+
+// Server serves the API over the data layer and the FoodData Central client.
+// Log receives the warnings of the commands and the internal errors, which
+// are not shown to the clients. The requests made with a token share a
+// session of Sessions, where they run one at a time.
+type Server struct {
+	Store    commands.Store
+	FDC      *fdcnal.Client
+	Log      io.Writer
+	TokenTTL time.Duration
+	Sessions *commands.SessionManager
+}
+
+// NewServer creates a server over store and fdc, logging to log
+func NewServer(store commands.Store, fdc *fdcnal.Client, log io.Writer) *Server {
+	return &Server{Store: store, FDC: fdc, Log: log, TokenTTL: DefaultTokenTTL, Sessions: commands.NewSessionManager()}
+}
+
+// Handler returns the HTTP handler of the routes, and of the web front end
+// outside of Prefix
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+Prefix+"/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, OpenAPI())
+	})
+	for _, route := range routes {
+		mux.Handle(route.Method+" "+Prefix+route.Path, s.handle(route))
+	}
+	mux.HandleFunc(Prefix+"/", func(w http.ResponseWriter, r *http.Request) {
+		ctx := s.context(r)
+		s.fail(w, r, ctx, s.errorf(ctx, http.StatusNotFound, "no route for %s %s", r.Method, r.URL.Path))
+	})
+	mux.Handle("/", web.Handler())
+	return mux
+}
+
+// ListenAndServe serves the API on addr until ctx is cancelled, then waits
+// for the requests in flight to finish
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	// Close the sessions of the tokens no longer used
+	go func() {
+		ticker := time.NewTicker(SessionIdle / 6)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.Sessions.CloseIdle(commands.SessionHTTP, SessionIdle)
+			}
+		}
+	}()
+	done := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		done <- server.Shutdown(shutdownCtx)
+	}()
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return <-done
+}
+
+// context returns the context of the commands run by a request, in the
+// language of its Accept-Language header. Nobody is logged in yet.
+func (s *Server) context(r *http.Request) *commands.Context {
+	return &commands.Context{
+		Ctx:      r.Context(),
+		Store:    s.Store,
+		FDC:      s.FDC,
+		Session:  &commands.Session{},
+		Language: language(r),
+		Out:      io.Discard,
+		Err:      s.Log,
+	}
+}
+
+// handle returns the handler of a route: it authenticates the request, reads
+// its fields and runs the route
+func (s *Server) handle(route route) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := s.context(r)
+		if !route.Public {
+			token, err := s.authenticate(ctx, r)
+			if err != nil {
+				s.fail(w, r, ctx, err)
+				return
+			}
+			if route.Scope != "" && !auth.HasScope(token.Scopes, route.Scope) {
+				s.fail(w, r, ctx, s.errorf(ctx, http.StatusForbidden, "the token does not have the '%s' scope", route.Scope))
+				return
+			}
+			user := ctx.Session.User
+			ctx.Session = s.Sessions.Attach(commands.SessionHTTP, strconv.Itoa(token.ID))
+			ctx.Session.Lock()
+			defer ctx.Session.Unlock()
+			if ctx.Session.UserID() != user.ID {
+				ctx.Session.Start(user)
+			} else {
+				// The user may have changed since the previous request
+				ctx.Session.User = user
+			}
+		}
+		fields, err := s.fields(ctx, r, route)
+		if err != nil {
+			s.fail(w, r, ctx, err)
+			return
+		}
+
+		var data interface{}
+		if route.Run != nil {
+			data, err = route.Run(s, ctx, r, route, fields)
+		} else {
+			data, err = s.run(ctx, route, fields)
+		}
+		if err != nil {
+			s.fail(w, r, ctx, err)
+			return
+		}
+		if route.Paginate {
+			if data, err = s.paginate(ctx, r, data); err != nil {
+				s.fail(w, r, ctx, err)
+				return
+			}
+		}
+		status := route.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		writeJSON(w, status, data)
+	})
+}
+
+// authenticate logs in the user of the bearer token of the request and
+// returns the token, the request then runs in the session of the token
+func (s *Server) authenticate(ctx *commands.Context, r *http.Request) (suser.APIToken, error) {
+	token, ok := bearerToken(r)
+	if !ok {
+		return suser.APIToken{}, s.errorf(ctx, http.StatusUnauthorized, "missing bearer token, login with POST %s/login", Prefix)
+	}
+	apiToken, found, err := s.Store.UseAPIToken(auth.HashToken(token))
+	if err != nil {
+		return suser.APIToken{}, fmt.Errorf("failed to check token: %w", err)
+	}
+	if !found || apiToken.ExpiresAt.Before(time.Now()) {
+		return suser.APIToken{}, s.errorf(ctx, http.StatusUnauthorized, "invalid or expired token")
+	}
+	user, err := s.Store.GetUser(apiToken.UserID)
+	if err != nil {
+		return suser.APIToken{}, fmt.Errorf("failed to get token user: %w", err)
+	}
+	ctx.Session.User = *user
+	return apiToken, nil
+}
+
+// bearerToken returns the token of the Authorization header of a request
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// run runs the command of a route with the fields of a request and returns its result
+func (s *Server) run(ctx *commands.Context, route route, fields map[string]string) (interface{}, error) {
+	words, err := s.words(ctx, route, fields)
+	if err != nil {
+		return nil, err
+	}
+	result, err := commands.NewRegistry(ctx).Run(words)
+	if err != nil {
+		return nil, err
+	}
+	return result.Data, nil
+}
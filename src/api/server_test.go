@@ -0,0 +1,327 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"gotracker/auth"
+	"gotracker/commands"
+	"gotracker/fdcnal"
+	suser "gotracker/structs"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeStore is a Store in memory with the users and tokens used by the
+// tests. The methods the routes under test do not call are left to the nil
+// embedded Store and panic.
+type fakeStore struct {
+	commands.Store
+	users     map[int]suser.SUser
+	passwords map[int]string // Password hashes by user ID
+	failures  map[int]int
+	tokens    map[string]suser.APIToken // By token hash
+	actors    []int                     // Actors of the scoped stores
+}
+
+func newFakeStore(t *testing.T) *fakeStore {
+	t.Helper()
+	hash, err := auth.HashPassword("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &fakeStore{
+		users:     map[int]suser.SUser{1: {ID: 1, Username: "john", Email: "john@example.com", Firstname: "John", Lastname: "Doe", Role: suser.RoleMember}},
+		passwords: map[int]string{1: hash},
+		failures:  make(map[int]int),
+		tokens:    make(map[string]suser.APIToken),
+	}
+}
+
+// addToken gives a token of scopes to the user and returns it
+func (s *fakeStore) addToken(userID int, scopes ...string) string {
+	token, hash, _ := auth.NewToken()
+	s.tokens[hash] = suser.APIToken{ID: len(s.tokens) + 1, UserID: userID, Scopes: scopes, ExpiresAt: time.Now().Add(time.Hour)}
+	return token
+}
+
+func (s *fakeStore) As(actorID int, sessionID string) commands.Store {
+	s.actors = append(s.actors, actorID)
+	return s
+}
+
+func (s *fakeStore) GetUser(userID int) (*suser.SUser, error) {
+	user := s.users[userID]
+	return &user, nil
+}
+
+func (s *fakeStore) FindUser(login string) (int, error) {
+	for id, user := range s.users {
+		if user.Username == login || user.Email == login {
+			return id, nil
+		}
+	}
+	return 0, nil
+}
+
+func (s *fakeStore) RecordLoginAttempt(userID int, maxFailures int, lockout time.Duration) (suser.Credentials, bool, error) {
+	s.failures[userID]++
+	allowed := s.failures[userID] <= maxFailures
+	return suser.Credentials{UserID: userID, PasswordHash: s.passwords[userID], FailedLogins: s.failures[userID]}, allowed, nil
+}
+
+func (s *fakeStore) ResetFailedLogins(userID int) error {
+	s.failures[userID] = 0
+	return nil
+}
+
+func (s *fakeStore) CreateAPIToken(userID int, name string, tokenHash string, scopes []string, expiresAt time.Time) (int, error) {
+	id := len(s.tokens) + 1
+	s.tokens[tokenHash] = suser.APIToken{ID: id, UserID: userID, Name: name, Scopes: scopes, ExpiresAt: expiresAt}
+	return id, nil
+}
+
+func (s *fakeStore) UseAPIToken(tokenHash string) (suser.APIToken, bool, error) {
+	token, found := s.tokens[tokenHash]
+	return token, found, nil
+}
+
+func (s *fakeStore) GetAliases(userID int) ([][2]string, error) {
+	return nil, nil
+}
+
+// newTestServer returns a server over store whose FoodData Central client
+// calls fdc, closed at the end of the test
+func newTestServer(t *testing.T, store commands.Store, fdc http.Handler) *Server {
+	t.Helper()
+	client := fdcnal.NewClient("http://fdc.invalid/", "test-key")
+	if fdc != nil {
+		server := httptest.NewServer(fdc)
+		t.Cleanup(server.Close)
+		client = fdcnal.NewClient(server.URL+"/", "test-key")
+		client.HTTP = server.Client()
+	}
+	return NewServer(store, client, io.Discard)
+}
+
+// request serves a request with a JSON body, unless body is empty, and a
+// bearer token, unless token is empty, and decodes the JSON response into
+// result when it is not nil
+func request(t *testing.T, s *Server, method string, target string, token string, body string, result interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	r := httptest.NewRequest(method, target, reader)
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, r)
+	if result != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), result); err != nil {
+			t.Fatalf("%s %s: invalid JSON response %q: %v", method, target, w.Body.String(), err)
+		}
+	}
+	return w
+}
+
+func TestMe(t *testing.T) {
+	store := newFakeStore(t)
+	s := newTestServer(t, store, nil)
+
+	var user commands.User
+	w := request(t, s, "GET", Prefix+"/me", store.addToken(1, auth.ScopeReadHistory), "", &user)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /me: status %d, body %s", w.Code, w.Body)
+	}
+	if user.ID != 1 || user.Username != "john" || user.Firstname != "John" {
+		t.Errorf("GET /me = %+v, want john", user)
+	}
+	if len(store.actors) != 1 || store.actors[0] != 1 {
+		t.Errorf("the command ran with the stores of the actors %v, want [1]", store.actors)
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	store := newFakeStore(t)
+	s := newTestServer(t, store, nil)
+	expired := store.addToken(1, auth.ScopeAdmin)
+	for hash, token := range store.tokens {
+		token.ExpiresAt = time.Now().Add(-time.Minute)
+		store.tokens[hash] = token
+	}
+
+	tests := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{"missing token", "", http.StatusUnauthorized},
+		{"unknown token", "gtk_unknown", http.StatusUnauthorized},
+		{"expired token", expired, http.StatusUnauthorized},
+		{"missing scope", store.addToken(1, auth.ScopeWriteLog), http.StatusForbidden},
+		{"scope", store.addToken(1, auth.ScopeReadHistory), http.StatusOK},
+		{"admin scope", store.addToken(1, auth.ScopeAdmin), http.StatusOK},
+	}
+	for _, test := range tests {
+		var body map[string]interface{}
+		w := request(t, s, "GET", Prefix+"/me", test.token, "", &body)
+		if w.Code != test.status {
+			t.Errorf("%s: status %d, want %d, body %s", test.name, w.Code, test.status, w.Body)
+		}
+		if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: no WWW-Authenticate header", test.name)
+		}
+		if w.Code != http.StatusOK && body["error"] == nil {
+			t.Errorf("%s: no error message in %v", test.name, body)
+		}
+	}
+}
+
+func TestLogin(t *testing.T) {
+	store := newFakeStore(t)
+	s := newTestServer(t, store, nil)
+
+	var session Session
+	w := request(t, s, "POST", Prefix+"/login", "", `{"login": "John", "password": "correct horse battery staple"}`, &session)
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /login: status %d, body %s", w.Code, w.Body)
+	}
+	if !strings.HasPrefix(session.Token, auth.TokenPrefix) || session.User.ID != 1 {
+		t.Errorf("POST /login = %+v, want a token of john", session)
+	}
+	token, found := store.tokens[auth.HashToken(session.Token)]
+	if !found || token.UserID != 1 || !auth.HasScope(token.Scopes, auth.ScopeAdmin) {
+		t.Errorf("the token of the login is %+v, want an admin token of john", token)
+	}
+	if store.failures[1] != 0 {
+		t.Errorf("%d failed logins after a login, want 0", store.failures[1])
+	}
+
+	// The token of the login gives access to the other routes
+	if w := request(t, s, "GET", Prefix+"/me", session.Token, "", nil); w.Code != http.StatusOK {
+		t.Errorf("GET /me with the token of the login: status %d", w.Code)
+	}
+}
+
+func TestLoginRefused(t *testing.T) {
+	store := newFakeStore(t)
+	s := newTestServer(t, store, nil)
+
+	tests := []struct {
+		name   string
+		target string
+		body   string
+		status int
+	}{
+		{"wrong password", "/login", `{"login": "john", "password": "wrong"}`, http.StatusUnauthorized},
+		{"unknown user", "/login", `{"login": "jane", "password": "wrong"}`, http.StatusUnauthorized},
+		{"missing password", "/login", `{"login": "john"}`, http.StatusBadRequest},
+		{"unknown field", "/login", `{"login": "john", "password": "x", "remember": true}`, http.StatusBadRequest},
+		{"password in the query", "/login?password=correct+horse+battery+staple", `{"login": "john"}`, http.StatusBadRequest},
+		{"not an object", "/login", `["john"]`, http.StatusBadRequest},
+		{"nested member", "/login", `{"login": {"username": "john"}, "password": "x"}`, http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		var body ErrorBody
+		w := request(t, s, "POST", Prefix+test.target, "", test.body, &body)
+		if w.Code != test.status {
+			t.Errorf("%s: status %d, want %d, body %s", test.name, w.Code, test.status, w.Body)
+		}
+		if body.Error == "" {
+			t.Errorf("%s: no error message", test.name)
+		}
+	}
+	if len(store.tokens) != 0 {
+		t.Errorf("%d tokens created by refused logins", len(store.tokens))
+	}
+}
+
+func TestLoginContentType(t *testing.T) {
+	s := newTestServer(t, newFakeStore(t), nil)
+	r := httptest.NewRequest("POST", Prefix+"/login", strings.NewReader(`{"login": "john", "password": "x"}`))
+	r.Header.Set("Content-Type", "text/plain")
+	w := httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, r)
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("POST /login as text/plain: status %d, want %d", w.Code, http.StatusUnsupportedMediaType)
+	}
+}
+
+func TestSearchFood(t *testing.T) {
+	var query, key string
+	fdc := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/foods/search" {
+			http.NotFound(w, r)
+			return
+		}
+		query = r.URL.Query().Get("query")
+		key = r.Header.Get("X-Api-Key")
+		if r.URL.Query().Has("api_key") {
+			t.Error("the FDC key is sent in the URL")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"foods": [
+			{"fdcId": 1, "description": "Apple, raw", "dataType": "Foundation"},
+			{"fdcId": 2, "description": "Apple juice", "dataType": "Branded"},
+			{"fdcId": 3, "description": "Apple pie", "dataType": "Survey (FNDDS)"}
+		]}`)
+	})
+	store := newFakeStore(t)
+	s := newTestServer(t, store, fdc)
+
+	var page struct {
+		Items   []commands.FoodMatch `json:"items"`
+		Page    int                  `json:"page"`
+		PerPage int                  `json:"per_page"`
+		Total   int                  `json:"total"`
+	}
+	w := request(t, s, "GET", Prefix+"/foods?food_name=apple&per_page=2&page=2", store.addToken(1, auth.ScopeReadHistory), "", &page)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /foods: status %d, body %s", w.Code, w.Body)
+	}
+	if query != "apple" || key != "test-key" {
+		t.Errorf("FDC called with the query %q and the key %q", query, key)
+	}
+	if page.Total != 3 || page.Page != 2 || page.PerPage != 2 || len(page.Items) != 1 {
+		t.Fatalf("GET /foods page = %+v, want the second page of 2 of 3 foods", page)
+	}
+	if want := (commands.FoodMatch{FoodID: 3, Description: "Apple pie", DataType: "Survey (FNDDS)"}); page.Items[0] != want {
+		t.Errorf("GET /foods item = %+v, want %+v", page.Items[0], want)
+	}
+}
+
+func TestSearchFoodUnavailable(t *testing.T) {
+	fdc := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "over rate limit", http.StatusTooManyRequests)
+	})
+	store := newFakeStore(t)
+	var log bytes.Buffer
+	s := newTestServer(t, store, fdc)
+	s.Log = &log
+
+	var body ErrorBody
+	w := request(t, s, "GET", Prefix+"/foods?food_name=apple", store.addToken(1, auth.ScopeReadHistory), "", &body)
+	if w.Code < 400 || body.Error == "" {
+		t.Errorf("GET /foods with FDC unavailable: status %d, body %s", w.Code, w.Body)
+	}
+	if strings.Contains(w.Body.String()+log.String(), "test-key") {
+		t.Error("the FDC key appears in the response or the log")
+	}
+}
+
+func TestUnknownRoute(t *testing.T) {
+	s := newTestServer(t, newFakeStore(t), nil)
+	var body ErrorBody
+	if w := request(t, s, "GET", Prefix+"/nowhere", "", "", &body); w.Code != http.StatusNotFound || body.Error == "" {
+		t.Errorf("GET /nowhere: status %d, body %+v", w.Code, body)
+	}
+}
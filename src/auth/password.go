@@ -0,0 +1,58 @@
+// Package auth hashes and checks the passwords and the API tokens of the users.
+package auth
+
+import (
+	"errors"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// MinPasswordLength is the minimum number of characters of a password
+	MinPasswordLength = 8
+	// MaxFailedLogins is the number of failed logins in a row that locks an account
+	MaxFailedLogins = 5
+	// LockoutDuration is how long an account stays locked after too many failed logins
+	LockoutDuration = 15 * time.Minute
+)
+
+// ErrPasswordTooShort is returned for passwords shorter than MinPasswordLength
+var ErrPasswordTooShort = errors.New("the password must be at least 8 characters long")
+
+// ErrPasswordTooLong is returned for passwords bcrypt cannot hash
+var ErrPasswordTooLong = errors.New("the password must be at most 72 bytes long")
+
+// dummyHash is checked against when a user does not exist, so that an
+// unknown username takes as long to reject as a wrong password
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("gotracker"), bcrypt.DefaultCost)
+
+// ValidatePassword checks the length of a new password
+func ValidatePassword(password string) error {
+	if utf8.RuneCountInString(password) < MinPasswordLength {
+		return ErrPasswordTooShort
+	}
+	if len(password) > 72 {
+		return ErrPasswordTooLong
+	}
+	return nil
+}
+
+// HashPassword returns the bcrypt hash of a password
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword reports whether password matches hash, an empty hash never matches
+func CheckPassword(hash string, password string) bool {
+	if hash == "" {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// TokenPrefix starts every API token, so that leaked tokens are easy to recognize
+const TokenPrefix = "gtk_"
+
+// ResetTokenPrefix starts the one-time tokens setting the credentials of an account
+const ResetTokenPrefix = "gtr_"
+
+// ResetTokenTTL is how long a reset token can be used
+const ResetTokenTTL = 24 * time.Hour
+
+// NewToken returns a random API token and the hash to store in its place
+func NewToken() (string, string, error) {
+	return newToken(TokenPrefix)
+}
+
+// NewResetToken returns a random reset token and the hash to store in its place
+func NewResetToken() (string, string, error) {
+	return newToken(ResetTokenPrefix)
+}
+
+// newToken returns a random token starting with prefix and its hash
+func newToken(prefix string) (string, string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", "", err
+	}
+	token := prefix + base64.RawURLEncoding.EncodeToString(random)
+	return token, HashToken(token), nil
+}
+
+// HashToken returns the hash under which a token is stored. Tokens are
+// random, a fast hash is enough to keep a leaked database from giving access.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(token)))
+	return hex.EncodeToString(sum[:])
+}
+
+// Scopes of the API tokens, a token only reaches the routes of its scopes
+const (
+	ScopeReadHistory = "history:read" // Read the histories, the meals, the targets and the foods
+	ScopeWriteLog    = "log:write"    // Write the food log and the water, exercise and body logs
+	ScopeManageMeals = "meals:write"  // Create and change the meals, the day presets and the targets
+	ScopeAdmin       = "admin"        // Everything, the account and its tokens included
+)
+
+// Scopes are the scopes a token can be given
+var Scopes = []string{ScopeReadHistory, ScopeWriteLog, ScopeManageMeals, ScopeAdmin}
+
+// HasScope tells whether the scopes of a token give scope, admin gives them all
+func HasScope(scopes []string, scope string) bool {
+	for _, granted := range scopes {
+		if granted == scope || granted == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
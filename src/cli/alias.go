@@ -0,0 +1,109 @@
+package cli
+
+import "strings"
+
+// MaxAliasDepth is the number of aliases that may expand into one another
+// before the expansion is considered runaway
+const MaxAliasDepth = 10
+
+// aliasParameter matches a parameter of an alias definition: $1 to $9 or $@
+func aliasParameter(word string, i int) (int, bool) {
+	if i+1 >= len(word) || word[i] != '$' {
+		return 0, false
+	}
+	if word[i+1] == '@' {
+		return 0, true
+	}
+	if word[i+1] >= '1' && word[i+1] <= '9' {
+		return int(word[i+1] - '0'), true
+	}
+	return 0, false
+}
+
+// expandAlias substitutes args in the definition of an alias and splits it
+// into its commands. $1 to $9 are replaced by the arguments and $@ by all of
+// them, a definition without parameters gets the arguments appended to its
+// last command.
+func (r *Registry) expandAlias(name string, definition string, args []string) ([][]string, error) {
+	commands, err := Split(definition)
+	if err != nil {
+		return nil, &UsageError{Message: r.printer().Sprintf("invalid alias '%s': %s", name, r.printer().Translate(err.Error())), printer: r.printer()}
+	}
+	if len(commands) == 0 {
+		return nil, &UsageError{Message: r.printer().Sprintf("alias '%s' is empty", name), printer: r.printer()}
+	}
+
+	parameterized := false
+	needed := 0
+	var expanded [][]string
+	for _, command := range commands {
+		var words []string
+		for _, word := range command {
+			// A lone $@ expands to one word per argument
+			if word == "$@" {
+				parameterized = true
+				words = append(words, args...)
+				continue
+			}
+			var substituted strings.Builder
+			for i := 0; i < len(word); i++ {
+				index, ok := aliasParameter(word, i)
+				if !ok {
+					substituted.WriteByte(word[i])
+					continue
+				}
+				parameterized = true
+				i++
+				if index == 0 {
+					substituted.WriteString(strings.Join(args, " "))
+					continue
+				}
+				needed = max(needed, index)
+				if index <= len(args) {
+					substituted.WriteString(args[index-1])
+				}
+			}
+			words = append(words, substituted.String())
+		}
+		expanded = append(expanded, words)
+	}
+
+	if needed > len(args) {
+		return nil, &UsageError{Message: r.printer().Sprintf("alias '%s' needs %d arguments, got %d", name, needed, len(args)), printer: r.printer()}
+	}
+	if !parameterized {
+		last := len(expanded) - 1
+		expanded[last] = append(expanded[last], args...)
+	}
+	return expanded, nil
+}
+
+// executeAlias runs the commands an alias expands to, stopping at the first
+// error. expanding holds the aliases being expanded to detect recursion, and
+// output the --output flag given to the alias, passed on to its commands.
+func (r *Registry) executeAlias(name string, definition string, args []string, output string, expanding []string) error {
+	for i, alias := range expanding {
+		if alias == name {
+			chain := strings.Join(append(append([]string{}, expanding[i:]...), name), " -> ")
+			return &UsageError{Message: r.printer().Sprintf("alias '%s' expands to itself: %s", name, chain), printer: r.printer()}
+		}
+	}
+	if len(expanding) >= MaxAliasDepth {
+		return &UsageError{Message: r.printer().Sprintf("alias '%s' is nested more than %d levels deep", name, MaxAliasDepth), printer: r.printer()}
+	}
+
+	commands, err := r.expandAlias(name, definition, args)
+	if err != nil {
+		return err
+	}
+	expanding = append(expanding, name)
+	for _, words := range commands {
+		if output != "" {
+			words = append([]string{words[0], "--output", output}, words[1:]...)
+		}
+		if err := r.executeWords(words, expanding); err != nil {
+			return err
+		}
+	}
+	return nil
+}
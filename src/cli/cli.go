@@ -1,32 +1,53 @@
 package cli
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
-	"strings"
+
+	"golang.org/x/term"
 )
 
+// Prompt is printed before reading each command in interactive mode
+const Prompt = "Command: "
+
+// CommandMessage is a command line read by Open. Err is only set on the last
+// message, when reading the input failed for another reason than its end.
+// The receiver closes Done once the command has run, Open waits for it
+// before reading the next line so that the prompt follows the output.
 type CommandMessage struct {
 	Command string
+	Err     error
+	Done    chan struct{}
 }
 
-func Open(commands chan<- CommandMessage) {
-	reader := bufio.NewReader(os.Stdin)
+// IsTerminal reports whether the file is an interactive terminal
+func IsTerminal(file *os.File) bool {
+	return term.IsTerminal(int(file.Fd()))
+}
 
-	fmt.Print("Command: ")
+// Open reads command lines with the reader and sends them to the channel,
+// which is closed at the end of the input
+func Open(commands chan<- CommandMessage, reader LineReader) {
+	defer close(commands)
 
 	for {
-		input, err := reader.ReadString('\n')
-		if err != nil {
-			fmt.Println("Error reading input:", err)
+		input, err := reader.ReadLine()
+		if errors.Is(err, ErrInterrupted) {
 			continue
 		}
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			commands <- CommandMessage{Err: fmt.Errorf("error reading input: %w", err)}
+			return
+		}
 
-		// Clean input (remove newline, spaces, etc.)
-		input = strings.TrimSpace(input)
-
-		// Send the command to the channel
-		commands <- CommandMessage{Command: input}
+		// Send the command to the channel and wait for it to run
+		done := make(chan struct{})
+		commands <- CommandMessage{Command: input, Done: done}
+		<-done
 	}
 }
@@ -0,0 +1,290 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ArgType is the type an argument or flag value is converted to
+type ArgType int
+
+const (
+	String ArgType = iota
+	Int
+	Float
+	Bool
+)
+
+func (t ArgType) String() string {
+	switch t {
+	case Int:
+		return "an integer"
+	case Float:
+		return "a number"
+	case Bool:
+		return "a boolean"
+	}
+	return "a string"
+}
+
+// Arg declares a positional argument of a command
+type Arg struct {
+	Name     string
+	Type     ArgType
+	Optional bool
+	// Rest collects all the remaining words joined by spaces, including words
+	// starting with --, it must be the last argument
+	Rest bool
+}
+
+// Flag declares a named --flag of a command, Bool flags take no value
+type Flag struct {
+	Name    string
+	Type    ArgType
+	Usage   string
+	Default string
+}
+
+// Command is a command or subcommand of the registry. A command either has
+// subcommands or runs itself with its declared arguments and returns a result
+// for the registry to render, nil when there is nothing to show.
+type Command struct {
+	Name        string
+	Summary     string
+	Args        []Arg
+	Flags       []Flag
+	Subcommands []*Command
+	Run         func(inv *Invocation) (*Result, error)
+	Secret      bool // The arguments are secrets, the lines running the command are not kept in the history
+}
+
+// Invocation holds the parsed arguments and flags of a command being run
+type Invocation struct {
+	Command *Command
+	Path    []string // Names of the command and its subcommands
+	values  map[string]interface{}
+	flags   map[string]interface{}
+	rest    []string
+}
+
+// UsageError is returned when a command line does not match the declaration of a command
+type UsageError struct {
+	Path    []string
+	Command *Command
+	Message string
+	printer Printer
+}
+
+func (e *UsageError) Error() string {
+	return e.Message
+}
+
+// Usage returns the usage line of the command the error is about
+func (e *UsageError) Usage() string {
+	if e.Command == nil || len(e.Path) == 0 {
+		return ""
+	}
+	p := e.printer
+	if p == nil {
+		p = english{}
+	}
+	return p.Sprintf("Usage: %s", e.Command.Usage(e.Path[:len(e.Path)-1]...))
+}
+
+// Usage returns the usage line of the command, prefixed by the path of its parents
+func (c *Command) Usage(parents ...string) string {
+	parts := append(append([]string{}, parents...), c.Name)
+	if len(c.Subcommands) > 0 {
+		names := make([]string, len(c.Subcommands))
+		for i, sub := range c.Subcommands {
+			names[i] = sub.Name
+		}
+		parts = append(parts, "<"+strings.Join(names, "|")+">")
+	}
+	for _, arg := range c.Args {
+		name := arg.Name
+		if arg.Rest {
+			name += "..."
+		}
+		if arg.Optional {
+			parts = append(parts, "["+name+"]")
+		} else {
+			parts = append(parts, "<"+name+">")
+		}
+	}
+	for _, flag := range c.Flags {
+		if flag.Type == Bool {
+			parts = append(parts, "[--"+flag.Name+"]")
+		} else {
+			parts = append(parts, "[--"+flag.Name+" <value>]")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Subcommand returns the subcommand with the given name
+func (c *Command) Subcommand(name string) *Command {
+	for _, sub := range c.Subcommands {
+		if sub.Name == name {
+			return sub
+		}
+	}
+	return nil
+}
+
+func (c *Command) flag(name string) *Flag {
+	for i := range c.Flags {
+		if c.Flags[i].Name == name {
+			return &c.Flags[i]
+		}
+	}
+	return nil
+}
+
+func convert(value string, argType ArgType) (interface{}, error) {
+	switch argType {
+	case Int:
+		return strconv.Atoi(value)
+	case Float:
+		return strconv.ParseFloat(value, 64)
+	case Bool:
+		return strconv.ParseBool(value)
+	}
+	return value, nil
+}
+
+// parse splits words into flags and positional arguments and converts them
+// to their declared types, p localizes the usage errors
+func (c *Command) parse(p Printer, path []string, words []string) (*Invocation, error) {
+	usageError := func(format string, a ...interface{}) error {
+		return &UsageError{Path: path, Command: c, Message: p.Sprintf(format, a...), printer: p}
+	}
+
+	inv := &Invocation{
+		Command: c,
+		Path:    path,
+		values:  make(map[string]interface{}),
+		flags:   make(map[string]interface{}),
+	}
+
+	var positional []string
+	onlyPositional := false
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		if onlyPositional || !strings.HasPrefix(word, "--") {
+			positional = append(positional, word)
+			// The words of a Rest argument are not flags
+			if len(positional) == len(c.Args) && c.Args[len(c.Args)-1].Rest {
+				onlyPositional = true
+			}
+			continue
+		}
+		if word == "--" {
+			onlyPositional = true
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(word, "--"), "=")
+		flag := c.flag(name)
+		if flag == nil {
+			return nil, usageError("unknown flag --%s", name)
+		}
+		if !hasValue {
+			if flag.Type == Bool {
+				value = "true"
+			} else {
+				if i+1 >= len(words) {
+					return nil, usageError("flag --%s needs a value", name)
+				}
+				i++
+				value = words[i]
+			}
+		}
+		converted, err := convert(value, flag.Type)
+		if err != nil {
+			return nil, usageError("invalid value '%s' for --%s: expected %s", value, name, p.Translate(flag.Type.String()))
+		}
+		inv.flags[name] = converted
+	}
+
+	for _, flag := range c.Flags {
+		if _, ok := inv.flags[flag.Name]; ok || flag.Default == "" {
+			continue
+		}
+		converted, err := convert(flag.Default, flag.Type)
+		if err != nil {
+			return nil, fmt.Errorf("invalid default for --%s: %w", flag.Name, err)
+		}
+		inv.flags[flag.Name] = converted
+	}
+
+	for i, arg := range c.Args {
+		if i >= len(positional) {
+			if !arg.Optional {
+				return nil, usageError("missing argument <%s>", arg.Name)
+			}
+			break
+		}
+		value := positional[i]
+		if arg.Rest {
+			inv.rest = positional[i:]
+			value = strings.Join(positional[i:], " ")
+		}
+		converted, err := convert(value, arg.Type)
+		if err != nil {
+			return nil, usageError("invalid value '%s' for <%s>: expected %s", value, arg.Name, p.Translate(arg.Type.String()))
+		}
+		inv.values[arg.Name] = converted
+	}
+	if len(positional) > len(c.Args) && (len(c.Args) == 0 || !c.Args[len(c.Args)-1].Rest) {
+		return nil, usageError("too many arguments")
+	}
+
+	return inv, nil
+}
+
+// Rest returns the words collected by the Rest argument, before they are joined
+func (inv *Invocation) Rest() []string {
+	return inv.rest
+}
+
+// Has reports whether an argument or flag was given
+func (inv *Invocation) Has(name string) bool {
+	if _, ok := inv.values[name]; ok {
+		return true
+	}
+	_, ok := inv.flags[name]
+	return ok
+}
+
+func (inv *Invocation) value(name string) interface{} {
+	if value, ok := inv.values[name]; ok {
+		return value
+	}
+	return inv.flags[name]
+}
+
+// String returns a string argument or flag, empty when not given
+func (inv *Invocation) String(name string) string {
+	value, _ := inv.value(name).(string)
+	return value
+}
+
+// Int returns an integer argument or flag, 0 when not given
+func (inv *Invocation) Int(name string) int {
+	value, _ := inv.value(name).(int)
+	return value
+}
+
+// Float returns a number argument or flag, 0 when not given
+func (inv *Invocation) Float(name string) float64 {
+	value, _ := inv.value(name).(float64)
+	return value
+}
+
+// Bool returns a boolean flag, false when not given
+func (inv *Invocation) Bool(name string) bool {
+	value, _ := inv.value(name).(bool)
+	return value
+}
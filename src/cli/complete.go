@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"sort"
+	"strings"
+)
+
+// Completion is a candidate of tab completion. Description is shown next to
+// the word when listing candidates, and the typed prefix is also matched
+// against it so that e.g. a meal can be completed by name to its ID.
+type Completion struct {
+	Word        string
+	Description string
+}
+
+// Completer returns the candidates for the word following words
+type Completer func(words []string) []Completion
+
+// ArgCompleter returns the candidates for a positional argument of the command at path
+type ArgCompleter func(path []string, arg Arg) []Completion
+
+// Filter returns the candidates whose word or description starts with prefix
+func Filter(candidates []Completion, prefix string) []Completion {
+	var matches []Completion
+	lower := strings.ToLower(prefix)
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate.Word, prefix) || (prefix != "" && strings.HasPrefix(strings.ToLower(candidate.Description), lower)) {
+			matches = append(matches, candidate)
+		}
+	}
+	return matches
+}
+
+// Complete returns the candidates for the word following words: command and
+// subcommand names, flags, output formats and, through values, the
+// positional arguments of the command. values may be nil.
+func (r *Registry) Complete(words []string, values ArgCompleter) []Completion {
+	if len(words) == 0 {
+		var names []Completion
+		for _, command := range r.Commands() {
+			names = append(names, Completion{Word: command.Name, Description: r.printer().Translate(command.Summary)})
+		}
+		if r.Aliases != nil {
+			aliases := r.Aliases()
+			var aliasNames []string
+			for name := range aliases {
+				if _, isCommand := r.commands[name]; !isCommand {
+					aliasNames = append(aliasNames, name)
+				}
+			}
+			sort.Strings(aliasNames)
+			for _, name := range aliasNames {
+				names = append(names, Completion{Word: name, Description: aliases[name]})
+			}
+		}
+		return names
+	}
+
+	command, ok := r.commands[words[0]]
+	if !ok {
+		return nil
+	}
+	path := []string{words[0]}
+	words = words[1:]
+	for len(command.Subcommands) > 0 {
+		if len(words) == 0 {
+			var names []Completion
+			for _, sub := range command.Subcommands {
+				names = append(names, Completion{Word: sub.Name, Description: r.printer().Translate(sub.Summary)})
+			}
+			return names
+		}
+		command = command.Subcommand(words[0])
+		if command == nil {
+			return nil
+		}
+		path = append(path, words[0])
+		words = words[1:]
+	}
+
+	// help takes a command line to describe
+	if command.Name == "help" && len(path) == 1 {
+		return r.Complete(words, nil)
+	}
+
+	// Skip the flags and their values to find the position of the argument
+	position := 0
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		if !strings.HasPrefix(word, "--") || strings.Contains(word, "=") {
+			if !strings.HasPrefix(word, "--") {
+				position++
+			}
+			continue
+		}
+		name := strings.TrimPrefix(word, "--")
+		flag := command.flag(name)
+		if name == "output" || (flag != nil && flag.Type != Bool) {
+			if i == len(words)-1 {
+				// The next word is the value of the flag
+				if name == "output" {
+					var formats []Completion
+					for _, format := range Formats() {
+						formats = append(formats, Completion{Word: format})
+					}
+					return formats
+				}
+				return nil
+			}
+			i++
+		}
+	}
+
+	var candidates []Completion
+	if position < len(command.Args) && values != nil {
+		candidates = values(path, command.Args[position])
+	} else if len(command.Args) > 0 && command.Args[len(command.Args)-1].Rest && values != nil {
+		candidates = values(path, command.Args[len(command.Args)-1])
+	}
+	for _, flag := range command.Flags {
+		candidates = append(candidates, Completion{Word: "--" + flag.Name, Description: r.printer().Translate(flag.Usage)})
+	}
+	candidates = append(candidates, Completion{Word: "--output", Description: r.printer().Translate("Output format of the result")})
+	return candidates
+}
+
+// commonPrefix returns the longest prefix shared by the words of the candidates
+func commonPrefix(candidates []Completion) string {
+	if len(candidates) == 0 {
+		return ""
+	}
+	words := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		words[i] = candidate.Word
+	}
+	sort.Strings(words)
+	first, last := words[0], words[len(words)-1]
+	i := 0
+	for i < len(first) && i < len(last) && first[i] == last[i] {
+		i++
+	}
+	return first[:i]
+}
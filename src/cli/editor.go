@@ -0,0 +1,498 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"golang.org/x/term"
+)
+
+// ErrInterrupted is returned by ReadLine when the line is abandoned with Ctrl-C
+var ErrInterrupted = errors.New("interrupted")
+
+// LineReader reads the command lines sent by Open
+type LineReader interface {
+	ReadLine() (string, error)
+}
+
+// PasswordReader reads passwords without echoing them
+type PasswordReader interface {
+	ReadPassword(prompt string) (string, error)
+}
+
+// plainReader reads lines as they come, for pipes and files
+type plainReader struct {
+	reader *bufio.Reader
+	out    io.Writer
+	prompt string
+}
+
+// NewPlainReader returns a line reader without editing, printing the prompt before each line when it is not empty
+func NewPlainReader(in io.Reader, out io.Writer, prompt string) LineReader {
+	return &plainReader{reader: bufio.NewReader(in), out: out, prompt: prompt}
+}
+
+func (p *plainReader) ReadLine() (string, error) {
+	if p.prompt != "" {
+		fmt.Fprint(p.out, p.prompt)
+	}
+	input, err := p.reader.ReadString('\n')
+	// A last line without newline is still a command
+	if err == io.EOF && input != "" {
+		return strings.TrimSpace(input), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(input), nil
+}
+
+// ReadPassword reads the next line as a password. Pipes do not echo and get no prompt.
+func (p *plainReader) ReadPassword(prompt string) (string, error) {
+	input, err := p.reader.ReadString('\n')
+	if err == io.EOF && input != "" {
+		err = nil
+	}
+	return strings.TrimRight(input, "\r\n"), err
+}
+
+// Editor reads command lines from a terminal in raw mode. Arrows and the usual
+// Emacs keys move and edit, up and down browse the history, Ctrl-R searches it
+// backwards, Tab completes the word under the cursor, Ctrl-C abandons the
+// line and Ctrl-D on an empty line ends the input.
+type Editor struct {
+	In       io.Reader
+	Out      io.Writer
+	Prompt   string
+	History  *History  // nil for no history
+	Complete Completer // nil for no completion
+	// MakeRaw switches the terminal to raw mode and returns the function
+	// restoring it, nil when In is already raw such as an SSH channel
+	MakeRaw func() (func() error, error)
+
+	reader *bufio.Reader
+}
+
+// NewTerminalEditor returns an editor reading the terminal in and writing to out
+func NewTerminalEditor(in *os.File, out io.Writer, prompt string) *Editor {
+	fd := int(in.Fd())
+	return &Editor{
+		In:     in,
+		Out:    out,
+		Prompt: prompt,
+		MakeRaw: func() (func() error, error) {
+			state, err := term.MakeRaw(fd)
+			if err != nil {
+				return nil, fmt.Errorf("failed to switch the terminal to raw mode: %w", err)
+			}
+			return func() error { return term.Restore(fd, state) }, nil
+		},
+	}
+}
+
+// Control keys
+const (
+	KeyCtrlA     = 1
+	KeyCtrlB     = 2
+	KeyCtrlC     = 3
+	KeyCtrlD     = 4
+	KeyCtrlE     = 5
+	KeyCtrlF     = 6
+	KeyCtrlG     = 7
+	KeyBackspace = 8
+	KeyTab       = 9
+	KeyLineFeed  = 10
+	KeyCtrlK     = 11
+	KeyCtrlL     = 12
+	KeyEnter     = 13
+	KeyCtrlN     = 14
+	KeyCtrlP     = 16
+	KeyCtrlR     = 18
+	KeyCtrlU     = 21
+	KeyCtrlW     = 23
+	KeyEscape    = 27
+	KeyDelete    = 127
+)
+
+// Keys decoded from escape sequences, outside of the Unicode range
+const (
+	KeyUp = unicode.MaxRune + 1 + iota
+	KeyDown
+	KeyRight
+	KeyLeft
+	KeyHome
+	KeyEnd
+	KeyDeleteForward
+	KeyUnknown
+)
+
+// lineState is the line being edited
+type lineState struct {
+	editor     *Editor
+	line       []rune
+	cursor     int
+	historyPos int
+	draft      []rune // Line being typed before browsing the history
+	tabbed     bool   // The previous key was Tab
+}
+
+// ReadLine reads a command line, adding it to the history
+func (e *Editor) ReadLine() (string, error) {
+	if e.MakeRaw != nil {
+		restore, err := e.MakeRaw()
+		if err != nil {
+			return "", err
+		}
+		defer restore()
+	}
+
+	s := &lineState{editor: e, historyPos: len(e.history())}
+	s.refresh()
+	for {
+		key, err := e.ReadKey()
+		if err != nil {
+			return "", err
+		}
+		if key == KeyCtrlR {
+			if key, err = s.search(); err != nil {
+				return "", err
+			}
+		}
+		line, done, err := s.handle(key)
+		if done || err != nil {
+			return line, err
+		}
+	}
+}
+
+// ReadPassword reads a line without echoing it nor adding it to the history.
+// Backspace and Ctrl-U edit it, Ctrl-C abandons it with ErrInterrupted.
+func (e *Editor) ReadPassword(prompt string) (string, error) {
+	if e.MakeRaw != nil {
+		restore, err := e.MakeRaw()
+		if err != nil {
+			return "", err
+		}
+		defer restore()
+	}
+
+	fmt.Fprint(e.Out, prompt)
+	var password []rune
+	for {
+		key, err := e.ReadKey()
+		if err != nil {
+			return "", err
+		}
+		switch key {
+		case KeyEnter, KeyLineFeed:
+			fmt.Fprint(e.Out, "\r\n")
+			return string(password), nil
+		case KeyCtrlC:
+			fmt.Fprint(e.Out, "^C\r\n")
+			return "", ErrInterrupted
+		case KeyCtrlD:
+			if len(password) == 0 {
+				fmt.Fprint(e.Out, "\r\n")
+				return "", io.EOF
+			}
+		case KeyCtrlU:
+			password = password[:0]
+		case KeyDelete, KeyBackspace:
+			if len(password) > 0 {
+				password = password[:len(password)-1]
+			}
+		default:
+			if unicode.IsPrint(key) {
+				password = append(password, key)
+			}
+		}
+	}
+}
+
+func (e *Editor) history() []string {
+	if e.History == nil {
+		return nil
+	}
+	return e.History.Entries()
+}
+
+// ReadKey reads a key, decoding the escape sequences of the special keys. The
+// terminal must already be in raw mode when reading keys outside of ReadLine.
+func (e *Editor) ReadKey() (rune, error) {
+	if e.reader == nil {
+		e.reader = bufio.NewReader(e.In)
+	}
+	r, _, err := e.reader.ReadRune()
+	if err != nil || r != KeyEscape {
+		return r, err
+	}
+	r, _, err = e.reader.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	if r != '[' && r != 'O' {
+		return KeyUnknown, nil
+	}
+
+	// Read up to the final byte of the sequence, e.g. "A" or "3~"
+	var sequence []rune
+	for {
+		r, _, err = e.reader.ReadRune()
+		if err != nil {
+			return 0, err
+		}
+		sequence = append(sequence, r)
+		if r >= 0x40 && r <= 0x7e {
+			break
+		}
+	}
+	switch string(sequence) {
+	case "A":
+		return KeyUp, nil
+	case "B":
+		return KeyDown, nil
+	case "C":
+		return KeyRight, nil
+	case "D":
+		return KeyLeft, nil
+	case "H", "1~", "7~":
+		return KeyHome, nil
+	case "F", "4~", "8~":
+		return KeyEnd, nil
+	case "3~":
+		return KeyDeleteForward, nil
+	}
+	return KeyUnknown, nil
+}
+
+// handle applies a key to the line, done is set when the line is complete
+func (s *lineState) handle(key rune) (string, bool, error) {
+	e := s.editor
+	tabbed := s.tabbed
+	s.tabbed = false
+
+	switch key {
+	case KeyEnter, KeyLineFeed:
+		line := string(s.line)
+		fmt.Fprint(e.Out, "\r\n")
+		if e.History != nil {
+			// A history that cannot be saved must not prevent running the command
+			e.History.Add(line)
+		}
+		return line, true, nil
+	case KeyCtrlC:
+		fmt.Fprint(e.Out, "^C\r\n")
+		return "", true, ErrInterrupted
+	case KeyCtrlD:
+		if len(s.line) == 0 {
+			fmt.Fprint(e.Out, "\r\n")
+			return "", true, io.EOF
+		}
+		s.deleteRange(s.cursor, s.cursor+1)
+	case KeyDeleteForward:
+		s.deleteRange(s.cursor, s.cursor+1)
+	case KeyDelete, KeyBackspace:
+		s.deleteRange(s.cursor-1, s.cursor)
+	case KeyCtrlA, KeyHome:
+		s.cursor = 0
+	case KeyCtrlE, KeyEnd:
+		s.cursor = len(s.line)
+	case KeyCtrlB, KeyLeft:
+		if s.cursor > 0 {
+			s.cursor--
+		}
+	case KeyCtrlF, KeyRight:
+		if s.cursor < len(s.line) {
+			s.cursor++
+		}
+	case KeyCtrlK:
+		s.deleteRange(s.cursor, len(s.line))
+	case KeyCtrlU:
+		s.deleteRange(0, s.cursor)
+	case KeyCtrlW:
+		start := s.cursor
+		for start > 0 && s.line[start-1] == ' ' {
+			start--
+		}
+		for start > 0 && s.line[start-1] != ' ' {
+			start--
+		}
+		s.deleteRange(start, s.cursor)
+	case KeyCtrlL:
+		fmt.Fprint(e.Out, "\x1b[H\x1b[2J")
+	case KeyCtrlP, KeyUp:
+		s.browse(-1)
+	case KeyCtrlN, KeyDown:
+		s.browse(1)
+	case KeyTab:
+		s.complete(tabbed)
+	default:
+		if key <= unicode.MaxRune && unicode.IsPrint(key) {
+			s.insert(string(key))
+		}
+	}
+	s.refresh()
+	return "", false, nil
+}
+
+// refresh redraws the prompt and the line and places the cursor
+func (s *lineState) refresh() {
+	out := s.editor.Out
+	fmt.Fprintf(out, "\r%s%s\x1b[K", s.editor.Prompt, string(s.line))
+	if back := len(s.line) - s.cursor; back > 0 {
+		fmt.Fprintf(out, "\x1b[%dD", back)
+	}
+}
+
+func (s *lineState) insert(text string) {
+	runes := []rune(text)
+	line := make([]rune, 0, len(s.line)+len(runes))
+	line = append(line, s.line[:s.cursor]...)
+	line = append(line, runes...)
+	s.line = append(line, s.line[s.cursor:]...)
+	s.cursor += len(runes)
+}
+
+func (s *lineState) deleteRange(from int, to int) {
+	from, to = max(from, 0), min(to, len(s.line))
+	if from >= to {
+		return
+	}
+	s.line = append(s.line[:from:from], s.line[to:]...)
+	if s.cursor > to {
+		s.cursor -= to - from
+	} else if s.cursor > from {
+		s.cursor = from
+	}
+}
+
+func (s *lineState) setLine(line []rune) {
+	s.line = append([]rune{}, line...)
+	s.cursor = len(s.line)
+}
+
+// browse moves through the history, direction -1 is older
+func (s *lineState) browse(direction int) {
+	entries := s.editor.history()
+	pos := s.historyPos + direction
+	if pos < 0 || pos > len(entries) {
+		return
+	}
+	if s.historyPos == len(entries) {
+		s.draft = s.line
+	}
+	s.historyPos = pos
+	if pos == len(entries) {
+		s.setLine(s.draft)
+	} else {
+		s.setLine([]rune(entries[pos]))
+	}
+}
+
+// complete completes the word before the cursor, listing the candidates on
+// the second Tab when they have no longer common prefix
+func (s *lineState) complete(tabbed bool) {
+	e := s.editor
+	if e.Complete == nil {
+		return
+	}
+	before := string(s.line[:s.cursor])
+	words := strings.Fields(before)
+	prefix := ""
+	if len(words) > 0 && !strings.HasSuffix(before, " ") {
+		prefix = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+
+	candidates := Filter(e.Complete(words), prefix)
+	replace := func(word string) {
+		s.deleteRange(s.cursor-len([]rune(prefix)), s.cursor)
+		s.insert(word)
+	}
+	switch {
+	case len(candidates) == 0:
+		fmt.Fprint(e.Out, "\a")
+	case len(candidates) == 1:
+		replace(Quote(candidates[0].Word) + " ")
+	default:
+		common := commonPrefix(candidates)
+		if strings.HasPrefix(common, prefix) && len(common) > len(prefix) {
+			replace(common)
+			return
+		}
+		if !tabbed {
+			s.tabbed = true
+			fmt.Fprint(e.Out, "\a")
+			return
+		}
+		fmt.Fprint(e.Out, "\r\n")
+		for _, candidate := range candidates {
+			if candidate.Description != "" {
+				fmt.Fprintf(e.Out, "%-20s %s\r\n", candidate.Word, candidate.Description)
+			} else {
+				fmt.Fprintf(e.Out, "%s\r\n", candidate.Word)
+			}
+		}
+	}
+}
+
+// search runs a reverse incremental search of the history and returns the key
+// that ended it for the caller to handle, or 0 when it was cancelled
+func (s *lineState) search() (rune, error) {
+	e := s.editor
+	entries := e.history()
+	original := s.line
+	var query []rune
+	match := len(entries)
+	failed := false
+
+	// find looks for the query in the entries older than from
+	find := func(from int) {
+		for i := from - 1; i >= 0; i-- {
+			if strings.Contains(entries[i], string(query)) {
+				match, failed = i, false
+				s.setLine([]rune(entries[i]))
+				return
+			}
+		}
+		failed = true
+	}
+	draw := func() {
+		label := "reverse-i-search"
+		if failed {
+			label = "failed " + label
+		}
+		fmt.Fprintf(e.Out, "\r(%s)`%s': %s\x1b[K", label, string(query), string(s.line))
+	}
+
+	draw()
+	for {
+		key, err := e.ReadKey()
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case key == KeyCtrlR:
+			find(match)
+		case key == KeyDelete || key == KeyBackspace:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				find(len(entries))
+			}
+		case key == KeyCtrlG || key == KeyCtrlC:
+			s.setLine(original)
+			return 0, nil
+		case key <= unicode.MaxRune && unicode.IsPrint(key):
+			query = append(query, key)
+			find(min(match+1, len(entries)))
+		default:
+			return key, nil
+		}
+		draw()
+	}
+}
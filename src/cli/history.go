@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MaxHistory is the number of command lines kept in the history
+const MaxHistory = 1000
+
+// History is the list of the command lines entered in the editor, oldest
+// first, persisted to a file when it has a path. Skip reports the lines that
+// must not be kept, such as the lines giving a secret; nil keeps them all.
+type History struct {
+	Skip    func(line string) bool
+	path    string
+	entries []string
+}
+
+// HistoryPath returns the file the history is saved to, under the user config directory
+func HistoryPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the config directory: %w", err)
+	}
+	return filepath.Join(dir, "gotracker", "history"), nil
+}
+
+// LoadHistory reads the history saved at path, which does not need to exist
+// yet. An empty path gives an in-memory history.
+func LoadHistory(path string) (*History, error) {
+	h := &History{path: path}
+	if path == "" {
+		return h, nil
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			h.entries = append(h.entries, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	// Rewrite the file once it grew over the limit
+	if len(h.entries) > MaxHistory {
+		h.entries = h.entries[len(h.entries)-MaxHistory:]
+		if err := h.save(); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// Entries returns the command lines of the history, oldest first
+func (h *History) Entries() []string {
+	return h.entries
+}
+
+// Add appends a command line to the history and its file, blank lines,
+// repeats of the previous line and the lines Skip reports are skipped
+func (h *History) Add(line string) error {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.Contains(line, "\n") {
+		return nil
+	}
+	if h.Skip != nil && h.Skip(line) {
+		return nil
+	}
+	if len(h.entries) > 0 && h.entries[len(h.entries)-1] == line {
+		return nil
+	}
+	h.entries = append(h.entries, line)
+	if h.path == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(h.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	file, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	defer file.Close()
+	if _, err := fmt.Fprintln(file, line); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return nil
+}
+
+// save rewrites the history file with the entries in memory
+func (h *History) save() error {
+	err := os.WriteFile(h.path, []byte(strings.Join(h.entries, "\n")+"\n"), 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// secretRegistry returns a registry where 'reset <token>' is secret and
+// 'add food' is not
+func secretRegistry(aliases map[string]string) *Registry {
+	r := NewRegistry()
+	r.Register(
+		&Command{Name: "reset", Args: []Arg{{Name: "token", Optional: true}}, Secret: true},
+		&Command{Name: "add", Subcommands: []*Command{{Name: "food", Args: []Arg{{Name: "food_id", Type: Int}}}}},
+	)
+	r.Aliases = func() map[string]string { return aliases }
+	return r
+}
+
+func TestSecret(t *testing.T) {
+	r := secretRegistry(map[string]string{"rt": "reset $1", "af": "add food $1", "chain": "rt $1", "loop": "loop"})
+	tests := []struct {
+		line   string
+		secret bool
+	}{
+		{"reset 5f2a", true},
+		{"reset", true},
+		{"add food 1; reset 5f2a", true},
+		{"reset '5f2a", true},
+		{"rt 5f2a", true},
+		{"chain 5f2a", true},
+		{"add food 1", false},
+		{"af 1", false},
+		{"loop", false},
+		{"unknown reset", false},
+		{"help reset", false},
+	}
+	for _, test := range tests {
+		if secret := r.Secret(test.line); secret != test.secret {
+			t.Errorf("Secret(%q) = %v, want %v", test.line, secret, test.secret)
+		}
+	}
+}
+
+func TestHistorySkip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	h, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Skip = secretRegistry(nil).Secret
+	for _, line := range []string{"add food 1", "reset 5f2a", "  ", "add food 1", "add food 2"} {
+		if err := h.Add(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{"add food 1", "add food 2"}
+	if !slices.Equal(h.Entries(), want) {
+		t.Errorf("history = %q, want %q", h.Entries(), want)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "add food 1\nadd food 2\n" {
+		t.Errorf("history file = %q", data)
+	}
+	loaded, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(loaded.Entries(), want) {
+		t.Errorf("loaded history = %q, want %q", loaded.Entries(), want)
+	}
+}
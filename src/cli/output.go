@@ -0,0 +1,209 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// DefaultFormat is the output format used when none is given
+const DefaultFormat = "text"
+
+// Result is the structured outcome of a command.
+//
+// Data is encoded as is by the JSON formatter, so its type is the JSON schema
+// of the command. Rows is the struct or slice of structs laid out by the CSV
+// and table formatters, one column per field named after its json tag; Data
+// is laid out when Rows is nil. Text is the human readable rendering, the text
+// formatter falls back to the table layout when it is empty.
+type Result struct {
+	Data interface{}
+	Rows interface{}
+	Text string
+}
+
+// Formatter writes a result in an output format
+type Formatter func(w io.Writer, result *Result) error
+
+var formatters = map[string]Formatter{
+	"text":  formatText,
+	"json":  formatJSON,
+	"csv":   formatCSV,
+	"table": formatTable,
+}
+
+// RegisterFormatter adds an output format, replacing the format with the same name
+func RegisterFormatter(name string, formatter Formatter) {
+	formatters[name] = formatter
+}
+
+// Formats returns the names of the output formats in alphabetical order
+func Formats() []string {
+	names := make([]string, 0, len(formatters))
+	for name := range formatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupFormatter returns the formatter of an output format
+func LookupFormatter(name string) (Formatter, error) {
+	formatter, ok := formatters[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown output format '%s', expected one of %s", name, strings.Join(Formats(), ", "))
+	}
+	return formatter, nil
+}
+
+func formatText(w io.Writer, result *Result) error {
+	if result.Text == "" {
+		return formatTable(w, result)
+	}
+	_, err := io.WriteString(w, result.Text)
+	return err
+}
+
+func formatJSON(w io.Writer, result *Result) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result.Data)
+}
+
+func formatCSV(w io.Writer, result *Result) error {
+	header, rows := layout(result)
+	if header == nil {
+		return nil
+	}
+	writer := csv.NewWriter(w)
+	writer.Write(header)
+	writer.WriteAll(rows)
+	return writer.Error()
+}
+
+func formatTable(w io.Writer, result *Result) error {
+	header, rows := layout(result)
+	if header == nil {
+		return nil
+	}
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, strings.ToUpper(strings.Join(header, "\t")))
+	for _, row := range rows {
+		fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+	return writer.Flush()
+}
+
+// layout returns the header and the cells of the rows of a result. Nested
+// slices, maps and structs have no place in a flat layout and are left out.
+func layout(result *Result) ([]string, [][]string) {
+	rows := result.Rows
+	if rows == nil {
+		rows = result.Data
+	}
+	value := reflect.ValueOf(rows)
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil, nil
+		}
+		value = value.Elem()
+	}
+
+	var items []reflect.Value
+	switch value.Kind() {
+	case reflect.Invalid:
+		return nil, nil
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			items = append(items, value.Index(i))
+		}
+	default:
+		items = []reflect.Value{value}
+	}
+
+	elemType := value.Type()
+	if value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+		elemType = elemType.Elem()
+	}
+	for elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		// Plain values get a single column
+		rows := make([][]string, len(items))
+		for i, item := range items {
+			rows[i] = []string{cell(item)}
+		}
+		return []string{"value"}, rows
+	}
+
+	var header []string
+	var fields []int
+	for i := 0; i < elemType.NumField(); i++ {
+		field := elemType.Field(i)
+		name, ok := columnName(field)
+		if !ok {
+			continue
+		}
+		header = append(header, name)
+		fields = append(fields, i)
+	}
+
+	cells := make([][]string, 0, len(items))
+	for _, item := range items {
+		for item.Kind() == reflect.Ptr {
+			item = item.Elem()
+		}
+		row := make([]string, len(fields))
+		if item.IsValid() {
+			for j, index := range fields {
+				row[j] = cell(item.Field(index))
+			}
+		}
+		cells = append(cells, row)
+	}
+	return header, cells
+}
+
+// columnName returns the column of a struct field, named after its json tag
+func columnName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+	kind := field.Type.Kind()
+	if kind == reflect.Ptr {
+		kind = field.Type.Elem().Kind()
+	}
+	if kind == reflect.Slice || kind == reflect.Map || kind == reflect.Struct {
+		return "", false
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return "", false
+	}
+	if name == "" {
+		name = field.Name
+	}
+	return name, true
+}
+
+// cell formats a value for CSV and table output, nil pointers are empty
+func cell(value reflect.Value) string {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return ""
+		}
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'f', -1, 64)
+	}
+	return fmt.Sprint(value.Interface())
+}
@@ -0,0 +1,429 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ErrEmptyCommand is returned when executing a blank line
+var ErrEmptyCommand = errors.New("empty command")
+
+// Printer localizes the messages of the registry: help, usage and errors
+type Printer interface {
+	Translate(message string) string
+	Sprintf(format string, a ...interface{}) string
+}
+
+// english prints the messages as they are written
+type english struct{}
+
+func (english) Translate(message string) string { return message }
+
+func (english) Sprintf(format string, a ...interface{}) string { return fmt.Sprintf(format, a...) }
+
+// UnknownCommandError is returned when a command or subcommand does not exist
+type UnknownCommandError struct {
+	Path        []string // Path of the parent command, empty for a top level command
+	Name        string
+	Suggestions []string
+	printer     Printer
+}
+
+func (e *UnknownCommandError) Error() string {
+	p := e.printer
+	if p == nil {
+		p = english{}
+	}
+	var message string
+	if len(e.Path) > 0 {
+		message = p.Sprintf("unknown '%s' type '%s'", strings.Join(e.Path, " "), e.Name)
+	} else {
+		message = p.Sprintf("unknown command '%s'", e.Name)
+	}
+	if len(e.Suggestions) > 0 {
+		message += p.Sprintf(", did you mean '%s'?", strings.Join(e.Suggestions, p.Translate("' or '")))
+	}
+	return message
+}
+
+// Registry holds the commands of the application, dispatches command lines to
+// them and renders their results to Out in the Output format. Printer
+// localizes the help and the errors, nil for English. Aliases returns the
+// user-defined aliases by name, a command line starting with an alias runs
+// its definition; nil when there are none.
+type Registry struct {
+	Out      io.Writer
+	Output   string
+	Printer  Printer
+	Aliases  func() map[string]string
+	commands map[string]*Command
+	order    []string
+}
+
+// NewRegistry creates a registry containing only the help command
+func NewRegistry() *Registry {
+	r := &Registry{Out: os.Stdout, Output: DefaultFormat, commands: make(map[string]*Command)}
+	r.Register(&Command{
+		Name:    "help",
+		Summary: "Show the list of commands or the usage of a command",
+		Args:    []Arg{{Name: "command", Optional: true, Rest: true}},
+		Run: func(inv *Invocation) (*Result, error) {
+			return r.Help(strings.Fields(inv.String("command")))
+		},
+	})
+	return r
+}
+
+func (r *Registry) printer() Printer {
+	if r.Printer == nil {
+		return english{}
+	}
+	return r.Printer
+}
+
+// Register adds commands to the registry, replacing commands with the same name
+func (r *Registry) Register(commands ...*Command) {
+	for _, command := range commands {
+		if _, exists := r.commands[command.Name]; !exists {
+			r.order = append(r.order, command.Name)
+		}
+		r.commands[command.Name] = command
+	}
+}
+
+// Lookup returns the top level command with the given name
+func (r *Registry) Lookup(name string) (*Command, bool) {
+	command, ok := r.commands[name]
+	return command, ok
+}
+
+// Commands returns the top level commands in registration order
+func (r *Registry) Commands() []*Command {
+	commands := make([]*Command, len(r.order))
+	for i, name := range r.order {
+		commands[i] = r.commands[name]
+	}
+	return commands
+}
+
+// Resolve walks the words down the subcommands and returns the command to run,
+// its path and the remaining words
+func (r *Registry) Resolve(words []string) (*Command, []string, []string, error) {
+	if len(words) == 0 {
+		return nil, nil, nil, ErrEmptyCommand
+	}
+	command, ok := r.commands[words[0]]
+	if !ok {
+		return nil, nil, nil, &UnknownCommandError{Name: words[0], Suggestions: Suggest(words[0], r.order), printer: r.printer()}
+	}
+
+	path := []string{words[0]}
+	words = words[1:]
+	for len(command.Subcommands) > 0 {
+		if len(words) == 0 || strings.HasPrefix(words[0], "--") {
+			return nil, nil, nil, &UsageError{Path: path, Command: command, Message: r.printer().Translate("missing type"), printer: r.printer()}
+		}
+		sub := command.Subcommand(words[0])
+		if sub == nil {
+			names := make([]string, len(command.Subcommands))
+			for i, s := range command.Subcommands {
+				names[i] = s.Name
+			}
+			return nil, nil, nil, &UnknownCommandError{Path: path, Name: words[0], Suggestions: Suggest(words[0], names), printer: r.printer()}
+		}
+		command = sub
+		path = append(path, words[0])
+		words = words[1:]
+	}
+	return command, path, words, nil
+}
+
+// Secret reports whether a command line runs a command whose arguments are
+// secrets, directly or through an alias, to keep it out of the history. A
+// line that cannot be tokenized is checked word by word.
+func (r *Registry) Secret(line string) bool {
+	commands, err := Split(line)
+	if err != nil {
+		commands = [][]string{strings.Fields(line)}
+	}
+	for _, words := range commands {
+		if r.secret(words, nil) {
+			return true
+		}
+	}
+	return false
+}
+
+// secret reports whether words run a secret command, expanding the aliases
+// like executeWords. expanding holds the aliases being expanded.
+func (r *Registry) secret(words []string, expanding []string) bool {
+	if len(words) == 0 {
+		return false
+	}
+	if _, isCommand := r.commands[words[0]]; isCommand || r.Aliases == nil {
+		command, _, _, err := r.Resolve(words)
+		return err == nil && command.Secret
+	}
+	alias := words[0]
+	definition, ok := r.Aliases()[alias]
+	if !ok || slices.Contains(expanding, alias) {
+		return false
+	}
+	commands, err := Split(definition)
+	if err != nil {
+		return false
+	}
+	for _, words := range commands {
+		if r.secret(words, append(expanding, alias)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Execute tokenizes a command line, parses it against the matching command and runs it
+func (r *Registry) Execute(line string) error {
+	words, err := Tokenize(line)
+	if err != nil {
+		return &UsageError{Message: r.printer().Translate(err.Error()), printer: r.printer()}
+	}
+	return r.ExecuteWords(words)
+}
+
+// ExecuteWords runs an already tokenized command line. The global
+// --output <format> flag may appear anywhere before a -- terminator or the
+// words of a Rest argument and overrides the output format of the registry
+// for this command.
+func (r *Registry) ExecuteWords(words []string) error {
+	return r.executeWords(words, nil)
+}
+
+// executeWords runs a command line, expanding it when it starts with an
+// alias. expanding holds the aliases being expanded.
+func (r *Registry) executeWords(words []string, expanding []string) error {
+	words, output, err := r.splitOutputFlag(words)
+	if err != nil {
+		return &UsageError{Message: r.printer().Translate(err.Error()), printer: r.printer()}
+	}
+	if len(words) > 0 && r.Aliases != nil {
+		// Commands take precedence over aliases of the same name
+		if _, isCommand := r.commands[words[0]]; !isCommand {
+			if definition, ok := r.Aliases()[words[0]]; ok {
+				return r.executeAlias(words[0], definition, words[1:], output, expanding)
+			}
+		}
+	}
+	if output == "" {
+		output = r.Output
+	}
+	formatter, err := LookupFormatter(output)
+	if err != nil {
+		message := r.printer().Sprintf("unknown output format '%s', expected one of %s", output, strings.Join(Formats(), ", "))
+		return &UsageError{Message: message, printer: r.printer()}
+	}
+
+	// A command may fail after producing part of its result, show it anyway
+	result, err := r.Run(words)
+	if result != nil {
+		if formatErr := formatter(r.Out, result); formatErr != nil && err == nil {
+			err = fmt.Errorf(r.printer().Translate("error writing output: %w"), formatErr)
+		}
+	}
+	return err
+}
+
+// Run resolves words to a command and runs it, returning its result without
+// rendering it, for front ends drawing the results themselves
+func (r *Registry) Run(words []string) (*Result, error) {
+	command, path, rest, err := r.Resolve(words)
+	if err != nil {
+		return nil, err
+	}
+	inv, err := command.parse(r.printer(), path, rest)
+	if err != nil {
+		return nil, err
+	}
+	return command.Run(inv)
+}
+
+// splitOutputFlag removes the global --output flag from words and returns its
+// value. The words of a Rest argument are the command's own and are kept as
+// they are, so that `alias j = history food --output json` keeps the flag in
+// the definition.
+func (r *Registry) splitOutputFlag(words []string) ([]string, string, error) {
+	var rest []string
+	var output string
+	var command *Command
+	positional := 0
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		switch {
+		case word == "--":
+			return append(rest, words[i:]...), output, nil
+		case word == "--output":
+			if i+1 >= len(words) {
+				return nil, "", errors.New("flag --output needs a value")
+			}
+			i++
+			output = words[i]
+		case strings.HasPrefix(word, "--output="):
+			output = strings.TrimPrefix(word, "--output=")
+		default:
+			rest = append(rest, word)
+			switch {
+			case len(rest) == 1:
+				// Aliases and unknown commands leave command nil
+				command = r.commands[word]
+			case command == nil:
+			case len(command.Subcommands) > 0:
+				command = command.Subcommand(word)
+			case strings.HasPrefix(word, "--"):
+				// The value of a flag is not a positional argument
+				name, _, hasValue := strings.Cut(strings.TrimPrefix(word, "--"), "=")
+				if flag := command.flag(name); flag != nil && flag.Type != Bool && !hasValue && i+1 < len(words) {
+					i++
+					rest = append(rest, words[i])
+				}
+			default:
+				positional++
+				if positional == len(command.Args) && command.Args[positional-1].Rest {
+					return append(rest, words[i+1:]...), output, nil
+				}
+			}
+		}
+	}
+	return rest, output, nil
+}
+
+// HelpEntry is a line of the help output.
+//
+//	{"command": "log water", "usage": "log water <amount> [count]", "summary": "...", "flags": {"asian": "..."}}
+type HelpEntry struct {
+	Command string            `json:"command"`
+	Usage   string            `json:"usage"`
+	Summary string            `json:"summary"`
+	Flags   map[string]string `json:"flags,omitempty"`
+}
+
+func (r *Registry) helpEntry(command *Command, parents []string) HelpEntry {
+	entry := HelpEntry{
+		Command: strings.Join(append(append([]string{}, parents...), command.Name), " "),
+		Usage:   command.Usage(parents...),
+		Summary: r.printer().Translate(command.Summary),
+	}
+	for _, flag := range command.Flags {
+		if entry.Flags == nil {
+			entry.Flags = make(map[string]string)
+		}
+		entry.Flags[flag.Name] = r.printer().Translate(flag.Usage)
+	}
+	return entry
+}
+
+// Help returns the list of commands, or the usage of the command at path
+func (r *Registry) Help(path []string) (*Result, error) {
+	p := r.printer()
+	var text strings.Builder
+	var entries []HelpEntry
+	if len(path) == 0 {
+		fmt.Fprintln(&text, p.Translate("Available commands:"))
+		for _, command := range r.Commands() {
+			entry := r.helpEntry(command, nil)
+			fmt.Fprintf(&text, "  - %s: %s\n", command.Name, entry.Summary)
+			entries = append(entries, entry)
+		}
+		fmt.Fprintln(&text, p.Translate("Type 'help <command>' for the usage of a command."))
+		return &Result{Data: entries, Text: text.String()}, nil
+	}
+
+	command, ok := r.commands[path[0]]
+	if !ok {
+		return nil, &UnknownCommandError{Name: path[0], Suggestions: Suggest(path[0], r.order), printer: p}
+	}
+	var parents []string
+	for _, name := range path[1:] {
+		sub := command.Subcommand(name)
+		if sub == nil {
+			break
+		}
+		parents = append(parents, command.Name)
+		command = sub
+	}
+
+	if len(command.Subcommands) > 0 {
+		fmt.Fprintf(&text, "%s: %s\n", strings.Join(append(parents, command.Name), " "), p.Translate(command.Summary))
+		fmt.Fprintln(&text, p.Translate("Usage:"))
+		for _, sub := range command.Subcommands {
+			entry := r.helpEntry(sub, append(parents, command.Name))
+			fmt.Fprintf(&text, "  %s\n", entry.Usage)
+			if entry.Summary != "" {
+				fmt.Fprintf(&text, "      %s\n", entry.Summary)
+			}
+			entries = append(entries, entry)
+		}
+		return &Result{Data: entries, Text: text.String()}, nil
+	}
+	entry := r.helpEntry(command, parents)
+	fmt.Fprintln(&text, p.Sprintf("Usage: %s", entry.Usage))
+	if entry.Summary != "" {
+		fmt.Fprintf(&text, "  %s\n", entry.Summary)
+	}
+	for _, flag := range command.Flags {
+		fmt.Fprintf(&text, "  --%s: %s\n", flag.Name, entry.Flags[flag.Name])
+	}
+	return &Result{Data: []HelpEntry{entry}, Text: text.String()}, nil
+}
+
+// Suggest returns the candidates close to name, closest first
+func Suggest(name string, candidates []string) []string {
+	type match struct {
+		name     string
+		distance int
+	}
+	var matches []match
+	for _, candidate := range candidates {
+		distance := levenshtein(name, candidate)
+		if (distance <= 2 && distance < len(name)) || (len(name) >= 2 && strings.HasPrefix(candidate, name)) {
+			matches = append(matches, match{candidate, distance})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].distance < matches[j].distance
+	})
+
+	var names []string
+	for i, m := range matches {
+		if i == 3 {
+			break
+		}
+		names = append(names, m.name)
+	}
+	return names
+}
+
+// levenshtein returns the edit distance between two words
+func levenshtein(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrStop can be returned by the execute function of RunScript to end the
+// script early without counting as a failure
+var ErrStop = errors.New("stop")
+
+// ScriptError is returned by RunScript when a command fails while stop-on-error is set
+type ScriptError struct {
+	Line    int
+	Command string
+	Err     error
+}
+
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf("line %d: %s: %v", e.Line, e.Command, e.Err)
+}
+
+func (e *ScriptError) Unwrap() error {
+	return e.Err
+}
+
+// RunScript executes the commands of a script, one per line. Blank lines and
+// lines starting with # are skipped, 'set -e' stops the script at the first
+// failing command and 'set +e' goes back to continuing after failures. It
+// returns the number of failed commands.
+func RunScript(src io.Reader, execute func(line string) error) (int, error) {
+	scanner := bufio.NewScanner(src)
+	stopOnError := false
+	failures := 0
+
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		switch strings.Join(strings.Fields(line), " ") {
+		case "set -e":
+			stopOnError = true
+			continue
+		case "set +e":
+			stopOnError = false
+			continue
+		}
+
+		err := execute(line)
+		if errors.Is(err, ErrStop) {
+			return failures, nil
+		}
+		if err != nil {
+			failures++
+			if stopOnError {
+				return failures, &ScriptError{Line: lineNumber, Command: line, Err: err}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return failures, fmt.Errorf("error reading script: %w", err)
+	}
+
+	return failures, nil
+}
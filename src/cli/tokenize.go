@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tokenize splits a command line into words the way a shell would: words are
+// separated by any amount of whitespace, single quotes keep their content
+// as-is, double quotes allow \" and \\ escapes, and a backslash outside quotes
+// escapes the next character.
+func Tokenize(line string) ([]string, error) {
+	commands, err := tokenize(line, false)
+	if err != nil || len(commands) == 0 {
+		return nil, err
+	}
+	return commands[0], nil
+}
+
+// Split tokenizes a line holding several commands separated by semicolons,
+// a quoted or escaped semicolon is part of its word. Empty commands are dropped.
+func Split(line string) ([][]string, error) {
+	return tokenize(line, true)
+}
+
+// tokenize splits line into words, and into commands at the unquoted
+// semicolons when split is set
+func tokenize(line string, split bool) ([][]string, error) {
+	var commands [][]string
+	var words []string
+	var word strings.Builder
+	inWord := false
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+
+		case r == ';' && split:
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+			if len(words) > 0 {
+				commands = append(commands, words)
+				words = nil
+			}
+
+		case r == '\\':
+			if i+1 >= len(runes) {
+				return nil, fmt.Errorf("trailing backslash")
+			}
+			i++
+			word.WriteRune(runes[i])
+			inWord = true
+
+		case r == '\'':
+			end := i + 1
+			for end < len(runes) && runes[end] != '\'' {
+				end++
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			word.WriteString(string(runes[i+1 : end]))
+			i = end
+			inWord = true
+
+		case r == '"':
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && (runes[i+1] == '"' || runes[i+1] == '\\') {
+					i++
+				}
+				word.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated double quote")
+			}
+			inWord = true
+
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	if len(words) > 0 || !split {
+		commands = append(commands, words)
+	}
+
+	return commands, nil
+}
+
+// Quote returns word quoted so that Tokenize reads it back unchanged
+func Quote(word string) string {
+	if word != "" && !strings.ContainsAny(word, " \t\n\r'\"\\") {
+		return word
+	}
+	return "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+}
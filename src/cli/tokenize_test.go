@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		line  string
+		words []string
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"add food 123 100", []string{"add", "food", "123", "100"}},
+		{"  add \t food\n123  ", []string{"add", "food", "123"}},
+		{`search food 'peanut butter'`, []string{"search", "food", "peanut butter"}},
+		{`search food "peanut butter"`, []string{"search", "food", "peanut butter"}},
+		{`alias set s 'search food $1'`, []string{"alias", "set", "s", "search food $1"}},
+		{`echo "say \"hi\" \\ \n"`, []string{"echo", `say "hi" \ \n`}},
+		{`echo 'no \escape'`, []string{"echo", `no \escape`}},
+		{`peanut\ butter`, []string{"peanut butter"}},
+		{`a'b'"c"d`, []string{"abcd"}},
+		{`''`, []string{""}},
+		{`a;b`, []string{"a;b"}},
+	}
+	for _, test := range tests {
+		words, err := Tokenize(test.line)
+		if err != nil {
+			t.Errorf("Tokenize(%q): %v", test.line, err)
+			continue
+		}
+		if !slices.Equal(words, test.words) {
+			t.Errorf("Tokenize(%q) = %q, want %q", test.line, words, test.words)
+		}
+	}
+}
+
+func TestTokenizeErrors(t *testing.T) {
+	for _, line := range []string{`echo \`, `echo 'open`, `echo "open`, `echo "open\"`} {
+		if words, err := Tokenize(line); err == nil {
+			t.Errorf("Tokenize(%q) = %q, want an error", line, words)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	commands, err := Split(`add food 1 100; ;today;echo 'a;b' c\;d`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"add", "food", "1", "100"}, {"today"}, {"echo", "a;b", "c;d"}}
+	if !slices.EqualFunc(commands, want, slices.Equal) {
+		t.Errorf("Split = %q, want %q", commands, want)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	for _, word := range []string{"plain", "", "two words", "it's", `back\slash`, `"quoted"`, "tab\there"} {
+		words, err := Tokenize(Quote(word))
+		if err != nil {
+			t.Errorf("Tokenize(Quote(%q)): %v", word, err)
+			continue
+		}
+		if len(words) != 1 || words[0] != word {
+			t.Errorf("Tokenize(Quote(%q)) = %q", word, words)
+		}
+	}
+}
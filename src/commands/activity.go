@@ -0,0 +1,159 @@
+package commands
+
+import (
+	"gotracker/cli"
+	"gotracker/exercise"
+	suser "gotracker/structs"
+	"math"
+	"strconv"
+	"strings"
+)
+
+func logExercise(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	activity, err := exercise.Lookup(inv.String("activity"))
+	if err != nil {
+		return nil, ctx.Errorf("%w, use 'list activity' to see the catalogue", err)
+	}
+	minutes := inv.Int("minutes")
+	if minutes <= 0 {
+		return nil, ctx.Errorf("the duration must be a positive number of minutes")
+	}
+	intensity := exercise.DefaultIntensity
+	if inv.Has("intensity") {
+		intensity = inv.String("intensity")
+	}
+	met, err := activity.METFor(intensity)
+	if err != nil {
+		return nil, err
+	}
+	calories := exercise.CaloriesBurned(met, float64(ctx.Session.User.Weight), minutes)
+	date, err := ctx.Date(inv)
+	if err != nil {
+		return nil, err
+	}
+
+	// Save the exercise history to the database
+	err = ctx.Store.AddExerciseHistory(ctx.Session.User.ID, date, activity.Name, minutes, intensity, calories)
+	if err != nil {
+		return nil, ctx.Errorf("error saving exercise history: %w", err)
+	}
+	return &cli.Result{
+		Data: ExerciseEntry{Date: date, Activity: activity.Name, Minutes: minutes, Intensity: intensity, Calories: math.Round(calories)},
+		Text: ctx.Sprintf("%d minutes of %s (%s) logged, %.0f kcal burned.\n", minutes, activity.Name, intensity, calories),
+	}, nil
+}
+
+func logWater(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	var amount int
+	switch inv.String("amount") {
+	case "glass":
+		amount = suser.GlassML
+	case "bottle":
+		amount = suser.BottleML
+	default:
+		var err error
+		amount, err = strconv.Atoi(inv.String("amount"))
+		if err != nil || amount <= 0 {
+			return nil, ctx.Errorf("the amount must be a positive number of ml, 'glass' or 'bottle'")
+		}
+	}
+	if inv.Has("count") {
+		if inv.Int("count") <= 0 {
+			return nil, ctx.Errorf("the count must be a positive number")
+		}
+		amount *= inv.Int("count")
+	}
+	date, err := ctx.Date(inv)
+	if err != nil {
+		return nil, err
+	}
+
+	// Save the water history to the database
+	err = ctx.Store.AddWaterHistory(ctx.Session.User.ID, date, amount)
+	if err != nil {
+		return nil, ctx.Errorf("error saving water history: %w", err)
+	}
+	return &cli.Result{
+		Data: WaterEntry{Date: date, Amount: amount},
+		Text: ctx.Sprintf("%d ml of water logged.\n", amount),
+	}, nil
+}
+
+func summary(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	user := &ctx.Session.User
+	date, err := ctx.Date(inv)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sum the calories eaten that day
+	foodsByDay, err := ctx.Store.GetFoodQuantitiesByDay(user.ID, date, date)
+	if err != nil {
+		return nil, ctx.Errorf("error fetching food history: %w", err)
+	}
+	var intake, foodWater float64
+	for foodID, quantity := range foodsByDay[date] {
+		// Beverages and watery foods count towards hydration, even when
+		// their energy is not reported
+		water, err := ctx.FDC.GetFoodWater(ctx.RequestContext(), foodID)
+		if err == nil {
+			foodWater += water * quantity / 100
+		}
+		kcal, err := ctx.FDC.GetFoodEnergy(ctx.RequestContext(), foodID)
+		if ctx.RequestContext().Err() != nil {
+			return nil, ctx.RequestContext().Err()
+		}
+		if err != nil {
+			ctx.Fprintf(ctx.Err, "Skipping food ID %d: %v\n", foodID, err)
+			continue
+		}
+		intake += kcal * quantity / 100
+	}
+
+	minutes, burned, err := ctx.Store.GetExerciseTotals(user.ID, date)
+	if err != nil {
+		return nil, ctx.Errorf("error fetching exercise history: %w", err)
+	}
+	target, err := ctx.Store.GetTargetCalories(user.ID)
+	if err != nil {
+		return nil, ctx.Errorf("error fetching calorie target: %w", err)
+	}
+
+	net := intake - burned
+	result := Summary{
+		Date:     date,
+		Intake:   math.Round(intake),
+		Exercise: math.Round(burned),
+		Net:      math.Round(net),
+	}
+	var text strings.Builder
+	ctx.Fprintf(&text, "Summary for %s:\n", date)
+	ctx.Fprintf(&text, " - Intake: %.0f kcal\n", intake)
+	ctx.Fprintf(&text, " - Exercise: %.0f kcal\n", burned)
+	ctx.Fprintf(&text, " - Net: %.0f kcal\n", net)
+	if target > 0 {
+		result.Target = optional(math.Round(target))
+		result.Remaining = optional(math.Round(target - net))
+		ctx.Fprintf(&text, " - Target: %.0f kcal (%.0f kcal remaining)\n", target, target-net)
+	} else {
+		ctx.Fprintf(&text, " - Target: not set, use 'tdee --apply' to set one\n")
+	}
+
+	// Hydration progress, water logged plus water contained in foods
+	drank, err := ctx.Store.GetWaterTotal(user.ID, date)
+	if err != nil {
+		return nil, ctx.Errorf("error fetching water history: %w", err)
+	}
+	hydration := drank + int(math.Round(foodWater))
+	hydrationTarget := user.GetHydrationTarget(minutes)
+	progress := 0
+	if hydrationTarget > 0 {
+		progress = hydration * 100 / hydrationTarget
+	}
+	result.Hydration = hydration
+	result.HydrationTarget = hydrationTarget
+	result.HydrationPercent = progress
+	result.FoodWater = int(math.Round(foodWater))
+	ctx.Fprintf(&text, " - Hydration: %d / %d ml (%d%%, %d ml from food)\n", hydration, hydrationTarget, progress, result.FoodWater)
+	return &cli.Result{Data: result, Text: text.String()}, nil
+}
@@ -0,0 +1,117 @@
+package commands
+
+import (
+	"gotracker/cli"
+	"regexp"
+	"strings"
+)
+
+// aliasName matches the names an alias may take
+var aliasName = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+
+// aliases returns the aliases of the session user by name, for the registry
+// to expand. Errors are reported as warnings and leave the user without aliases.
+func (ctx *Context) aliases() map[string]string {
+	if !ctx.Session.LoggedIn() {
+		return nil
+	}
+	list, err := ctx.Store.GetAliases(ctx.Session.User.ID)
+	if err != nil {
+		ctx.Fprintf(ctx.Err, "Warning: aliases are unavailable: %v\n", err)
+		return nil
+	}
+	aliases := make(map[string]string, len(list))
+	for _, alias := range list {
+		aliases[alias[0]] = alias[1]
+	}
+	return aliases
+}
+
+// commandNames holds the names aliases may not shadow: the commands of the table and help
+var commandNames = map[string]bool{"help": true}
+
+func init() {
+	for _, spec := range Table {
+		commandNames[spec.Name] = true
+	}
+}
+
+// alias defines an alias with 'alias <name> = <commands>', shows one with
+// 'alias <name>' and lists them with 'alias' or 'alias list'
+func alias(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	name := inv.String("alias_name")
+	words := inv.Rest()
+	if name == "" || (name == "list" && len(words) == 0) {
+		return listAliases(ctx)
+	}
+	if len(words) == 0 {
+		definition, ok := ctx.aliases()[name]
+		if !ok {
+			return nil, ctx.Errorf("unknown alias '%s'", name)
+		}
+		entry := Alias{Name: name, Definition: definition}
+		return &cli.Result{Data: entry, Text: ctx.Sprintf("%s = %s\n", name, definition)}, nil
+	}
+	if words[0] != "=" || len(words) == 1 {
+		return nil, ctx.Errorf("expected 'alias %s = <commands>'", name)
+	}
+
+	if !aliasName.MatchString(name) || name == "list" {
+		return nil, ctx.Errorf("invalid alias name '%s', use letters, digits, '-' and '_'", name)
+	}
+	if commandNames[name] {
+		return nil, ctx.Errorf("'%s' is a command and cannot be an alias", name)
+	}
+	// A single word is a quoted command line, several words are requoted
+	definition := words[1]
+	if len(words) > 2 {
+		quoted := make([]string, len(words)-1)
+		for i, word := range words[1:] {
+			quoted[i] = cli.Quote(word)
+		}
+		definition = strings.Join(quoted, " ")
+	}
+	commands, err := cli.Split(definition)
+	if err != nil {
+		return nil, ctx.Errorf("invalid alias definition: %s", ctx.Translate(err.Error()))
+	}
+	if len(commands) == 0 {
+		return nil, ctx.Errorf("expected 'alias %s = <commands>'", name)
+	}
+
+	if err := ctx.Store.SetAlias(ctx.Session.User.ID, name, definition); err != nil {
+		return nil, ctx.Errorf("error saving alias: %w", err)
+	}
+	entry := Alias{Name: name, Definition: definition}
+	return &cli.Result{Data: entry, Text: ctx.Sprintf("Alias '%s' saved: %s\n", name, definition)}, nil
+}
+
+func listAliases(ctx *Context) (*cli.Result, error) {
+	list, err := ctx.Store.GetAliases(ctx.Session.User.ID)
+	if err != nil {
+		return nil, ctx.Errorf("error fetching aliases: %w", err)
+	}
+	if len(list) == 0 {
+		return &cli.Result{Data: []Alias{}, Text: ctx.Translate("No aliases defined, create one with 'alias <name> = <commands>'.\n")}, nil
+	}
+	result := make([]Alias, len(list))
+	var text strings.Builder
+	ctx.Fprintf(&text, "Aliases:\n")
+	for i, entry := range list {
+		result[i] = Alias{Name: entry[0], Definition: entry[1]}
+		ctx.Fprintf(&text, " - %s = %s\n", entry[0], entry[1])
+	}
+	return &cli.Result{Data: result, Text: text.String()}, nil
+}
+
+func unalias(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	name := inv.String("alias_name")
+	deleted, err := ctx.Store.DeleteAlias(ctx.Session.User.ID, name)
+	if err != nil {
+		return nil, ctx.Errorf("error deleting alias: %w", err)
+	}
+	if !deleted {
+		return nil, ctx.Errorf("unknown alias '%s'", name)
+	}
+	return &cli.Result{Data: Alias{Name: name}, Text: ctx.Sprintf("Alias '%s' deleted.\n", name)}, nil
+}
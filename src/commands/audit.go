@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"fmt"
+	"gotracker/cli"
+	suser "gotracker/structs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default and largest number of entries shown by audit
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 500
+)
+
+// auditEntryResult returns the result of an audit entry, in the time zone of the session user
+func auditEntryResult(ctx *Context, entry suser.AuditEntry) AuditEntry {
+	return AuditEntry{
+		ID:        entry.ID,
+		ChangedAt: entry.ChangedAt.In(ctx.Location()).Format(time.RFC3339),
+		ActorID:   entry.ActorID,
+		Actor:     entry.Actor,
+		SessionID: entry.SessionID,
+		UserID:    entry.UserID,
+		Table:     entry.Table,
+		RowID:     entry.RowID,
+		Action:    entry.Action,
+		OldValues: entry.OldValues,
+		NewValues: entry.NewValues,
+	}
+}
+
+// auditChanges describes the columns an entry changed: the old and new
+// values of an update, the values of an insert or a delete
+func auditChanges(entry suser.AuditEntry) string {
+	values := entry.NewValues
+	if values == nil {
+		values = entry.OldValues
+	}
+	var columns []string
+	for column := range values {
+		// The row and its owner are already shown
+		if column == "id" || column == "user_id" {
+			continue
+		}
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	var changes []string
+	for _, column := range columns {
+		switch {
+		case entry.Action != "update":
+			changes = append(changes, fmt.Sprintf("%s %s", column, auditValue(values[column])))
+		case fmt.Sprint(entry.OldValues[column]) != fmt.Sprint(entry.NewValues[column]):
+			changes = append(changes, fmt.Sprintf("%s %s → %s", column, auditValue(entry.OldValues[column]), auditValue(entry.NewValues[column])))
+		}
+	}
+	return strings.Join(changes, ", ")
+}
+
+// auditValue formats a value of a row, NULL as -
+func auditValue(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "-"
+	case float64:
+		// The JSON numbers, without exponent
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+// audit shows the latest changes of the data of the session user and the
+// changes it made, or those of another user for the administrators
+func audit(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	userID := ctx.Session.User.ID
+	if inv.Has("user") {
+		var err error
+		if userID, err = findUser(ctx, inv.String("user")); err != nil {
+			return nil, err
+		}
+	}
+	limit := defaultAuditLimit
+	if inv.Has("limit") {
+		limit = inv.Int("limit")
+		if limit <= 0 || limit > maxAuditLimit {
+			return nil, ctx.Errorf("the limit must be between 1 and %d", maxAuditLimit)
+		}
+	}
+	entries, err := ctx.Store.GetAuditLog(userID, inv.String("table"), limit)
+	if err != nil {
+		return nil, ctx.Errorf("error fetching audit log: %w", err)
+	}
+	if len(entries) == 0 {
+		return &cli.Result{Data: []AuditEntry{}, Text: ctx.Sprintf("No changes recorded.\n")}, nil
+	}
+
+	results := make([]AuditEntry, 0, len(entries))
+	var text strings.Builder
+	ctx.Fprintf(&text, "Changes, the latest first:\n")
+	for _, entry := range entries {
+		results = append(results, auditEntryResult(ctx, entry))
+		actor := entry.Actor
+		switch {
+		case entry.ActorID == 0:
+			actor = ctx.Translate("no logged in user")
+		case actor == "":
+			actor = fmt.Sprintf("#%d", entry.ActorID)
+		}
+		if entry.SessionID != "" {
+			actor += " (" + entry.SessionID + ")"
+		}
+		fmt.Fprintf(&text, " - %s | %s | %s %s %s", entry.ChangedAt.In(ctx.Location()).Format("2006-01-02 15:04:05"), actor, entry.Action, entry.Table, entry.RowID)
+		if changes := auditChanges(entry); changes != "" {
+			fmt.Fprintf(&text, ": %s", changes)
+		}
+		text.WriteString("\n")
+	}
+	return &cli.Result{Data: results, Text: text.String()}, nil
+}
@@ -0,0 +1,316 @@
+package commands
+
+import (
+	"errors"
+	"gotracker/auth"
+	"gotracker/cli"
+	suser "gotracker/structs"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrKeySession is returned by login and register over SSH, where the key of the connection chooses the user
+var ErrKeySession = errors.New("the SSH key of the connection chooses the user, login and register are not available over SSH")
+
+// ErrInvalidCredentials is returned when the username or the password of a login is wrong
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
+// ErrInvalidResetToken is returned when a reset token is unknown, expired or already used
+var ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+// username matches the usernames, which start with a letter so that they are never taken for an ID
+var username = regexp.MustCompile(`^[a-z][a-z0-9_.-]{2,31}$`)
+
+// password asks for a password with the prompt in the language of the session
+func (ctx *Context) password(prompt string) (string, error) {
+	if ctx.ReadPassword == nil {
+		return "", ctx.Errorf("cannot ask for a password, set GOTRACKER_PASSWORD")
+	}
+	password, err := ctx.ReadPassword(ctx.Translate(prompt))
+	if err != nil {
+		return "", ctx.Errorf("error reading password: %w", err)
+	}
+	return password, nil
+}
+
+// newPassword asks for a new password twice and returns its hash
+func newPassword(ctx *Context, prompt string) (string, error) {
+	password, err := ctx.password(prompt)
+	if err != nil {
+		return "", err
+	}
+	if err := auth.ValidatePassword(password); err != nil {
+		return "", err
+	}
+	confirmation, err := ctx.password("Confirm the password: ")
+	if err != nil {
+		return "", err
+	}
+	if confirmation != password {
+		return "", ctx.Errorf("the passwords do not match")
+	}
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return "", ctx.Errorf("error hashing password: %w", err)
+	}
+	return hash, nil
+}
+
+// checkCredentials validates a new username and email of a user, either may
+// be empty, and checks that no other user has them. userID is 0 for a new user.
+func checkCredentials(ctx *Context, userID int, name string, email string) error {
+	if name != "" {
+		if !username.MatchString(name) {
+			return ctx.Errorf("invalid username '%s': use 3 to 32 lowercase letters, digits, '.', '-' or '_', starting with a letter", name)
+		}
+		ownerID, err := ctx.Store.FindUser(name)
+		if err != nil {
+			return ctx.Errorf("error checking user existence: %w", err)
+		}
+		if ownerID != 0 && ownerID != userID {
+			return ctx.Errorf("the username '%s' is already taken", name)
+		}
+	}
+	if email != "" {
+		if address, err := mail.ParseAddress(email); err != nil || address.Address != email {
+			return ctx.Errorf("invalid email address '%s'", email)
+		}
+		ownerID, err := ctx.Store.FindUser(email)
+		if err != nil {
+			return ctx.Errorf("error checking user existence: %w", err)
+		}
+		if ownerID != 0 && ownerID != userID {
+			return ctx.Errorf("the email address '%s' is already used by another account", email)
+		}
+	}
+	return nil
+}
+
+// Login asks for the password of the user with the given username or email
+// and logs it in. Too many failed logins in a row lock the account for a
+// while. Accounts without a password cannot log in, they set their
+// credentials with a reset token, see ResetPassword.
+func Login(ctx *Context, login string) error {
+	if ctx.Session.Kind == SessionSSH {
+		return ErrKeySession
+	}
+	userID, err := ctx.Store.FindUser(strings.ToLower(login))
+	if err != nil {
+		return ctx.Errorf("error checking user existence: %w", err)
+	}
+
+	password, err := ctx.password("Password: ")
+	if err != nil {
+		return err
+	}
+	if userID == 0 {
+		auth.CheckPassword("", password)
+		return ErrInvalidCredentials
+	}
+	if err := checkPassword(ctx, userID, password); err != nil {
+		return err
+	}
+	return startSession(ctx, userID)
+}
+
+// checkPassword checks the password of a user under the lockout: the attempt
+// is counted as failed before the password is checked, and cleared when it
+// matches, so that parallel guesses are counted as well
+func checkPassword(ctx *Context, userID int, password string) error {
+	credentials, allowed, err := ctx.Store.RecordLoginAttempt(userID, auth.MaxFailedLogins, auth.LockoutDuration)
+	if err != nil {
+		return ctx.Errorf("error recording failed login: %w", err)
+	}
+	if !allowed {
+		return ctx.Errorf("too many failed logins, the account is locked until %s", credentials.LockedUntil.In(ctx.Location()).Format("15:04"))
+	}
+	if !auth.CheckPassword(credentials.PasswordHash, password) {
+		return ErrInvalidCredentials
+	}
+	if err := ctx.Store.ResetFailedLogins(userID); err != nil {
+		return ctx.Errorf("error resetting failed logins: %w", err)
+	}
+	return nil
+}
+
+// startSession loads the user into the session
+func startSession(ctx *Context, userID int) error {
+	user, err := ctx.Store.GetUser(userID)
+	if err != nil {
+		return ctx.Errorf("error fetching user: %w", err)
+	}
+	ctx.Session.Start(*user)
+	return nil
+}
+
+func login(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	if err := Login(ctx, inv.String("username")); err != nil {
+		return nil, err
+	}
+	user := &ctx.Session.User
+	text := ctx.Sprintf("User %s %s logged in successfully with ID: %d\n", user.Firstname, user.Lastname, user.ID)
+	return &cli.Result{Data: userResult(user), Text: text}, nil
+}
+
+func register(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	if ctx.Session.Kind == SessionSSH {
+		return nil, ErrKeySession
+	}
+	newUser := suser.SUser{
+		Username:     strings.ToLower(inv.String("username")),
+		Email:        strings.ToLower(inv.String("email")),
+		Firstname:    inv.String("firstname"),
+		Lastname:     inv.String("lastname"),
+		Age:          inv.Int("age"),
+		Weight:       inv.Int("weight"),
+		Height:       inv.Int("height"),
+		TargetWeight: inv.Int("target_weight"),
+	}
+	if err := checkCredentials(ctx, 0, newUser.Username, newUser.Email); err != nil {
+		return nil, err
+	}
+	hash, err := newPassword(ctx, "Password: ")
+	if err != nil {
+		return nil, err
+	}
+	// Save the user to the database
+	userID, err := ctx.Store.CreateUser(newUser.Username, newUser.Email, hash, newUser.Firstname, newUser.Lastname, newUser.Age, newUser.Weight, newUser.Height, newUser.TargetWeight)
+	if err != nil {
+		return nil, ctx.Errorf("error creating user: %w", err)
+	}
+	// Set the user ID
+	newUser.ID = userID
+	// Set the user in the session
+	ctx.Session.Start(newUser)
+	text := ctx.Sprintf("User %s %s registered successfully with ID: %d\n", newUser.Firstname, newUser.Lastname, newUser.ID)
+	return &cli.Result{Data: userResult(&newUser), Text: text}, nil
+}
+
+// passwd changes the password of the session user, and its username or email
+// with the flags, after checking the current password
+func passwd(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	user := &ctx.Session.User
+	name := strings.ToLower(inv.String("username"))
+	email := strings.ToLower(inv.String("email"))
+	if err := checkCredentials(ctx, user.ID, name, email); err != nil {
+		return nil, err
+	}
+
+	current, err := ctx.password("Current password: ")
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPassword(ctx, user.ID, current); err != nil {
+		return nil, err
+	}
+	hash, err := newPassword(ctx, "New password: ")
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Store.SetCredentials(user.ID, name, email, hash); err != nil {
+		return nil, ctx.Errorf("error updating password: %w", err)
+	}
+	if name != "" {
+		user.Username = name
+	}
+	if email != "" {
+		user.Email = email
+	}
+	return updated(ctx, "Password updated successfully.\n")
+}
+
+// ResetPassword issues a one-time reset token for the user with the given
+// username, email or ID, the ID being the only way to name the accounts
+// created before the passwords. The token lets the user set its credentials
+// with reset, without the password it lost or never had. The store of ctx
+// must be allowed to: an administrator's, or the host CLI's unscoped store.
+func ResetPassword(ctx *Context, login string) (*cli.Result, error) {
+	userID, convErr := strconv.Atoi(login)
+	if convErr != nil {
+		var err error
+		if userID, err = findUser(ctx, strings.ToLower(login)); err != nil {
+			return nil, err
+		}
+	}
+	token, hash, err := auth.NewResetToken()
+	if err != nil {
+		return nil, ctx.Errorf("error creating reset token: %w", err)
+	}
+	expiresAt := time.Now().Add(auth.ResetTokenTTL)
+	found, err := ctx.Store.SetResetToken(userID, hash, expiresAt)
+	if err != nil {
+		return nil, ctx.Errorf("error creating reset token: %w", err)
+	}
+	if !found {
+		return nil, ctx.Errorf("no user with ID %d", userID)
+	}
+	var text strings.Builder
+	ctx.Fprintf(&text, "Reset token of %s, valid once until %s:\n", login, expiresAt.In(ctx.Location()).Format("2006-01-02 15:04"))
+	ctx.Fprintf(&text, "  %s\n", token)
+	ctx.Fprintf(&text, "Give it to the user, who sets a password with 'reset', and a username with --username if the account has none.\n")
+	return &cli.Result{
+		Data: ResetToken{UserID: userID, Token: token, ExpiresAt: expiresAt.In(ctx.Location()).Format(time.RFC3339)},
+		Text: text.String(),
+	}, nil
+}
+
+func resetPassword(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	return ResetPassword(ctx, inv.String("user"))
+}
+
+// reset sets the credentials of an account with a reset token and logs it
+// in. The token is asked for like a password when it is not given, so that
+// it stays out of the shell history.
+func reset(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	if ctx.Session.Kind == SessionSSH {
+		return nil, ErrKeySession
+	}
+	token := inv.String("token")
+	if token == "" {
+		var err error
+		if token, err = ctx.password("Reset token: "); err != nil {
+			return nil, err
+		}
+	}
+	hash := auth.HashToken(token)
+	userID, err := ctx.Store.FindResetToken(hash)
+	if err != nil {
+		return nil, ctx.Errorf("error checking reset token: %w", err)
+	}
+	if userID == 0 {
+		return nil, ErrInvalidResetToken
+	}
+
+	name := strings.ToLower(inv.String("username"))
+	email := strings.ToLower(inv.String("email"))
+	credentials, err := ctx.Store.GetCredentials(userID)
+	if err != nil {
+		return nil, ctx.Errorf("error fetching credentials: %w", err)
+	}
+	if credentials.Username == "" && name == "" {
+		return nil, ctx.Errorf("this account has no username yet, choose one with --username <name>")
+	}
+	if err := checkCredentials(ctx, userID, name, email); err != nil {
+		return nil, err
+	}
+	passwordHash, err := newPassword(ctx, "New password: ")
+	if err != nil {
+		return nil, err
+	}
+	done, err := ctx.Store.ResetCredentials(userID, hash, name, email, passwordHash)
+	if err != nil {
+		return nil, ctx.Errorf("error updating password: %w", err)
+	}
+	if !done {
+		return nil, ErrInvalidResetToken
+	}
+	if err := startSession(ctx, userID); err != nil {
+		return nil, err
+	}
+	user := &ctx.Session.User
+	text := ctx.Sprintf("Password set, user %s %s logged in with the username %s\n", user.Firstname, user.Lastname, user.Username)
+	return &cli.Result{Data: userResult(user), Text: text}, nil
+}
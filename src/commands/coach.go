@@ -0,0 +1,255 @@
+package commands
+
+import (
+	"fmt"
+	"gotracker/cli"
+	suser "gotracker/structs"
+	"slices"
+	"strings"
+	"time"
+)
+
+// findUser returns the ID of the user with the given username or email
+func findUser(ctx *Context, login string) (int, error) {
+	userID, err := ctx.Store.FindUser(login)
+	if err != nil {
+		return 0, ctx.Errorf("error finding user: %w", err)
+	}
+	if userID == 0 {
+		return 0, ctx.Errorf("no user with the username or email '%s'", login)
+	}
+	return userID, nil
+}
+
+// coachLinkResult returns the result of a link with a coach or a client, in
+// the time zone of the session user
+func coachLinkResult(ctx *Context, link suser.CoachClient, userID int) CoachLink {
+	result := CoachLink{
+		UserID:    userID,
+		Username:  link.Username,
+		Firstname: link.Firstname,
+		Lastname:  link.Lastname,
+		InvitedAt: link.InvitedAt.In(ctx.Location()).Format(time.RFC3339),
+	}
+	if !link.AcceptedAt.IsZero() {
+		result.AcceptedAt = link.AcceptedAt.In(ctx.Location()).Format(time.RFC3339)
+	}
+	return result
+}
+
+// coachLinksResult lists the links of the session user, with the ID of the
+// other user picked by otherID
+func coachLinksResult(ctx *Context, links []suser.CoachClient, title string, empty string, otherID func(suser.CoachClient) int) *cli.Result {
+	if len(links) == 0 {
+		return &cli.Result{Data: []CoachLink{}, Text: ctx.Sprintf(empty)}
+	}
+	results := make([]CoachLink, 0, len(links))
+	var text strings.Builder
+	ctx.Fprintf(&text, title)
+	for _, link := range links {
+		results = append(results, coachLinkResult(ctx, link, otherID(link)))
+		fmt.Fprintf(&text, " - %s %s", link.Firstname, link.Lastname)
+		if link.Username != "" {
+			fmt.Fprintf(&text, " (%s)", link.Username)
+		}
+		if link.AcceptedAt.IsZero() {
+			ctx.Fprintf(&text, " | invited on %s, waiting for the consent\n", link.InvitedAt.In(ctx.Location()).Format("2006-01-02"))
+		} else {
+			ctx.Fprintf(&text, " | following since %s\n", link.AcceptedAt.In(ctx.Location()).Format("2006-01-02"))
+		}
+	}
+	return &cli.Result{Data: results, Text: text.String()}
+}
+
+// inviteClient invites a user to be coached, the user becomes a client once
+// it accepts with coach accept
+func inviteClient(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	login := inv.String("client")
+	clientID, err := findUser(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	if clientID == ctx.Session.User.ID {
+		return nil, ctx.Errorf("you cannot coach yourself")
+	}
+	invited, err := ctx.Store.InviteClient(ctx.Session.User.ID, clientID)
+	if err != nil {
+		return nil, ctx.Errorf("error inviting client: %w", err)
+	}
+	if !invited {
+		return nil, ctx.Errorf("%s is already invited or coached", login)
+	}
+	return &cli.Result{
+		Data: CoachInvitation{CoachID: ctx.Session.User.ID, ClientID: clientID},
+		Text: ctx.Sprintf("Invited %s, who can accept with 'coach accept %s'.\n", login, ctx.Session.User.Username),
+	}, nil
+}
+
+func listClients(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	links, err := ctx.Store.GetClients(ctx.Session.User.ID)
+	if err != nil {
+		return nil, ctx.Errorf("error fetching clients: %w", err)
+	}
+	return coachLinksResult(ctx, links, "Clients:\n", "No clients, invite one with 'client invite <username>'.\n",
+		func(link suser.CoachClient) int { return link.ClientID }), nil
+}
+
+func removeClient(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	login := inv.String("client")
+	clientID, err := findUser(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	removed, err := ctx.Store.EndCoaching(ctx.Session.User.ID, clientID)
+	if err != nil {
+		return nil, ctx.Errorf("error removing client: %w", err)
+	}
+	if !removed {
+		return nil, ctx.Errorf("%s is not your client", login)
+	}
+	return &cli.Result{
+		Data: CoachInvitation{CoachID: ctx.Session.User.ID, ClientID: clientID},
+		Text: ctx.Sprintf("%s is no longer your client.\n", login),
+	}, nil
+}
+
+// clientView runs a command reading the data of the session user on the data
+// of a client instead. The store still acts for the coach, so that the data
+// layer refuses the clients who did not accept and any change of their data.
+func clientView(handler Handler) Handler {
+	return func(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+		clientID, err := findUser(ctx, inv.String("client"))
+		if err != nil {
+			return nil, err
+		}
+		client, err := ctx.Store.GetUser(clientID)
+		if err != nil {
+			return nil, ctx.Errorf("error fetching client: %w", err)
+		}
+		view := *ctx
+		view.Language = ctx.Lang()
+		view.Session = &Session{Kind: ctx.Session.Kind, User: *client}
+		return handler(&view, inv)
+	}
+}
+
+func listCoaches(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	links, err := ctx.Store.GetCoaches(ctx.Session.User.ID)
+	if err != nil {
+		return nil, ctx.Errorf("error fetching coaches: %w", err)
+	}
+	return coachLinksResult(ctx, links, "Coaches:\n", "No coaches, a coach invites you with 'client invite'.\n",
+		func(link suser.CoachClient) int { return link.CoachID }), nil
+}
+
+// acceptCoach records the consent of the session user to be coached, the
+// coach may read its data from then on
+func acceptCoach(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	login := inv.String("coach")
+	coachID, err := findUser(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	accepted, err := ctx.Store.AcceptCoach(ctx.Session.User.ID, coachID)
+	if err != nil {
+		return nil, ctx.Errorf("error accepting coach: %w", err)
+	}
+	if !accepted {
+		return nil, ctx.Errorf("%s has no pending invitation for you", login)
+	}
+	return &cli.Result{
+		Data: CoachInvitation{CoachID: coachID, ClientID: ctx.Session.User.ID},
+		Text: ctx.Sprintf("%s is now your coach and can read your summaries, histories and targets, 'coach revoke %s' ends it.\n", login, login),
+	}, nil
+}
+
+// revokeCoach withdraws the consent of the session user, or declines the invitation
+func revokeCoach(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	login := inv.String("coach")
+	coachID, err := findUser(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	revoked, err := ctx.Store.EndCoaching(coachID, ctx.Session.User.ID)
+	if err != nil {
+		return nil, ctx.Errorf("error revoking coach: %w", err)
+	}
+	if !revoked {
+		return nil, ctx.Errorf("%s is not your coach", login)
+	}
+	return &cli.Result{
+		Data: CoachInvitation{CoachID: coachID, ClientID: ctx.Session.User.ID},
+		Text: ctx.Sprintf("%s is no longer your coach.\n", login),
+	}, nil
+}
+
+func listAccounts(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	accounts, err := ctx.Store.GetAccounts()
+	if err != nil {
+		return nil, ctx.Errorf("error fetching users: %w", err)
+	}
+	results := make([]Account, 0, len(accounts))
+	var text strings.Builder
+	ctx.Fprintf(&text, "Users:\n")
+	now := time.Now()
+	for _, account := range accounts {
+		result := Account{
+			ID:        account.ID,
+			Username:  account.Username,
+			Email:     account.Email,
+			Firstname: account.Firstname,
+			Lastname:  account.Lastname,
+			Role:      account.Role,
+		}
+		ctx.Fprintf(&text, " - ID: %d | %s %s", account.ID, account.Firstname, account.Lastname)
+		if account.Username != "" {
+			fmt.Fprintf(&text, " (%s)", account.Username)
+		}
+		fmt.Fprintf(&text, " | %s", account.Role)
+		if account.LockedUntil.After(now) {
+			result.LockedUntil = account.LockedUntil.In(ctx.Location()).Format(time.RFC3339)
+			ctx.Fprintf(&text, " | locked until %s", account.LockedUntil.In(ctx.Location()).Format("2006-01-02 15:04"))
+		}
+		text.WriteString("\n")
+		results = append(results, result)
+	}
+	return &cli.Result{Data: results, Text: text.String()}, nil
+}
+
+func setRole(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	login := inv.String("user")
+	role := strings.ToLower(inv.String("role"))
+	if !slices.Contains(suser.Roles, role) {
+		return nil, ctx.Errorf("invalid role '%s', expected member, coach or admin", role)
+	}
+	userID, err := findUser(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ctx.Store.SetUserRole(userID, role); err != nil {
+		return nil, ctx.Errorf("error setting role: %w", err)
+	}
+	if userID == ctx.Session.User.ID {
+		ctx.Session.User.Role = role
+	}
+	return &cli.Result{
+		Data: Role{UserID: userID, Role: role},
+		Text: ctx.Sprintf("%s is now %s.\n", login, role),
+	}, nil
+}
+
+// unlockUser ends the lockout of an account after too many failed logins
+func unlockUser(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	login := inv.String("user")
+	userID, err := findUser(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Store.UnlockUser(userID); err != nil {
+		return nil, ctx.Errorf("error unlocking user: %w", err)
+	}
+	return &cli.Result{
+		Data: Unlocked{UserID: userID},
+		Text: ctx.Sprintf("%s can login again.\n", login),
+	}, nil
+}
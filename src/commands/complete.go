@@ -0,0 +1,121 @@
+package commands
+
+import (
+	"fmt"
+	"gotracker/cli"
+	"gotracker/exercise"
+	"sort"
+	"strconv"
+)
+
+// recentFoods is the number of food IDs offered by completion
+const recentFoods = 10
+
+// Completer returns the tab completion of the registry, completing the
+// arguments with the meals, day presets, activities and recent foods of the
+// session user
+func Completer(registry *cli.Registry, ctx *Context) cli.Completer {
+	return func(words []string) []cli.Completion {
+		return registry.Complete(words, func(path []string, arg cli.Arg) []cli.Completion {
+			return completeArg(ctx, arg)
+		})
+	}
+}
+
+// completeArg returns the candidates of an argument, by name
+func completeArg(ctx *Context, arg cli.Arg) []cli.Completion {
+	var candidates []cli.Completion
+	switch arg.Name {
+	case "meal_id":
+		meals, err := ctx.Store.GetAllMeals(ctx.Session.User.ID)
+		if err != nil {
+			return nil
+		}
+		for _, meal := range meals {
+			candidates = append(candidates, cli.Completion{Word: strconv.Itoa(meal.ID), Description: meal.Name + " (" + meal.Type + ")"})
+		}
+	case "day_id":
+		days, err := ctx.Store.GetAllDays(ctx.Session.User.ID)
+		if err != nil {
+			return nil
+		}
+		for _, day := range days {
+			candidates = append(candidates, cli.Completion{Word: strconv.Itoa(day.ID), Description: day.Name})
+		}
+	case "food_id":
+		candidates = completeRecentFoods(ctx, false)
+	case "entry_id":
+		candidates = completeRecentFoods(ctx, true)
+	case "activity":
+		for _, name := range exercise.Names() {
+			candidates = append(candidates, cli.Completion{Word: name})
+		}
+	case "intensity":
+		for _, intensity := range []string{exercise.IntensityLight, exercise.IntensityModerate, exercise.IntensityVigorous} {
+			candidates = append(candidates, cli.Completion{Word: intensity})
+		}
+	case "nutrient":
+		for _, nutrient := range TrackedNutrients {
+			candidates = append(candidates, cli.Completion{Word: nutrient.Key, Description: nutrient.Unit})
+		}
+	case "alias_name":
+		aliases := ctx.aliases()
+		for _, name := range sortedKeys(aliases) {
+			candidates = append(candidates, cli.Completion{Word: name, Description: aliases[name]})
+		}
+	case "amount":
+		candidates = []cli.Completion{{Word: "glass", Description: "250 ml"}, {Word: "bottle", Description: "500 ml"}}
+	}
+	return candidates
+}
+
+// completeRecentFoods returns the foods logged last by the session user, as
+// food IDs or as entry IDs of the food history
+func completeRecentFoods(ctx *Context, entries bool) []cli.Completion {
+	if !ctx.Session.LoggedIn() {
+		return nil
+	}
+	history, err := ctx.Store.GetFoodHistory(ctx.Session.User.ID)
+	if err != nil {
+		return nil
+	}
+	// Most recent first, entry IDs break ties within a day
+	sort.Slice(history, func(i, j int) bool {
+		if dateI, dateJ := dateOnly(history[i][1]), dateOnly(history[j][1]); dateI != dateJ {
+			return dateI > dateJ
+		}
+		return history[i][3].(int) > history[j][3].(int)
+	})
+
+	var candidates []cli.Completion
+	seen := make(map[int]bool)
+	for _, food := range history {
+		if len(candidates) == recentFoods {
+			break
+		}
+		foodID, entryID := food[0].(int), food[3].(int)
+		if entries {
+			candidates = append(candidates, cli.Completion{
+				Word:        strconv.Itoa(entryID),
+				Description: fmt.Sprintf("food %d, %vg on %s", foodID, food[2], dateOnly(food[1])),
+			})
+			continue
+		}
+		if seen[foodID] {
+			continue
+		}
+		seen[foodID] = true
+		candidates = append(candidates, cli.Completion{Word: strconv.Itoa(foodID), Description: "last eaten on " + dateOnly(food[1])})
+	}
+	return candidates
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
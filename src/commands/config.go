@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"fmt"
+	"gotracker/cli"
+	"gotracker/config"
+	"strings"
+)
+
+// sourceText describes where the value of a setting comes from
+func sourceText(ctx *Context, source config.Source) string {
+	switch source.Kind {
+	case "file":
+		return ctx.Translate("config file")
+	case "env":
+		return ctx.Sprintf("environment %s", source.Name)
+	case "flag":
+		return ctx.Sprintf("flag %s", source.Name)
+	}
+	return ctx.Translate("default")
+}
+
+// showConfig shows the settings of the application, the secrets redacted,
+// and where each comes from. The servers do not show theirs to the sessions.
+func showConfig(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	if ctx.Config == nil {
+		return nil, ctx.Errorf("the configuration is only shown by the CLI of the host")
+	}
+	settings := ctx.Config.Settings()
+	results := make([]ConfigSetting, 0, len(settings))
+	var text strings.Builder
+	if ctx.Config.Path != "" {
+		ctx.Fprintf(&text, "Config file: %s\n", ctx.Config.Path)
+	} else if path, err := config.DefaultPath(); err == nil {
+		ctx.Fprintf(&text, "No config file, the settings can be written to %s\n", path)
+	}
+	for _, setting := range settings {
+		results = append(results, ConfigSetting{Key: setting.Key, Value: setting.Value, Source: setting.Source.Kind, From: setting.Source.Name})
+		value := setting.Value
+		if value == "" {
+			value = "-"
+		}
+		fmt.Fprintf(&text, " - %s = %s (%s)\n", setting.Key, value, sourceText(ctx, setting.Source))
+	}
+	if err := ctx.Config.Validate(); err != nil && ctx.Err != nil {
+		ctx.Fprintf(ctx.Err, "Warning: the configuration is invalid: %v\n", err)
+	}
+	return &cli.Result{Data: results, Text: text.String()}, nil
+}
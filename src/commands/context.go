@@ -0,0 +1,300 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"gotracker/cli"
+	"gotracker/config"
+	"gotracker/fdcnal"
+	"gotracker/i18n"
+	suser "gotracker/structs"
+	db "gotracker/utils"
+	"io"
+	"time"
+)
+
+// ErrNotLoggedIn is returned by commands that need a logged in user
+var ErrNotLoggedIn = errors.New("please login or register first")
+
+// ErrExit is returned by the exit command to stop the application
+var ErrExit = errors.New("exit")
+
+// ErrForbidden is returned when the session user may not access some data or
+// lacks the role of a command, the data layer returns it too
+var ErrForbidden = db.ErrForbidden
+
+// roleError is the ErrForbidden of a command requiring a role, in the language of the session
+type roleError struct {
+	message string
+}
+
+func (e roleError) Error() string { return e.message }
+
+func (e roleError) Unwrap() error { return ErrForbidden }
+
+// Store is the data layer used by the commands, implemented by *db.Store
+// through DataStore. The sessions share it, it must be safe for concurrent use.
+type Store interface {
+	CreateUser(username string, email string, passwordHash string, firstname string, lastname string, age int, weight int, height int, targetWeight int) (int, error)
+	GetUser(userID int) (*suser.SUser, error)
+	FindUser(login string) (int, error)
+	GetCredentials(userID int) (suser.Credentials, error)
+	SetCredentials(userID int, username string, email string, passwordHash string) error
+	RecordLoginAttempt(userID int, maxFailures int, lockout time.Duration) (suser.Credentials, bool, error)
+	ResetFailedLogins(userID int) error
+	SetResetToken(userID int, tokenHash string, expiresAt time.Time) (bool, error)
+	FindResetToken(tokenHash string) (int, error)
+	ResetCredentials(userID int, tokenHash string, username string, email string, passwordHash string) (bool, error)
+	UpdateUserFirstname(userID int, firstname string) error
+	UpdateUserLastname(userID int, lastname string) error
+	UpdateUserAge(userID int, age int) error
+	UpdateUserWeight(userID int, weight int) error
+	UpdateUserHeight(userID int, height int) error
+	UpdateUserTargetWeight(userID int, targetWeight int) error
+	UpdateUserLang(userID int, lang string) error
+	UpdateUserTimezone(userID int, timezone string) error
+
+	CreateIMCHistory(userID int, date string, imc float64, category string) error
+	GetIMCHistory(userID int) ([][3]interface{}, error)
+	CreateBodyFatHistory(userID int, date string, bodyFat float64) error
+	GetBodyFatHistory(userID int) ([][3]interface{}, error)
+	CreateWeightHistory(userID int, date string, weight int) error
+	GetWeightHistory(userID int) ([][3]interface{}, error)
+	GetWeightSeries(userID int) ([]time.Time, []float64, error)
+
+	AddFoodHistory(userID int, foodID int, date string, timeOfDay string, quantity int, mealType string) (int, error)
+	UpdateFoodHistory(userID int, entryID int, date string, timeOfDay string, quantity int, mealType string) ([6]interface{}, bool, error)
+	RestoreFoodHistory(userID int, entryID int, date string, timeOfDay string, quantity float64, mealType string) (bool, error)
+	GetFoodEntry(userID int, entryID int) ([6]interface{}, bool, error)
+	GetFoodDay(userID int, date string) ([][5]interface{}, error)
+	GetFoodHistory(userID int) ([][5]interface{}, error)
+	GetFoodQuantitiesByDay(userID int, from string, to string) (map[string]map[int]float64, error)
+	DeleteFoodHistory(userID int, entryID int) (bool, error)
+
+	CreateMeal(userID int, name string, mealType string) (int, error)
+	GetMeal(userID int, mealID int) (suser.Meal, error)
+	GetAllMeals(userID int) ([]suser.Meal, error)
+	GetFoodWithMeal(userID int, mealID int) ([][2]int, error)
+	LinkFoodToMeal(userID int, foodID int, mealID int, quantity int) error
+	CreateDayPreset(userID int, name string) (int, error)
+	GetAllDays(userID int) ([]suser.DayPreset, error)
+	GetMealWithDayPreset(userID int, dayPresetID int) ([][2]int, error)
+	LinkMealToDayPreset(userID int, mealID int, dayPresetID int, quantity int) (bool, error)
+	DeleteMeal(userID int, mealID int) (bool, error)
+	DeleteDayPreset(userID int, dayPresetID int) (bool, error)
+
+	SetTargetCalories(userID int, date string, calories float64) error
+	GetTargetCalories(userID int) (float64, error)
+	SetTarget(userID int, date string, nutrient string, amount float64) error
+	GetTargets(userID int) (map[string]float64, error)
+
+	AddExerciseHistory(userID int, date string, activity string, minutes int, intensity string, calories float64) error
+	GetExerciseHistory(userID int) ([][6]interface{}, error)
+	GetExerciseTotals(userID int, date string) (int, float64, error)
+	AddWaterHistory(userID int, date string, amount int) error
+	GetWaterHistory(userID int) ([][3]interface{}, error)
+	GetWaterTotal(userID int, date string) (int, error)
+
+	SetAlias(userID int, name string, definition string) error
+	GetAliases(userID int) ([][2]string, error)
+	DeleteAlias(userID int, name string) (bool, error)
+
+	CreateAPIToken(userID int, name string, tokenHash string, scopes []string, expiresAt time.Time) (int, error)
+	UseAPIToken(tokenHash string) (suser.APIToken, bool, error)
+	GetAPITokens(userID int) ([]suser.APIToken, error)
+	RevokeAPIToken(userID int, tokenID int) (bool, error)
+	DeleteAPIToken(tokenHash string) error
+	AddSSHKey(userID int, name string, fingerprint string, publicKey string) (int, bool, error)
+	FindSSHKey(fingerprint string) (suser.SSHKey, bool, error)
+	UseSSHKey(keyID int) error
+	GetSSHKeys(userID int) ([]suser.SSHKey, error)
+	DeleteSSHKey(userID int, keyID int) (bool, error)
+
+	InviteClient(coachID int, clientID int) (bool, error)
+	AcceptCoach(clientID int, coachID int) (bool, error)
+	EndCoaching(coachID int, clientID int) (bool, error)
+	GetClients(coachID int) ([]suser.CoachClient, error)
+	GetCoaches(clientID int) ([]suser.CoachClient, error)
+	GetAccounts() ([]suser.Account, error)
+	SetUserRole(userID int, role string) (bool, error)
+	UnlockUser(userID int) error
+	GetAuditLog(userID int, table string, limit int) ([]suser.AuditEntry, error)
+
+	GetTrash(userID int) ([]suser.TrashItem, error)
+	RestoreTrash(userID int, kind string, id int) (bool, error)
+	PurgeTrash(userID int) (int64, error)
+
+	// As returns the store acting for the user actorID in the session
+	// sessionID, which may only access its own data and the data its
+	// permissions allow. The audit log records its changes with both.
+	As(actorID int, sessionID string) Store
+}
+
+// dataStore is the Store of a *db.Store, whose As returns the concrete store
+type dataStore struct {
+	*db.Store
+}
+
+// DataStore returns the Store of the commands over a store of the data layer
+func DataStore(store *db.Store) Store {
+	return dataStore{store}
+}
+
+func (s dataStore) As(actorID int, sessionID string) Store {
+	return dataStore{s.Store.As(actorID, sessionID)}
+}
+
+// Context is what every command handler receives. Results are rendered to
+// Out by the registry, warnings that are not part of a result go to Err. Ctx
+// is cancelled when the application shuts down and aborts the FDC requests.
+// Language is set by --lang and overrides the preference of the user.
+// ReadPassword asks for a password without echoing it, nil when the
+// application cannot ask. Sessions holds the other sessions of the
+// application, nil when it only has this one. Config is the configuration of
+// the application, nil for the sessions of the servers.
+type Context struct {
+	Ctx          context.Context
+	Store        Store
+	FDC          *fdcnal.Client
+	Config       *config.Config
+	Session      *Session
+	Sessions     *SessionManager
+	Language     i18n.Lang
+	Out          io.Writer
+	Err          io.Writer
+	ReadPassword func(prompt string) (string, error)
+}
+
+// RequestContext returns the context bounding the requests made by the commands
+func (ctx *Context) RequestContext() context.Context {
+	if ctx.Ctx == nil {
+		return context.Background()
+	}
+	return ctx.Ctx
+}
+
+// Lang returns the language of the messages: --lang, else the preference of
+// the session user, else the LANG environment variable
+func (ctx *Context) Lang() i18n.Lang {
+	if ctx.Language != "" {
+		return ctx.Language
+	}
+	if ctx.Session != nil && ctx.Session.User.Lang != "" {
+		if lang, err := i18n.Parse(ctx.Session.User.Lang); err == nil {
+			return lang
+		}
+	}
+	return i18n.FromEnv()
+}
+
+// Translate returns a message in the language of the session
+func (ctx *Context) Translate(message string) string {
+	return ctx.Lang().Translate(message)
+}
+
+// Sprintf formats a message in the language of the session
+func (ctx *Context) Sprintf(format string, a ...interface{}) string {
+	return ctx.Lang().Sprintf(format, a...)
+}
+
+// Fprintf writes a message in the language of the session to w
+func (ctx *Context) Fprintf(w io.Writer, format string, a ...interface{}) (int, error) {
+	return ctx.Lang().Fprintf(w, format, a...)
+}
+
+// Errorf returns an error with a message in the language of the session
+func (ctx *Context) Errorf(format string, a ...interface{}) error {
+	return ctx.Lang().Errorf(format, a...)
+}
+
+// Location returns the time zone of the session user, the local zone of the
+// host when none is set
+func (ctx *Context) Location() *time.Location {
+	if ctx.Session != nil && ctx.Session.User.Timezone != "" {
+		if location, err := time.LoadLocation(ctx.Session.User.Timezone); err == nil {
+			return location
+		}
+	}
+	return time.Local
+}
+
+// Now returns the current time in the time zone of the session user
+func (ctx *Context) Now() time.Time {
+	return time.Now().In(ctx.Location())
+}
+
+// Today returns the current date of the session user formatted for the store
+func (ctx *Context) Today() string {
+	return ctx.Now().Format("2006-01-02")
+}
+
+// Handler runs a command with its parsed invocation and returns its result
+type Handler func(ctx *Context, inv *cli.Invocation) (*cli.Result, error)
+
+// Spec declares a command of the table and the handler running it.
+// RequiresRole is the role the session user needs, the data layer checks the
+// permissions of each query as well. Secret keeps the lines running the
+// command, whose arguments are secrets, out of the history.
+type Spec struct {
+	Name          string
+	Summary       string
+	Args          []cli.Arg
+	Flags         []cli.Flag
+	Subcommands   []Spec
+	Handler       Handler
+	RequiresLogin bool
+	RequiresRole  string
+	Secret        bool
+}
+
+// command builds the cli command running the handler of the spec with ctx
+func (spec Spec) command(ctx *Context) *cli.Command {
+	command := &cli.Command{
+		Name:    spec.Name,
+		Summary: spec.Summary,
+		Args:    spec.Args,
+		Flags:   spec.Flags,
+		Secret:  spec.Secret,
+	}
+	for _, sub := range spec.Subcommands {
+		command.Subcommands = append(command.Subcommands, sub.command(ctx))
+	}
+	if spec.Handler != nil {
+		handler := spec.Handler
+		requiresLogin := spec.RequiresLogin
+		requiresRole := spec.RequiresRole
+		command.Run = func(inv *cli.Invocation) (*cli.Result, error) {
+			if !requiresLogin {
+				return handler(ctx, inv)
+			}
+			if !ctx.Session.LoggedIn() {
+				return nil, ErrNotLoggedIn
+			}
+			if requiresRole != "" && !ctx.Session.User.HasRole(requiresRole) {
+				return nil, roleError{ctx.Sprintf("the %s role is required", requiresRole)}
+			}
+			// The data layer acts for the session user
+			scoped := *ctx
+			scoped.Store = ctx.Store.As(ctx.Session.User.ID, ctx.Session.ID)
+			return handler(&scoped, inv)
+		}
+	}
+	return command
+}
+
+// Register adds the commands of the table to the registry, bound to ctx
+func Register(registry *cli.Registry, ctx *Context) {
+	registry.Out = ctx.Out
+	registry.Printer = ctx
+	registry.Aliases = ctx.aliases
+	for _, spec := range Table {
+		registry.Register(spec.command(ctx))
+	}
+}
+
+// NewRegistry creates a registry with all the commands bound to ctx
+func NewRegistry(ctx *Context) *cli.Registry {
+	registry := cli.NewRegistry()
+	Register(registry, ctx)
+	return registry
+}
@@ -0,0 +1,158 @@
+package commands
+
+import (
+	"gotracker/cli"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateFlag selects the day a command logs or shows, today by default
+var dateFlag = cli.Flag{Name: "date", Usage: "Day of the entry: YYYY-MM-DD, today, yesterday, -2d, -1w or last monday"}
+
+// timeFlag sets the time of day a food is eaten
+var timeFlag = cli.Flag{Name: "time", Usage: "Time of day the food is eaten, HH:MM, now by default when logging today"}
+
+// weekdays maps the English and French names of the days to their weekday
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+	"dimanche": time.Sunday, "lundi": time.Monday, "mardi": time.Tuesday, "mercredi": time.Wednesday,
+	"jeudi": time.Thursday, "vendredi": time.Friday, "samedi": time.Saturday,
+}
+
+// daysAgo matches the relative dates such as -2d or -1w
+var daysAgo = regexp.MustCompile(`^-(\d+)([dw])$`)
+
+// ParseDate reads a day relative to today: a YYYY-MM-DD date, today,
+// yesterday, -Nd for N days ago, -Nw for N weeks ago, a weekday for its
+// latest occurrence up to today, or last <weekday> for its latest occurrence
+// before today. The French forms (hier, lundi dernier...) are also accepted.
+func ParseDate(value string, today time.Time) (time.Time, bool) {
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	words := strings.Fields(strings.ToLower(value))
+	switch strings.Join(words, " ") {
+	case "today", "aujourd'hui":
+		return today, true
+	case "yesterday", "hier":
+		return today.AddDate(0, 0, -1), true
+	case "avant-hier":
+		return today.AddDate(0, 0, -2), true
+	}
+
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return date, true
+	}
+	if match := daysAgo.FindStringSubmatch(strings.Join(words, "")); match != nil {
+		n, err := strconv.Atoi(match[1])
+		if err != nil {
+			return time.Time{}, false
+		}
+		if match[2] == "w" {
+			n *= 7
+		}
+		return today.AddDate(0, 0, -n), true
+	}
+
+	// A weekday, optionally preceded by last or followed by dernier
+	before := false
+	if len(words) == 2 && (words[0] == "last" || words[1] == "dernier") {
+		before = true
+		if words[0] == "last" {
+			words = words[1:]
+		} else {
+			words = words[:1]
+		}
+	}
+	if len(words) != 1 {
+		return time.Time{}, false
+	}
+	weekday, ok := weekdays[words[0]]
+	if !ok {
+		return time.Time{}, false
+	}
+	back := (int(today.Weekday()) - int(weekday) + 7) % 7
+	if back == 0 && before {
+		back = 7
+	}
+	return today.AddDate(0, 0, -back), true
+}
+
+// Date returns the day given by the --date flag of inv, the active date of
+// the session or today when it is not set. Days after today are refused.
+func (ctx *Context) Date(inv *cli.Invocation) (string, error) {
+	if !inv.Has("date") {
+		if ctx.Session != nil && ctx.Session.ActiveDate != "" {
+			return ctx.Session.ActiveDate, nil
+		}
+		return ctx.Today(), nil
+	}
+	return parseDay(ctx, inv.String("date"))
+}
+
+// parseDay returns the day of a date given to a command, refusing days after today
+func parseDay(ctx *Context, value string) (string, error) {
+	date, ok := ParseDate(value, ctx.Now())
+	if !ok {
+		return "", ctx.Errorf("invalid date '%s', expected YYYY-MM-DD, today, yesterday, -2d, -1w or last monday", value)
+	}
+	day := date.Format("2006-01-02")
+	if day > ctx.Today() {
+		return "", ctx.Errorf("the date %s is in the future", day)
+	}
+	return day, nil
+}
+
+// setDate shows or sets the active date of the session, the day the commands
+// log and show without --date. It is reset to today by 'date today' and when
+// another user logs in.
+func setDate(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	if inv.Has("day") {
+		day, err := parseDay(ctx, inv.String("day"))
+		if err != nil {
+			return nil, err
+		}
+		ctx.Session.ActiveDate = day
+		if day == ctx.Today() {
+			// Today follows the clock, past midnight it is the next day
+			ctx.Session.ActiveDate = ""
+		}
+	}
+	result := ActiveDate{Date: ctx.Session.ActiveDate, Today: ctx.Session.ActiveDate == ""}
+	if result.Today {
+		result.Date = ctx.Today()
+		return &cli.Result{Data: result, Text: ctx.Sprintf("The commands use today, %s.\n", result.Date)}, nil
+	}
+	return &cli.Result{Data: result, Text: ctx.Sprintf("The commands use %s, 'date today' goes back to today.\n", result.Date)}, nil
+}
+
+// eatenAt returns the day and time of day given by the --date and --time
+// flags of inv. Without --time, the time is now when logging today and
+// unknown, empty, for a past day.
+func eatenAt(ctx *Context, inv *cli.Invocation) (string, string, error) {
+	date, err := ctx.Date(inv)
+	if err != nil {
+		return "", "", err
+	}
+	if !inv.Has("time") {
+		if date == ctx.Today() {
+			return date, ctx.Now().Format("15:04"), nil
+		}
+		return date, "", nil
+	}
+	timeOfDay, err := parseTime(ctx, inv.String("time"))
+	if err != nil {
+		return "", "", err
+	}
+	return date, timeOfDay, nil
+}
+
+// parseTime reads a time of day, HH:MM
+func parseTime(ctx *Context, value string) (string, error) {
+	timeOfDay, err := time.Parse("15:04", value)
+	if err != nil {
+		return "", ctx.Errorf("invalid time '%s', expected HH:MM", value)
+	}
+	return timeOfDay.Format("15:04"), nil
+}
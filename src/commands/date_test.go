@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	// A Wednesday, late in the day and in another zone than UTC
+	today := time.Date(2024, time.March, 13, 23, 30, 0, 0, time.FixedZone("UTC+2", 2*3600))
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"today", "2024-03-13"},
+		{"aujourd'hui", "2024-03-13"},
+		{"Yesterday", "2024-03-12"},
+		{"hier", "2024-03-12"},
+		{"avant-hier", "2024-03-11"},
+		{"2024-02-29", "2024-02-29"},
+		{"-0d", "2024-03-13"},
+		{"-3d", "2024-03-10"},
+		{"-2w", "2024-02-28"},
+		{"- 1 w", "2024-03-06"},
+		{"wednesday", "2024-03-13"},
+		{"monday", "2024-03-11"},
+		{"thursday", "2024-03-07"},
+		{"last wednesday", "2024-03-06"},
+		{"last monday", "2024-03-11"},
+		{"mercredi dernier", "2024-03-06"},
+		{"lundi", "2024-03-11"},
+	}
+	for _, test := range tests {
+		date, ok := ParseDate(test.value, today)
+		if !ok {
+			t.Errorf("ParseDate(%q) failed, want %s", test.value, test.want)
+			continue
+		}
+		if got := date.Format("2006-01-02"); got != test.want {
+			t.Errorf("ParseDate(%q) = %s, want %s", test.value, got, test.want)
+		}
+	}
+}
+
+func TestParseDateInvalid(t *testing.T) {
+	today := time.Date(2024, time.March, 13, 12, 0, 0, 0, time.UTC)
+	for _, value := range []string{"", "tomorrow", "2024-02-30", "13/03/2024", "-d", "-3m", "3d", "last", "last week", "next monday", "monday tuesday"} {
+		if date, ok := ParseDate(value, today); ok {
+			t.Errorf("ParseDate(%q) = %s, want a failure", value, date.Format("2006-01-02"))
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package commands
+
+import (
+	"gotracker/cli"
+	"gotracker/fdcnal"
+	"math"
+	"sort"
+	"strings"
+)
+
+// TrackedNutrient is a nutrient of the target table with its FDC nutrient number
+type TrackedNutrient struct {
+	Key    string // Column of the target table
+	Name   string
+	Unit   string
+	Number string // Empty for calories, read with GetFoodEnergy
+}
+
+// TrackedNutrients lists the nutrients a target can be set for
+var TrackedNutrients = []TrackedNutrient{
+	{"calories", "Calories", "kcal", ""},
+	{"protein", "Protein", "g", fdcnal.NutrientProtein},
+	{"carbohydrates", "Carbohydrates", "g", fdcnal.NutrientCarbohydrates},
+	{"fat", "Fat", "g", fdcnal.NutrientFat},
+	{"saturated_fat", "Saturated fat", "g", fdcnal.NutrientSaturatedFat},
+	{"trans_fat", "Trans fat", "g", fdcnal.NutrientTransFat},
+	{"cholesterol", "Cholesterol", "mg", fdcnal.NutrientCholesterol},
+	{"sodium", "Sodium", "mg", fdcnal.NutrientSodium},
+	{"fiber", "Fiber", "g", fdcnal.NutrientFiber},
+	{"sugars", "Sugars", "g", fdcnal.NutrientSugars},
+	{"calcium", "Calcium", "mg", fdcnal.NutrientCalcium},
+	{"iron", "Iron", "mg", fdcnal.NutrientIron},
+	{"potassium", "Potassium", "mg", fdcnal.NutrientPotassium},
+}
+
+// mainNutrients are shown by today even without a target
+var mainNutrients = map[string]bool{"calories": true, "protein": true, "carbohydrates": true, "fat": true}
+
+// MealTypes are the usual meal types, in the order of the day
+var MealTypes = []string{"breakfast", "lunch", "dinner", "snack"}
+
+func lookupNutrient(ctx *Context, key string) (TrackedNutrient, error) {
+	for _, nutrient := range TrackedNutrients {
+		if nutrient.Key == key {
+			return nutrient, nil
+		}
+	}
+	keys := make([]string, len(TrackedNutrients))
+	for i, nutrient := range TrackedNutrients {
+		keys[i] = nutrient.Key
+	}
+	return TrackedNutrient{}, ctx.Errorf("unknown nutrient '%s', expected one of %s", key, strings.Join(keys, ", "))
+}
+
+// GroupByMeal groups foods by meal type, usual meal types first in the order
+// of the day, then the other types alphabetically and foods without type last
+func GroupByMeal(foods []DayFood) ([]string, map[string][]DayFood) {
+	groups := make(map[string][]DayFood)
+	for _, food := range foods {
+		groups[food.MealType] = append(groups[food.MealType], food)
+	}
+
+	rank := func(mealType string) int {
+		for i, usual := range MealTypes {
+			if usual == mealType {
+				return i
+			}
+		}
+		if mealType == "" {
+			return len(MealTypes) + 1
+		}
+		return len(MealTypes)
+	}
+	var types []string
+	for mealType := range groups {
+		types = append(types, mealType)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if rank(types[i]) != rank(types[j]) {
+			return rank(types[i]) < rank(types[j])
+		}
+		return types[i] < types[j]
+	})
+	return types, groups
+}
+
+// MealLabel returns the heading of a meal type
+func MealLabel(mealType string) string {
+	if mealType == "" {
+		return "Other"
+	}
+	return strings.ToUpper(mealType[:1]) + mealType[1:]
+}
+
+func today(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	user := &ctx.Session.User
+	date, err := ctx.Date(inv)
+	if err != nil {
+		return nil, err
+	}
+	log := DayLog{Date: date, Foods: []DayFood{}}
+
+	foods, err := ctx.Store.GetFoodDay(user.ID, log.Date)
+	if err != nil {
+		return nil, ctx.Errorf("error fetching food history: %w", err)
+	}
+	targets, err := ctx.Store.GetTargets(user.ID)
+	if err != nil {
+		return nil, ctx.Errorf("error fetching targets: %w", err)
+	}
+
+	// Sum the nutrients of the foods eaten today
+	consumed := make(map[string]float64)
+	for _, food := range foods {
+		entry := DayFood{EntryID: food[0].(int), FoodID: food[1].(int), Quantity: food[2].(float64), MealType: food[3].(string), Time: food[4].(string)}
+		details, err := ctx.FDC.GetFood(ctx.RequestContext(), entry.FoodID)
+		if ctx.RequestContext().Err() != nil {
+			return nil, ctx.RequestContext().Err()
+		}
+		if err != nil {
+			ctx.Fprintf(ctx.Err, "Skipping food ID %d: %v\n", entry.FoodID, err)
+			log.Foods = append(log.Foods, entry)
+			continue
+		}
+		entry.Description = details.Description
+		if kcal, err := ctx.FDC.GetFoodEnergy(ctx.RequestContext(), entry.FoodID); err == nil {
+			entry.Calories = math.Round(kcal * entry.Quantity / 100)
+			consumed["calories"] += kcal * entry.Quantity / 100
+		}
+		nutrients, _ := ctx.FDC.GetFoodNutrients(ctx.RequestContext(), entry.FoodID)
+		for _, nutrient := range TrackedNutrients {
+			if nutrient.Number != "" {
+				consumed[nutrient.Key] += nutrients[nutrient.Number] * entry.Quantity / 100
+			}
+		}
+		log.Foods = append(log.Foods, entry)
+	}
+
+	for _, nutrient := range TrackedNutrients {
+		target, hasTarget := targets[nutrient.Key]
+		if !hasTarget && !mainNutrients[nutrient.Key] {
+			continue
+		}
+		progress := NutrientProgress{Nutrient: nutrient.Key, Name: nutrient.Name, Unit: nutrient.Unit, Consumed: round(consumed[nutrient.Key], 1)}
+		if hasTarget {
+			progress.Target = optional(target)
+		}
+		log.Nutrients = append(log.Nutrients, progress)
+	}
+
+	var text strings.Builder
+	if log.Date == ctx.Today() {
+		ctx.Fprintf(&text, "Today (%s):\n", log.Date)
+	} else {
+		ctx.Fprintf(&text, "Day of %s:\n", log.Date)
+	}
+	if len(log.Foods) == 0 {
+		ctx.Fprintf(&text, "No food logged on this day.\n")
+	}
+	types, groups := GroupByMeal(log.Foods)
+	for _, mealType := range types {
+		ctx.Fprintf(&text, "%s:\n", ctx.Translate(MealLabel(mealType)))
+		for _, food := range groups[mealType] {
+			if food.Time != "" {
+				ctx.Fprintf(&text, " - %s %s (%d) | %.0f g | %.0f kcal | Entry ID: %d\n", food.Time, food.Description, food.FoodID, food.Quantity, food.Calories, food.EntryID)
+			} else {
+				ctx.Fprintf(&text, " - %s (%d) | %.0f g | %.0f kcal | Entry ID: %d\n", food.Description, food.FoodID, food.Quantity, food.Calories, food.EntryID)
+			}
+		}
+	}
+	ctx.Fprintf(&text, "Nutrients:\n")
+	for _, nutrient := range log.Nutrients {
+		if nutrient.Target == nil {
+			ctx.Fprintf(&text, " - %s: %.1f %s (no target)\n", ctx.Translate(nutrient.Name), nutrient.Consumed, nutrient.Unit)
+			continue
+		}
+		percent := 0.0
+		if *nutrient.Target > 0 {
+			percent = nutrient.Consumed * 100 / *nutrient.Target
+		}
+		ctx.Fprintf(&text, " - %s: %.1f / %.1f %s (%.0f%%)\n", ctx.Translate(nutrient.Name), nutrient.Consumed, *nutrient.Target, nutrient.Unit, percent)
+	}
+	return &cli.Result{Data: log, Rows: log.Foods, Text: text.String()}, nil
+}
+
+func showTargets(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	targets, err := ctx.Store.GetTargets(ctx.Session.User.ID)
+	if err != nil {
+		return nil, ctx.Errorf("error fetching targets: %w", err)
+	}
+	var result []NutrientTarget
+	var text strings.Builder
+	ctx.Fprintf(&text, "Targets:\n")
+	for _, nutrient := range TrackedNutrients {
+		entry := NutrientTarget{Nutrient: nutrient.Key, Name: nutrient.Name, Unit: nutrient.Unit}
+		if target, ok := targets[nutrient.Key]; ok {
+			entry.Target = optional(target)
+			ctx.Fprintf(&text, " - %s: %.1f %s\n", ctx.Translate(nutrient.Name), target, nutrient.Unit)
+		} else {
+			ctx.Fprintf(&text, " - %s: not set\n", ctx.Translate(nutrient.Name))
+		}
+		result = append(result, entry)
+	}
+	return &cli.Result{Data: result, Text: text.String()}, nil
+}
+
+func setTarget(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	nutrient, err := lookupNutrient(ctx, inv.String("nutrient"))
+	if err != nil {
+		return nil, err
+	}
+	amount := inv.Float("amount")
+	if amount <= 0 {
+		return nil, ctx.Errorf("the target must be a positive amount")
+	}
+	err = ctx.Store.SetTarget(ctx.Session.User.ID, ctx.Today(), nutrient.Key, amount)
+	if err != nil {
+		return nil, ctx.Errorf("error updating target: %w", err)
+	}
+	return &cli.Result{
+		Data: NutrientTarget{Nutrient: nutrient.Key, Name: nutrient.Name, Unit: nutrient.Unit, Target: optional(amount)},
+		Text: ctx.Sprintf("%s target updated to %.1f %s.\n", ctx.Translate(nutrient.Name), amount, nutrient.Unit),
+	}, nil
+}
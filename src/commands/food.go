@@ -0,0 +1,231 @@
+package commands
+
+import (
+	"gotracker/cli"
+	suser "gotracker/structs"
+	"strings"
+)
+
+func searchFood(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	response, err := ctx.FDC.SearchFood(ctx.RequestContext(), inv.String("food_name"))
+	if err != nil {
+		return nil, ctx.Errorf("error fetching food data: %w", err)
+	}
+	matches := []FoodMatch{}
+	var text strings.Builder
+	ctx.Fprintf(&text, "Food Search Results:\n")
+	for _, food := range response.Foods {
+		matches = append(matches, FoodMatch{FoodID: food.FdcId, Description: food.Description, DataType: food.DataType})
+		ctx.Fprintf(&text, " - %s | ID : %d\n", food.Description, food.FdcId)
+	}
+	return &cli.Result{Data: matches, Text: text.String()}, nil
+}
+
+func details(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	// Get food details from api
+	food, err := ctx.FDC.GetFood(ctx.RequestContext(), inv.Int("food_id"))
+	if err != nil {
+		return nil, ctx.Errorf("error fetching food details: %w", err)
+	}
+	result := FoodDetails{FoodID: food.FdcId, Description: food.Description, Nutrients: []Nutrient{}}
+	var text strings.Builder
+	ctx.Fprintf(&text, "Food Details:\n")
+	ctx.Fprintf(&text, " - Description: %s\n", food.Description)
+	ctx.Fprintf(&text, " - FDC ID: %d\n", food.FdcId)
+	ctx.Fprintf(&text, " - Nutrients:\n")
+	for _, nutrient := range food.Nutrients {
+		result.Nutrients = append(result.Nutrients, Nutrient{
+			Number: nutrient.Nutrient.Number,
+			Name:   nutrient.Nutrient.Name,
+			Unit:   nutrient.Nutrient.UnitName,
+			Amount: nutrient.Amount,
+		})
+		ctx.Fprintf(&text, " - %s: %.2f\n", ctx.Lang().Nutrient(nutrient.Nutrient.Number, nutrient.Nutrient.Name), nutrient.Amount)
+	}
+	return &cli.Result{Data: result, Rows: result.Nutrients, Text: text.String()}, nil
+}
+
+func addFood(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	date, timeOfDay, err := eatenAt(ctx, inv)
+	if err != nil {
+		return nil, err
+	}
+	entry := FoodEntry{Date: date, Time: timeOfDay, FoodID: inv.Int("food_id"), Quantity: float64(inv.Int("quantity")), MealType: inv.String("meal")}
+	// Save the food history to the database
+	entry.EntryID, err = ctx.Store.AddFoodHistory(ctx.Session.User.ID, entry.FoodID, entry.Date, entry.Time, inv.Int("quantity"), entry.MealType)
+	if err != nil {
+		return nil, ctx.Errorf("error saving food history: %w", err)
+	}
+	recordAdded(ctx, ctx.Sprintf("add food %d", entry.FoodID), []FoodEntry{entry})
+	return &cli.Result{Data: entry, Text: ctx.Translate("Food history saved successfully.\n")}, nil
+}
+
+// recordAdded lets the session undo the entries just added to the food history
+func recordAdded(ctx *Context, description string, entries []FoodEntry) {
+	if len(entries) == 0 {
+		return
+	}
+	userID := ctx.Session.User.ID
+	ctx.Session.Record(description, func(ctx *Context) error {
+		// Delete the entries that are left even when one was already deleted
+		var missing error
+		for _, entry := range entries {
+			deleted, err := ctx.Store.DeleteFoodHistory(userID, entry.EntryID)
+			if err != nil {
+				return err
+			}
+			if !deleted && missing == nil {
+				missing = ctx.Errorf("food entry %d no longer exists", entry.EntryID)
+			}
+		}
+		return missing
+	})
+}
+
+// addFoods saves the foods of a meal under its type at the date and time of
+// day, each quantity multiplied by servings, and appends the saved entries to
+// the result. Foods that fail are reported to ctx.Err and skipped.
+func addFoods(ctx *Context, meal suser.Meal, foods [][2]int, servings int, date string, timeOfDay string, entries *[]FoodEntry, text *strings.Builder) {
+	for _, food := range foods {
+		entry := FoodEntry{Date: date, Time: timeOfDay, FoodID: food[0], Quantity: float64(food[1] * servings), MealType: meal.Type}
+		entryID, err := ctx.Store.AddFoodHistory(ctx.Session.User.ID, food[0], entry.Date, entry.Time, food[1]*servings, meal.Type)
+		if err != nil {
+			ctx.Fprintf(ctx.Err, "Error saving food history: %v\n", err)
+			continue
+		}
+		entry.EntryID = entryID
+		*entries = append(*entries, entry)
+		ctx.Fprintf(text, "Food history for food ID %d saved successfully.\n", food[0])
+	}
+}
+
+func addMeal(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	date, timeOfDay, err := eatenAt(ctx, inv)
+	if err != nil {
+		return nil, err
+	}
+	meal, err := ctx.Store.GetMeal(ctx.Session.User.ID, inv.Int("meal_id"))
+	if err != nil {
+		return nil, ctx.Errorf("error fetching meal: %w", err)
+	}
+	// Get food IDs associated with the meal
+	foods, err := ctx.Store.GetFoodWithMeal(ctx.Session.User.ID, meal.ID)
+	if err != nil {
+		return nil, ctx.Errorf("error fetching food IDs with meal: %w", err)
+	}
+	if len(foods) == 0 {
+		return nil, ctx.Errorf("no food IDs found for the specified meal")
+	}
+	// Save the food history to the database
+	entries := []FoodEntry{}
+	var text strings.Builder
+	addFoods(ctx, meal, foods, 1, date, timeOfDay, &entries, &text)
+	recordAdded(ctx, ctx.Sprintf("add meal %d", meal.ID), entries)
+	return &cli.Result{Data: entries, Text: text.String()}, nil
+}
+
+func addDay(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	date, timeOfDay, err := eatenAt(ctx, inv)
+	if err != nil {
+		return nil, err
+	}
+	// Get meal IDs associated with the day
+	meals, err := ctx.Store.GetMealWithDayPreset(ctx.Session.User.ID, inv.Int("day_id"))
+	if err != nil {
+		return nil, ctx.Errorf("error fetching meal IDs with day: %w", err)
+	}
+	if len(meals) == 0 {
+		return nil, ctx.Errorf("no meal IDs found for the specified day")
+	}
+	// Save the food history to the database
+	entries := []FoodEntry{}
+	var text strings.Builder
+	for _, dayMeal := range meals {
+		meal, err := ctx.Store.GetMeal(ctx.Session.User.ID, dayMeal[0])
+		if err != nil {
+			ctx.Fprintf(ctx.Err, "Error fetching meal: %v\n", err)
+			continue
+		}
+		foods, err := ctx.Store.GetFoodWithMeal(ctx.Session.User.ID, meal.ID)
+		if err != nil {
+			ctx.Fprintf(ctx.Err, "Error fetching food IDs with meal: %v\n", err)
+			continue
+		}
+		if len(foods) == 0 {
+			ctx.Fprintf(ctx.Err, "No food IDs found for meal ID %d.\n", meal.ID)
+			continue
+		}
+		addFoods(ctx, meal, foods, dayMeal[1], date, timeOfDay, &entries, &text)
+	}
+	recordAdded(ctx, ctx.Sprintf("add day %d", inv.Int("day_id")), entries)
+	return &cli.Result{Data: entries, Text: text.String()}, nil
+}
+
+func deleteFood(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	entryID := inv.Int("entry_id")
+	// Move the entry to the trash, undo restores it
+	deleted, err := ctx.Store.DeleteFoodHistory(ctx.Session.User.ID, entryID)
+	if err != nil {
+		return nil, ctx.Errorf("error deleting food history: %w", err)
+	}
+	if !deleted {
+		return nil, ctx.Errorf("no food history entry with ID %d", entryID)
+	}
+	recordTrashed(ctx, ctx.Sprintf("delete food entry %d", entryID), "food", entryID)
+	return &cli.Result{
+		Data: DeletedEntry{EntryID: entryID},
+		Text: ctx.Sprintf("Food history with Entry ID %d deleted successfully.\n", entryID),
+	}, nil
+}
+
+// updateFood changes the quantity, meal type, date or time of day of an entry
+// of the food history, the values not given are kept
+func updateFood(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	entryID := inv.Int("entry_id")
+	if !inv.Has("quantity") && !inv.Has("meal") && !inv.Has("date") && !inv.Has("time") {
+		return nil, ctx.Errorf("nothing to update, give --quantity, --meal, --date or --time")
+	}
+	if inv.Has("quantity") && inv.Int("quantity") <= 0 {
+		return nil, ctx.Errorf("the quantity must be a positive number of grams")
+	}
+	var date, timeOfDay string
+	var err error
+	if inv.Has("date") {
+		if date, err = ctx.Date(inv); err != nil {
+			return nil, err
+		}
+	}
+	if inv.Has("time") {
+		if timeOfDay, err = parseTime(ctx, inv.String("time")); err != nil {
+			return nil, err
+		}
+	}
+	// Keep the previous values to set them back on undo
+	old, found, err := ctx.Store.GetFoodEntry(ctx.Session.User.ID, entryID)
+	if err != nil {
+		return nil, ctx.Errorf("error fetching food history: %w", err)
+	}
+	if !found {
+		return nil, ctx.Errorf("no food history entry with ID %d", entryID)
+	}
+	row, found, err := ctx.Store.UpdateFoodHistory(ctx.Session.User.ID, entryID, date, timeOfDay, inv.Int("quantity"), inv.String("meal"))
+	if err != nil {
+		return nil, ctx.Errorf("error updating food history: %w", err)
+	}
+	if !found {
+		return nil, ctx.Errorf("no food history entry with ID %d", entryID)
+	}
+	userID := ctx.Session.User.ID
+	ctx.Session.Record(ctx.Sprintf("update food entry %d", entryID), func(ctx *Context) error {
+		restored, err := ctx.Store.RestoreFoodHistory(userID, entryID, dateOnly(old[2]), old[5].(string), old[3].(float64), old[4].(string))
+		if err != nil {
+			return err
+		}
+		if !restored {
+			return ctx.Errorf("food entry %d no longer exists", entryID)
+		}
+		return nil
+	})
+	entry := FoodEntry{EntryID: row[0].(int), FoodID: row[1].(int), Date: dateOnly(row[2]), Quantity: row[3].(float64), MealType: row[4].(string), Time: row[5].(string)}
+	return &cli.Result{Data: entry, Text: ctx.Sprintf("Food history with Entry ID %d updated successfully.\n", entryID)}, nil
+}
@@ -0,0 +1,426 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"gotracker/fdcnal"
+	suser "gotracker/structs"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeStore is a Store in memory with the food history and the weigh-ins
+// used by the tests. The methods the commands under test do not call are
+// left to the nil embedded Store and panic.
+type fakeStore struct {
+	Store
+	entries map[int]FoodEntry // Food history by entry ID
+	meals   map[int]suser.Meal
+	foods   map[int][][2]int // Foods of the meals by meal ID
+	weights []float64        // Daily weigh-ins ending today
+	imcs    []float64        // Saved IMC reports
+	eaten   map[int]float64  // Grams of each food eaten every day
+	periods [][2]string      // Periods of the food quantities read
+	actors  []int            // Actors of the scoped stores
+	fail    error            // Returned by the writes when set
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{entries: make(map[int]FoodEntry), meals: make(map[int]suser.Meal), foods: make(map[int][][2]int)}
+}
+
+func (s *fakeStore) As(actorID int, sessionID string) Store {
+	s.actors = append(s.actors, actorID)
+	return s
+}
+
+func (s *fakeStore) GetAliases(userID int) ([][2]string, error) {
+	return nil, nil
+}
+
+func (s *fakeStore) AddFoodHistory(userID int, foodID int, date string, timeOfDay string, quantity int, mealType string) (int, error) {
+	if s.fail != nil {
+		return 0, s.fail
+	}
+	id := len(s.entries) + 1
+	s.entries[id] = FoodEntry{EntryID: id, Date: date, Time: timeOfDay, FoodID: foodID, Quantity: float64(quantity), MealType: mealType}
+	return id, nil
+}
+
+func (s *fakeStore) DeleteFoodHistory(userID int, entryID int) (bool, error) {
+	_, ok := s.entries[entryID]
+	delete(s.entries, entryID)
+	return ok, nil
+}
+
+func (s *fakeStore) CreateMeal(userID int, name string, mealType string) (int, error) {
+	id := len(s.meals) + 1
+	s.meals[id] = suser.Meal{ID: id, Name: name, Type: mealType}
+	return id, nil
+}
+
+func (s *fakeStore) LinkFoodToMeal(userID int, foodID int, mealID int, quantity int) error {
+	s.foods[mealID] = append(s.foods[mealID], [2]int{foodID, quantity})
+	return nil
+}
+
+func (s *fakeStore) CreateIMCHistory(userID int, date string, imc float64, category string) error {
+	s.imcs = append(s.imcs, imc)
+	return nil
+}
+
+func (s *fakeStore) GetFoodQuantitiesByDay(userID int, from string, to string) (map[string]map[int]float64, error) {
+	s.periods = append(s.periods, [2]string{from, to})
+	quantities := make(map[string]map[int]float64)
+	start, _ := time.Parse("2006-01-02", from)
+	end, _ := time.Parse("2006-01-02", to)
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		quantities[day.Format("2006-01-02")] = s.eaten
+	}
+	return quantities, nil
+}
+
+func (s *fakeStore) GetExerciseTotals(userID int, date string) (int, float64, error) {
+	return 30, 200, nil
+}
+
+func (s *fakeStore) GetTargetCalories(userID int) (float64, error) {
+	return 0, nil
+}
+
+func (s *fakeStore) GetWaterTotal(userID int, date string) (int, error) {
+	return 1000, nil
+}
+
+func (s *fakeStore) GetWeightSeries(userID int) ([]time.Time, []float64, error) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	dates := make([]time.Time, len(s.weights))
+	for i := range s.weights {
+		dates[i] = today.AddDate(0, 0, i-len(s.weights)+1)
+	}
+	return dates, s.weights, nil
+}
+
+// newTestContext returns the context of a session logged in as a member
+// over store, whose FoodData Central client calls fdc, closed at the end of
+// the test
+func newTestContext(t *testing.T, store Store, fdc http.Handler) *Context {
+	t.Helper()
+	client := fdcnal.NewClient("http://fdc.invalid/", "test-key")
+	if fdc != nil {
+		server := httptest.NewServer(fdc)
+		t.Cleanup(server.Close)
+		client = fdcnal.NewClient(server.URL+"/", "test-key")
+		client.HTTP = server.Client()
+	}
+	session := NewSessionManager().Open(SessionREPL)
+	session.Start(suser.SUser{ID: 1, Username: "john", Role: suser.RoleMember, Lang: "en", Timezone: "UTC", TargetWeight: 75})
+	return &Context{Store: store, FDC: client, Session: session, Out: io.Discard, Err: io.Discard}
+}
+
+// foodHandler serves the details of the foods of FoodData Central with the
+// kcal per 100g given by their ID, 50g of water per 100g and no energy when
+// the kcal are 0
+func foodHandler(kcal map[int]float64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var id int
+		if _, err := fmt.Sscanf(r.URL.Path, "/food/%d", &id); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		energy, ok := kcal[id]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		nutrients := `{"nutrient": {"number": "255", "name": "Water", "unitName": "G"}, "amount": 50}`
+		if energy > 0 {
+			nutrients = fmt.Sprintf(`{"nutrient": {"number": "208", "name": "Energy", "unitName": "KCAL"}, "amount": %g}, `, energy) + nutrients
+		}
+		fmt.Fprintf(w, `{"fdcId": %d, "description": "Food %d", "foodNutrients": [%s]}`, id, id, nutrients)
+	})
+}
+
+func TestAddFood(t *testing.T) {
+	today := time.Now().UTC().Format("2006-01-02")
+	yesterday := time.Now().UTC().AddDate(0, 0, -1).Format("2006-01-02")
+	tests := []struct {
+		name  string
+		words []string
+		want  FoodEntry
+		err   string
+	}{
+		{"today", []string{"add", "food", "123", "150"}, FoodEntry{EntryID: 1, Date: today, FoodID: 123, Quantity: 150}, ""},
+		{"past day", []string{"add", "food", "123", "80", "--date", "yesterday"}, FoodEntry{EntryID: 1, Date: yesterday, FoodID: 123, Quantity: 80}, ""},
+		{"all flags", []string{"add", "food", "7", "200", "--meal", "lunch", "--date", "2024-01-02", "--time", "12:30"}, FoodEntry{EntryID: 1, Date: "2024-01-02", Time: "12:30", FoodID: 7, Quantity: 200, MealType: "lunch"}, ""},
+		{"future day", []string{"add", "food", "123", "150", "--date", "2999-01-01"}, FoodEntry{}, "the date 2999-01-01 is in the future"},
+		{"invalid time", []string{"add", "food", "123", "150", "--time", "noon"}, FoodEntry{}, "invalid time 'noon', expected HH:MM"},
+		{"invalid quantity", []string{"add", "food", "123", "lots"}, FoodEntry{}, "lots"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := newFakeStore()
+			ctx := newTestContext(t, store, nil)
+			result, err := NewRegistry(ctx).Run(test.words)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("Run error = %v, want %q", err, test.err)
+				}
+				if len(store.entries) != 0 {
+					t.Errorf("a refused command saved %v", store.entries)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			entry, ok := result.Data.(FoodEntry)
+			if !ok {
+				t.Fatalf("result data is a %T, want a FoodEntry", result.Data)
+			}
+			if test.want.Date == today && test.want.Time == "" {
+				// Logging today defaults to now
+				if entry.Time == "" {
+					t.Error("an entry of today has no time")
+				}
+				test.want.Time = entry.Time
+			}
+			if entry != test.want || store.entries[1] != test.want {
+				t.Errorf("added %+v, saved %+v, want %+v", entry, store.entries[1], test.want)
+			}
+			if len(store.actors) != 1 || store.actors[0] != 1 {
+				t.Errorf("the store acted for %v, want the session user", store.actors)
+			}
+		})
+	}
+}
+
+func TestAddFoodErrors(t *testing.T) {
+	store := newFakeStore()
+	ctx := newTestContext(t, store, nil)
+	ctx.Session.Start(suser.SUser{})
+	if _, err := NewRegistry(ctx).Run([]string{"add", "food", "123", "150"}); !errors.Is(err, ErrNotLoggedIn) {
+		t.Errorf("add food logged out: %v, want ErrNotLoggedIn", err)
+	}
+
+	ctx = newTestContext(t, store, nil)
+	store.fail = errors.New("connection refused")
+	_, err := NewRegistry(ctx).Run([]string{"add", "food", "123", "150"})
+	if err == nil || !strings.Contains(err.Error(), "error saving food history: connection refused") {
+		t.Errorf("add food with the store down: %v", err)
+	}
+}
+
+func TestAddFoodUndo(t *testing.T) {
+	store := newFakeStore()
+	ctx := newTestContext(t, store, nil)
+	registry := NewRegistry(ctx)
+	if _, err := registry.Run([]string{"add", "food", "123", "150"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := registry.Run([]string{"undo"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.entries) != 0 {
+		t.Errorf("undo left %v in the food history", store.entries)
+	}
+	if _, err := registry.Run([]string{"undo"}); err == nil {
+		t.Error("undo succeeded with nothing to undo")
+	}
+
+	// Undo fails when the entry was deleted since
+	if _, err := registry.Run([]string{"add", "food", "123", "150"}); err != nil {
+		t.Fatal(err)
+	}
+	store.entries = make(map[int]FoodEntry)
+	if _, err := registry.Run([]string{"undo"}); err == nil || !strings.Contains(err.Error(), "food entry 1 no longer exists") {
+		t.Errorf("undo of a deleted entry: %v", err)
+	}
+}
+
+func TestTrendWeight(t *testing.T) {
+	flat := []float64{80, 80, 80, 80, 80, 80, 80, 80, 80, 80}
+	tests := []struct {
+		name    string
+		weights []float64
+		words   []string
+		want    *WeightTrend
+		text    string
+		err     string
+	}{
+		{"no weigh-ins", nil, []string{"trend", "weight"}, nil, "No weight history found.", ""},
+		{"flat", flat, []string{"trend", "weight", "7"}, &WeightTrend{Latest: 80, Trend: 80, WindowDays: 7, Samples: 7, TargetWeight: 75}, "Target weight of 75 kg is not reached", ""},
+		{"last weigh-in", append(flat[:9:9], 81), []string{"trend", "weight", "3"}, &WeightTrend{Latest: 81, Trend: 80.1, WeeklyRate: 0.35, WindowDays: 3, Samples: 3, TargetWeight: 75}, "Trend weight: 80.1 kg", ""},
+		{"invalid window", flat, []string{"trend", "weight", "0"}, nil, "", "the window must be a positive number of days"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := newFakeStore()
+			store.weights = test.weights
+			result, err := NewRegistry(newTestContext(t, store, nil)).Run(test.words)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("Run error = %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(result.Text, test.text) {
+				t.Errorf("text %q does not contain %q", result.Text, test.text)
+			}
+			if test.want == nil {
+				if result.Data != nil {
+					t.Errorf("data = %+v, want none", result.Data)
+				}
+				return
+			}
+			if data, ok := result.Data.(WeightTrend); !ok || data != *test.want {
+				t.Errorf("data = %+v, want %+v", result.Data, *test.want)
+			}
+		})
+	}
+}
+
+func TestDetails(t *testing.T) {
+	ctx := newTestContext(t, newFakeStore(), foodHandler(map[int]float64{42: 250}))
+	result, err := NewRegistry(ctx).Run([]string{"details", "42"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	food, ok := result.Data.(FoodDetails)
+	if !ok || food.FoodID != 42 || len(food.Nutrients) != 2 {
+		t.Fatalf("data = %+v, want the 2 nutrients of food 42", result.Data)
+	}
+	if food.Nutrients[0] != (Nutrient{Number: "208", Name: "Energy", Unit: "KCAL", Amount: 250}) {
+		t.Errorf("first nutrient = %+v", food.Nutrients[0])
+	}
+
+	if _, err := NewRegistry(ctx).Run([]string{"details", "43"}); err == nil || !strings.Contains(err.Error(), "error fetching food details") {
+		t.Errorf("details of an unknown food: %v", err)
+	}
+}
+
+func TestSharedMealsAdminOnly(t *testing.T) {
+	tests := [][]string{
+		{"create", "meal", "porridge", "breakfast"},
+		{"link", "food_to_meal", "123", "1", "80"},
+	}
+	for _, words := range tests {
+		store := newFakeStore()
+		ctx := newTestContext(t, store, nil)
+		if _, err := NewRegistry(ctx).Run(words); !errors.Is(err, ErrForbidden) {
+			t.Errorf("%s by a member: %v, want ErrForbidden", strings.Join(words, " "), err)
+		}
+		if len(store.meals) != 0 || len(store.foods) != 0 {
+			t.Errorf("%s by a member changed the meals", strings.Join(words, " "))
+		}
+
+		ctx.Session.User.Role = suser.RoleAdmin
+		if _, err := NewRegistry(ctx).Run(words); err != nil {
+			t.Errorf("%s by an administrator: %v", strings.Join(words, " "), err)
+		}
+		if len(store.meals)+len(store.foods) != 1 {
+			t.Errorf("%s by an administrator did not change the meals", strings.Join(words, " "))
+		}
+	}
+}
+
+func TestIMCWithoutHeight(t *testing.T) {
+	for _, words := range [][]string{{"imc"}, {"report", "imc"}} {
+		store := newFakeStore()
+		ctx := newTestContext(t, store, nil)
+		ctx.Session.User.Age = 30
+		ctx.Session.User.Weight = 70
+		_, err := NewRegistry(ctx).Run(words)
+		if err == nil || !strings.Contains(err.Error(), "set your height first") {
+			t.Errorf("%s without a height: %v", strings.Join(words, " "), err)
+		}
+		if len(store.imcs) != 0 {
+			t.Errorf("%s without a height saved %v", strings.Join(words, " "), store.imcs)
+		}
+
+		ctx.Session.User.Height = 175
+		if _, err := NewRegistry(ctx).Run(words); err != nil {
+			t.Errorf("%s: %v", strings.Join(words, " "), err)
+		}
+	}
+}
+
+func TestTDEE(t *testing.T) {
+	today := time.Now().UTC()
+	tests := []struct {
+		name   string
+		words  []string
+		active string
+		from   string
+		to     string
+	}{
+		{"today", []string{"tdee"}, "", today.AddDate(0, 0, -20).Format("2006-01-02"), today.Format("2006-01-02")},
+		{"period", []string{"tdee", "14"}, "", today.AddDate(0, 0, -13).Format("2006-01-02"), today.Format("2006-01-02")},
+		{"date", []string{"tdee", "--date", "yesterday"}, "", today.AddDate(0, 0, -21).Format("2006-01-02"), today.AddDate(0, 0, -1).Format("2006-01-02")},
+		{"active date", []string{"tdee"}, today.AddDate(0, 0, -2).Format("2006-01-02"), today.AddDate(0, 0, -22).Format("2006-01-02"), today.AddDate(0, 0, -2).Format("2006-01-02")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := newFakeStore()
+			store.weights = make([]float64, 40)
+			for i := range store.weights {
+				store.weights[i] = 80
+			}
+			// 600 g at 250 kcal and 500 g at 100 kcal per 100 g every day
+			store.eaten = map[int]float64{1: 600, 2: 500}
+			ctx := newTestContext(t, store, foodHandler(map[int]float64{1: 250, 2: 100}))
+			ctx.Session.ActiveDate = test.active
+			result, err := NewRegistry(ctx).Run(test.words)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(store.periods) != 1 || store.periods[0] != [2]string{test.from, test.to} {
+				t.Errorf("read the food of %v, want %s - %s", store.periods, test.from, test.to)
+			}
+			balance, ok := result.Data.(*EnergyBalance)
+			if !ok {
+				t.Fatalf("result data is a %T, want an *EnergyBalance", result.Data)
+			}
+			if balance.Maintenance != 2000 || balance.AverageIntake != 2000 || balance.WeeklyChange != 0 {
+				t.Errorf("balance = %+v, want a maintenance of 2000 kcal", balance)
+			}
+		})
+	}
+}
+
+func TestSummaryHydration(t *testing.T) {
+	store := newFakeStore()
+	// Food 3 reports its water but not its energy
+	store.eaten = map[int]float64{1: 600, 3: 500}
+	ctx := newTestContext(t, store, foodHandler(map[int]float64{1: 250, 3: 0}))
+	ctx.Session.User.Weight = 70
+	result, err := NewRegistry(ctx).Run([]string{"summary"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := result.Data.(Summary)
+	if !ok {
+		t.Fatalf("result data is a %T, want a Summary", result.Data)
+	}
+	// 70 kg × 35 ml plus 30 minutes of exercise × 500 ml per hour
+	want := Summary{Date: got.Date, Intake: 1500, Exercise: 200, Net: 1300, Hydration: 1550, HydrationTarget: 2700, HydrationPercent: 57, FoodWater: 550}
+	if got != want {
+		t.Errorf("summary = %+v, want %+v", got, want)
+	}
+}
+
+func TestSecretCommands(t *testing.T) {
+	registry := NewRegistry(newTestContext(t, newFakeStore(), nil))
+	for line, secret := range map[string]bool{"reset 5f2a": true, "admin reset john": false, "login john": false, "add food 1 100": false} {
+		if registry.Secret(line) != secret {
+			t.Errorf("Secret(%q) = %v, want %v", line, !secret, secret)
+		}
+	}
+}
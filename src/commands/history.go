@@ -0,0 +1,139 @@
+package commands
+
+import (
+	"fmt"
+	"gotracker/cli"
+	"strings"
+)
+
+func historyFood(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	// Get food history for the user
+	foodHistory, err := ctx.Store.GetFoodHistory(ctx.Session.User.ID)
+	if err != nil {
+		return nil, ctx.Errorf("error fetching food history: %w", err)
+	}
+	entries := []FoodEntry{}
+	var text strings.Builder
+	ctx.Fprintf(&text, "Food History:\n")
+	for _, food := range foodHistory {
+		entry := FoodEntry{EntryID: food[3].(int), Date: dateOnly(food[1]), Time: food[4].(string), FoodID: food[0].(int), Quantity: food[2].(float64)}
+		entries = append(entries, entry)
+		if entry.Time != "" {
+			ctx.Fprintf(&text, " - Date: %s %s | Food ID: %d | Quantity: %v | Entry ID: %d\n", entry.Date, entry.Time, entry.FoodID, entry.Quantity, entry.EntryID)
+		} else {
+			ctx.Fprintf(&text, " - Date: %s | Food ID: %d | Quantity: %v | Entry ID: %d\n", entry.Date, entry.FoodID, entry.Quantity, entry.EntryID)
+		}
+	}
+	return historyResult(entries, len(entries), "No food history found.\n", &text), nil
+}
+
+func historyWeight(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	// Get weight history for the user
+	weightHistory, err := ctx.Store.GetWeightHistory(ctx.Session.User.ID)
+	if err != nil {
+		return nil, ctx.Errorf("error fetching weight history: %w", err)
+	}
+	entries := []Weight{}
+	var text strings.Builder
+	ctx.Fprintf(&text, "Weight History:\n")
+	for _, weight := range weightHistory {
+		entry := Weight{Date: dateOnly(weight[0]), Weight: toFloat(weight[1])}
+		entries = append(entries, entry)
+		ctx.Fprintf(&text, " - Date: %s | Weight: %v\n", entry.Date, entry.Weight)
+	}
+	return historyResult(entries, len(entries), "No weight history found.\n", &text), nil
+}
+
+func historyIMC(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	// Get IMC history for the user
+	imcHistory, err := ctx.Store.GetIMCHistory(ctx.Session.User.ID)
+	if err != nil {
+		return nil, ctx.Errorf("error fetching IMC history: %w", err)
+	}
+	entries := []IMCEntry{}
+	var text strings.Builder
+	ctx.Fprintf(&text, "IMC History:\n")
+	for _, imc := range imcHistory {
+		entry := IMCEntry{Date: dateOnly(imc[0]), IMC: round(toFloat(imc[1]), 2), Category: fmt.Sprint(imc[2])}
+		entries = append(entries, entry)
+		ctx.Fprintf(&text, " - Date: %s | IMC: %v | Category: %s\n", entry.Date, entry.IMC, ctx.Translate(entry.Category))
+	}
+	return historyResult(entries, len(entries), "No IMC history found.\n", &text), nil
+}
+
+func historyBodyFat(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	// Get body fat history for the user
+	bodyFatHistory, err := ctx.Store.GetBodyFatHistory(ctx.Session.User.ID)
+	if err != nil {
+		return nil, ctx.Errorf("error fetching body fat history: %w", err)
+	}
+	entries := []BodyFat{}
+	var text strings.Builder
+	ctx.Fprintf(&text, "Body Fat History:\n")
+	for _, bodyFat := range bodyFatHistory {
+		entry := BodyFat{Date: dateOnly(bodyFat[0]), BodyFat: round(toFloat(bodyFat[1]), 2)}
+		entries = append(entries, entry)
+		ctx.Fprintf(&text, " - Date: %s | Body Fat: %v\n", entry.Date, entry.BodyFat)
+	}
+	return historyResult(entries, len(entries), "No body fat history found.\n", &text), nil
+}
+
+func historyExercise(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	// Get exercise history for the user
+	exerciseHistory, err := ctx.Store.GetExerciseHistory(ctx.Session.User.ID)
+	if err != nil {
+		return nil, ctx.Errorf("error fetching exercise history: %w", err)
+	}
+	entries := []ExerciseEntry{}
+	var text strings.Builder
+	ctx.Fprintf(&text, "Exercise History:\n")
+	for _, row := range exerciseHistory {
+		entry := ExerciseEntry{
+			EntryID:   row[5].(int),
+			Date:      dateOnly(row[0]),
+			Activity:  fmt.Sprint(row[1]),
+			Minutes:   row[2].(int),
+			Intensity: fmt.Sprint(row[3]),
+			Calories:  round(toFloat(row[4]), 0),
+		}
+		entries = append(entries, entry)
+		ctx.Fprintf(&text, " - Date: %s | Activity: %s | Minutes: %d | Intensity: %s | Calories: %v | Entry ID: %d\n", entry.Date, entry.Activity, entry.Minutes, entry.Intensity, entry.Calories, entry.EntryID)
+	}
+	return historyResult(entries, len(entries), "No exercise history found.\n", &text), nil
+}
+
+func historyWater(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	// Get water history for the user
+	waterHistory, err := ctx.Store.GetWaterHistory(ctx.Session.User.ID)
+	if err != nil {
+		return nil, ctx.Errorf("error fetching water history: %w", err)
+	}
+	entries := []WaterEntry{}
+	var text strings.Builder
+	ctx.Fprintf(&text, "Water History:\n")
+	for _, water := range waterHistory {
+		entry := WaterEntry{Date: dateOnly(water[0]), Amount: water[1].(int)}
+		entries = append(entries, entry)
+		ctx.Fprintf(&text, " - Date: %s | Water: %d ml\n", entry.Date, entry.Amount)
+	}
+	return historyResult(entries, len(entries), "No water history found.\n", &text), nil
+}
+
+// historyResult returns the entries of a history, with the empty message as text when there are none
+func historyResult(entries interface{}, count int, empty string, text *strings.Builder) *cli.Result {
+	if count == 0 {
+		return &cli.Result{Data: entries, Text: empty}
+	}
+	return &cli.Result{Data: entries, Text: text.String()}
+}
+
+// toFloat converts a numeric column value to float64
+func toFloat(value interface{}) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	}
+	return 0
+}
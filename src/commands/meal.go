@@ -0,0 +1,163 @@
+package commands
+
+import (
+	"gotracker/cli"
+	"gotracker/exercise"
+	"strings"
+)
+
+func createMeal(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	meal := Meal{Name: inv.String("meal_name"), Type: inv.String("meal_type")}
+	// Create a new meal in the database
+	mealID, err := ctx.Store.CreateMeal(ctx.Session.User.ID, meal.Name, meal.Type)
+	if err != nil {
+		return nil, ctx.Errorf("error creating meal: %w", err)
+	}
+	meal.ID = mealID
+	return &cli.Result{Data: meal, Text: ctx.Sprintf("Meal '%s' created successfully with ID: %d\n", meal.Name, meal.ID)}, nil
+}
+
+func createDay(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	day := DayPreset{Name: inv.String("day_name")}
+	// Create a new day in the database
+	dayID, err := ctx.Store.CreateDayPreset(ctx.Session.User.ID, day.Name)
+	if err != nil {
+		return nil, ctx.Errorf("error creating day: %w", err)
+	}
+	day.ID = dayID
+	return &cli.Result{Data: day, Text: ctx.Sprintf("Day '%s' created successfully with ID: %d\n", day.Name, day.ID)}, nil
+}
+
+func listMeals(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	// List all meals in the database
+	meals, err := ctx.Store.GetAllMeals(ctx.Session.User.ID)
+	if err != nil {
+		return nil, ctx.Errorf("error fetching meals: %w", err)
+	}
+	if len(meals) == 0 {
+		return &cli.Result{Data: []Meal{}, Text: ctx.Translate("No meals found.\n")}, nil
+	}
+	result := make([]Meal, len(meals))
+	var text strings.Builder
+	ctx.Fprintf(&text, "Meals:\n")
+	for i, meal := range meals {
+		result[i] = Meal{ID: meal.ID, Name: meal.Name, Type: meal.Type}
+		ctx.Fprintf(&text, " - ID: %d | Name: %s | Type: %s\n", meal.ID, meal.Name, meal.Type)
+	}
+	return &cli.Result{Data: result, Text: text.String()}, nil
+}
+
+func listDays(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	// List the day presets of the session user
+	days, err := ctx.Store.GetAllDays(ctx.Session.User.ID)
+	if err != nil {
+		return nil, ctx.Errorf("error fetching days: %w", err)
+	}
+	if len(days) == 0 {
+		return &cli.Result{Data: []DayPreset{}, Text: ctx.Translate("No days found.\n")}, nil
+	}
+	result := make([]DayPreset, len(days))
+	var text strings.Builder
+	ctx.Fprintf(&text, "Days:\n")
+	for i, day := range days {
+		result[i] = DayPreset{ID: day.ID, Name: day.Name}
+		ctx.Fprintf(&text, " - ID: %d | Name: %s\n", day.ID, day.Name)
+	}
+	return &cli.Result{Data: result, Text: text.String()}, nil
+}
+
+func listActivities(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	var activities []Activity
+	var text strings.Builder
+	ctx.Fprintf(&text, "Activities:\n")
+	for _, name := range exercise.Names() {
+		activity, _ := exercise.Lookup(name)
+		entry := Activity{
+			Name:        name,
+			METLight:    activity.MET[exercise.IntensityLight],
+			METModerate: activity.MET[exercise.IntensityModerate],
+			METVigorous: activity.MET[exercise.IntensityVigorous],
+		}
+		activities = append(activities, entry)
+		ctx.Fprintf(&text, " - %s | MET light: %.1f | moderate: %.1f | vigorous: %.1f\n", name, entry.METLight, entry.METModerate, entry.METVigorous)
+	}
+	return &cli.Result{Data: activities, Text: text.String()}, nil
+}
+
+func linkFoodToMeal(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	link := MealFood{MealID: inv.Int("meal_id"), FoodID: inv.Int("food_id"), Quantity: inv.Int("quantity")}
+	// Link food to meal in the database
+	err := ctx.Store.LinkFoodToMeal(ctx.Session.User.ID, link.FoodID, link.MealID, link.Quantity)
+	if err != nil {
+		return nil, ctx.Errorf("error linking food to meal: %w", err)
+	}
+	return &cli.Result{Data: link, Text: ctx.Sprintf("Food ID %d linked to Meal ID %d successfully.\n", link.FoodID, link.MealID)}, nil
+}
+
+func linkMealToDay(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	link := DayMeal{DayID: inv.Int("day_id"), MealID: inv.Int("meal_id"), Quantity: inv.Int("quantity")}
+	// Link meal to day in the database
+	linked, err := ctx.Store.LinkMealToDayPreset(ctx.Session.User.ID, link.MealID, link.DayID, link.Quantity)
+	if err != nil {
+		return nil, ctx.Errorf("error linking meal to day: %w", err)
+	}
+	if !linked {
+		return nil, ctx.Errorf("day %d not found", link.DayID)
+	}
+	return &cli.Result{Data: link, Text: ctx.Sprintf("Meal ID %d linked to Day ID %d successfully.\n", link.MealID, link.DayID)}, nil
+}
+
+func showMeal(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	meal, err := ctx.Store.GetMeal(ctx.Session.User.ID, inv.Int("meal_id"))
+	if err != nil {
+		return nil, ctx.Errorf("error fetching meal: %w", err)
+	}
+	foods, err := ctx.Store.GetFoodWithMeal(ctx.Session.User.ID, meal.ID)
+	if err != nil {
+		return nil, ctx.Errorf("error fetching food IDs with meal: %w", err)
+	}
+	result := MealDetails{ID: meal.ID, Name: meal.Name, Type: meal.Type, Foods: []MealFood{}}
+	var text strings.Builder
+	ctx.Fprintf(&text, "Meal '%s' (%s), ID: %d\n", meal.Name, meal.Type, meal.ID)
+	for _, food := range foods {
+		result.Foods = append(result.Foods, MealFood{MealID: meal.ID, FoodID: food[0], Quantity: food[1]})
+		ctx.Fprintf(&text, " - Food ID: %d | Quantity: %d g\n", food[0], food[1])
+	}
+	if len(foods) == 0 {
+		ctx.Fprintf(&text, "No foods linked to this meal yet.\n")
+	}
+	return &cli.Result{Data: result, Rows: result.Foods, Text: text.String()}, nil
+}
+
+func showDay(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	days, err := ctx.Store.GetAllDays(ctx.Session.User.ID)
+	if err != nil {
+		return nil, ctx.Errorf("error fetching days: %w", err)
+	}
+	dayID := inv.Int("day_id")
+	result := DayDetails{ID: dayID, Meals: []DayMeal{}}
+	found := false
+	for _, day := range days {
+		if day.ID == dayID {
+			result.Name = day.Name
+			found = true
+		}
+	}
+	if !found {
+		return nil, ctx.Errorf("day %d not found", dayID)
+	}
+	meals, err := ctx.Store.GetMealWithDayPreset(ctx.Session.User.ID, dayID)
+	if err != nil {
+		return nil, ctx.Errorf("error fetching meal IDs with day: %w", err)
+	}
+	var text strings.Builder
+	ctx.Fprintf(&text, "Day '%s', ID: %d\n", result.Name, dayID)
+	for _, meal := range meals {
+		result.Meals = append(result.Meals, DayMeal{DayID: dayID, MealID: meal[0], Quantity: meal[1]})
+		ctx.Fprintf(&text, " - Meal ID: %d | Servings: %d\n", meal[0], meal[1])
+	}
+	if len(meals) == 0 {
+		ctx.Fprintf(&text, "No meals linked to this day yet.\n")
+	}
+	return &cli.Result{Data: result, Rows: result.Meals, Text: text.String()}, nil
+}
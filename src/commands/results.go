@@ -0,0 +1,524 @@
+package commands
+
+import (
+	"fmt"
+	suser "gotracker/structs"
+	"math"
+)
+
+// The types below are the JSON schemas of the command results. Their field
+// names are part of the interface of --output json and --output csv and must
+// not be renamed; new fields may be added. Dates are YYYY-MM-DD, energies are
+// in kcal, weights in kg, heights in cm and volumes in ml.
+
+// User is the result of register, login and update, lang is omitted when the
+// user has no preferred language.
+//
+//	{"id": 1, "firstname": "Jane", "lastname": "Doe", "age": 30, "weight": 70, "height": 170, "target_weight": 65, "lang": "fr", "role": "member"}
+type User struct {
+	ID           int    `json:"id"`
+	Username     string `json:"username,omitempty"`
+	Email        string `json:"email,omitempty"`
+	Firstname    string `json:"firstname"`
+	Lastname     string `json:"lastname"`
+	Age          int    `json:"age"`
+	Weight       int    `json:"weight"`
+	Height       int    `json:"height"`
+	TargetWeight int    `json:"target_weight"`
+	Lang         string `json:"lang,omitempty"`
+	Role         string `json:"role,omitempty"`
+}
+
+func userResult(user *suser.SUser) User {
+	return User{
+		ID:           user.ID,
+		Username:     user.Username,
+		Email:        user.Email,
+		Firstname:    user.Firstname,
+		Lastname:     user.Lastname,
+		Age:          user.Age,
+		Weight:       user.Weight,
+		Height:       user.Height,
+		TargetWeight: user.TargetWeight,
+		Lang:         user.Lang,
+		Role:         user.Role,
+	}
+}
+
+// IMC is the result of imc and report imc. The healthy range fields are
+// null under 18 where adult categories do not apply, the distance is
+// positive above the range, negative below and 0 within.
+//
+//	{"date": "2024-01-02", "imc": 24.22, "category": "Normal weight", "healthy_weight_min": 53.5, "healthy_weight_max": 72, "distance_to_healthy": 0}
+type IMC struct {
+	Date              string   `json:"date,omitempty"`
+	IMC               float64  `json:"imc"`
+	Category          string   `json:"category"`
+	HealthyWeightMin  *float64 `json:"healthy_weight_min"`
+	HealthyWeightMax  *float64 `json:"healthy_weight_max"`
+	DistanceToHealthy *float64 `json:"distance_to_healthy"`
+}
+
+// IMCEntry is an entry of history imc.
+//
+//	{"date": "2024-01-02", "imc": 24.22, "category": "Normal weight"}
+type IMCEntry struct {
+	Date     string  `json:"date"`
+	IMC      float64 `json:"imc"`
+	Category string  `json:"category"`
+}
+
+// BodyFat is the result of bodyfat and report bodyfat, and an entry of history bodyfat.
+//
+//	{"date": "2024-01-02", "body_fat": 14}
+type BodyFat struct {
+	Date    string  `json:"date,omitempty"`
+	BodyFat float64 `json:"body_fat"`
+}
+
+// Weight is the result of report weight and an entry of history weight.
+//
+//	{"date": "2024-01-02", "weight": 70}
+type Weight struct {
+	Date   string  `json:"date"`
+	Weight float64 `json:"weight"`
+}
+
+// WeightTrend is the result of trend weight, target_date is omitted when the
+// target is not reached at the current rate.
+//
+//	{"latest": 70.2, "trend": 70.6, "weekly_rate": -0.35, "window_days": 14, "samples": 9, "target_weight": 65, "target_date": "2024-05-01"}
+type WeightTrend struct {
+	Latest       float64 `json:"latest"`
+	Trend        float64 `json:"trend"`
+	WeeklyRate   float64 `json:"weekly_rate"`
+	WindowDays   int     `json:"window_days"`
+	Samples      int     `json:"samples"`
+	TargetWeight int     `json:"target_weight"`
+	TargetDate   string  `json:"target_date,omitempty"`
+}
+
+// EnergyBalance is the result of tdee, applied_target is set by --apply.
+//
+//	{"average_intake": 2100, "weekly_change": -0.2, "maintenance": 2320, "confidence": "high", "logged_days": 20, "days": 21, "applied_target": 2320}
+type EnergyBalance struct {
+	AverageIntake float64  `json:"average_intake"`
+	WeeklyChange  float64  `json:"weekly_change"`
+	Maintenance   float64  `json:"maintenance"`
+	Confidence    string   `json:"confidence"`
+	LoggedDays    int      `json:"logged_days"`
+	Days          int      `json:"days"`
+	AppliedTarget *float64 `json:"applied_target,omitempty"`
+}
+
+// FoodEntry is an entry of the food history, logged by add, changed by
+// update food and listed by history food. The meal type is not listed by the
+// history, the time of day (HH:MM) is omitted when unknown.
+//
+//	{"entry_id": 12, "date": "2024-01-02", "time": "12:30", "food_id": 171705, "quantity": 150, "meal_type": "lunch"}
+type FoodEntry struct {
+	EntryID  int     `json:"entry_id,omitempty"`
+	Date     string  `json:"date"`
+	Time     string  `json:"time,omitempty"`
+	FoodID   int     `json:"food_id"`
+	Quantity float64 `json:"quantity"`
+	MealType string  `json:"meal_type,omitempty"`
+}
+
+// DeletedEntry is the result of delete food.
+//
+//	{"entry_id": 12}
+type DeletedEntry struct {
+	EntryID int `json:"entry_id"`
+}
+
+// FoodMatch is a result of search food.
+//
+//	{"food_id": 171705, "description": "Avocados, raw, all commercial varieties", "data_type": "SR Legacy"}
+type FoodMatch struct {
+	FoodID      int    `json:"food_id"`
+	Description string `json:"description"`
+	DataType    string `json:"data_type"`
+}
+
+// FoodDetails is the result of details, with nutrient amounts per 100g.
+//
+//	{"food_id": 171705, "description": "...", "nutrients": [{"number": "208", "name": "Energy", "unit": "kcal", "amount": 160}]}
+type FoodDetails struct {
+	FoodID      int        `json:"food_id"`
+	Description string     `json:"description"`
+	Nutrients   []Nutrient `json:"nutrients"`
+}
+
+// Nutrient is a nutrient of FoodDetails
+type Nutrient struct {
+	Number string  `json:"number"`
+	Name   string  `json:"name"`
+	Unit   string  `json:"unit"`
+	Amount float64 `json:"amount"`
+}
+
+// Meal is the result of create meal and an entry of list meal.
+//
+//	{"id": 3, "name": "porridge", "type": "breakfast"}
+type Meal struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// DayPreset is the result of create day and an entry of list day.
+//
+//	{"id": 2, "name": "workday"}
+type DayPreset struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// MealDetails is the result of show meal, with the foods of the meal.
+//
+//	{"id": 3, "name": "porridge", "type": "breakfast", "foods": [{"meal_id": 3, "food_id": 171705, "quantity": 100}]}
+type MealDetails struct {
+	ID    int        `json:"id"`
+	Name  string     `json:"name"`
+	Type  string     `json:"type"`
+	Foods []MealFood `json:"foods"`
+}
+
+// DayDetails is the result of show day, with the meals of the day preset.
+//
+//	{"id": 2, "name": "workday", "meals": [{"day_id": 2, "meal_id": 3, "quantity": 1}]}
+type DayDetails struct {
+	ID    int       `json:"id"`
+	Name  string    `json:"name"`
+	Meals []DayMeal `json:"meals"`
+}
+
+// MealFood is the result of link food_to_meal, quantity is in grams.
+//
+//	{"meal_id": 3, "food_id": 171705, "quantity": 100}
+type MealFood struct {
+	MealID   int `json:"meal_id"`
+	FoodID   int `json:"food_id"`
+	Quantity int `json:"quantity"`
+}
+
+// DayMeal is the result of link meal_to_day, quantity is a number of servings.
+//
+//	{"day_id": 2, "meal_id": 3, "quantity": 1}
+type DayMeal struct {
+	DayID    int `json:"day_id"`
+	MealID   int `json:"meal_id"`
+	Quantity int `json:"quantity"`
+}
+
+// Activity is an entry of list activity.
+//
+//	{"name": "running", "met_light": 7, "met_moderate": 9.8, "met_vigorous": 11.8}
+type Activity struct {
+	Name        string  `json:"name"`
+	METLight    float64 `json:"met_light"`
+	METModerate float64 `json:"met_moderate"`
+	METVigorous float64 `json:"met_vigorous"`
+}
+
+// ExerciseEntry is the result of log exercise and an entry of history exercise.
+//
+//	{"entry_id": 4, "date": "2024-01-02", "activity": "running", "minutes": 30, "intensity": "moderate", "calories": 343}
+type ExerciseEntry struct {
+	EntryID   int     `json:"entry_id,omitempty"`
+	Date      string  `json:"date"`
+	Activity  string  `json:"activity"`
+	Minutes   int     `json:"minutes"`
+	Intensity string  `json:"intensity"`
+	Calories  float64 `json:"calories"`
+}
+
+// WaterEntry is the result of log water and an entry of history water, the
+// history sums the water drunk per day.
+//
+//	{"date": "2024-01-02", "amount": 1500}
+type WaterEntry struct {
+	Date   string `json:"date"`
+	Amount int    `json:"amount"`
+}
+
+// Summary is the result of summary, target and remaining are null when no
+// calorie target is set.
+//
+//	{"date": "2024-01-02", "intake": 1800, "exercise": 300, "net": 1500, "target": 2300, "remaining": 800,
+//	 "hydration": 1750, "hydration_target": 2450, "hydration_percent": 71, "food_water": 250}
+type Summary struct {
+	Date             string   `json:"date"`
+	Intake           float64  `json:"intake"`
+	Exercise         float64  `json:"exercise"`
+	Net              float64  `json:"net"`
+	Target           *float64 `json:"target"`
+	Remaining        *float64 `json:"remaining"`
+	Hydration        int      `json:"hydration"`
+	HydrationTarget  int      `json:"hydration_target"`
+	HydrationPercent int      `json:"hydration_percent"`
+	FoodWater        int      `json:"food_water"`
+}
+
+// DayLog is the result of today: the foods logged today and the nutrients
+// eaten against the targets of the user, targets are null when not set.
+//
+//	{"date": "2024-01-02", "foods": [{"entry_id": 12, "food_id": 171705, "description": "Avocados, raw", "meal_type": "lunch", "quantity": 150, "calories": 240}],
+//	 "nutrients": [{"nutrient": "protein", "name": "Protein", "unit": "g", "consumed": 3, "target": 120}]}
+type DayLog struct {
+	Date      string             `json:"date"`
+	Foods     []DayFood          `json:"foods"`
+	Nutrients []NutrientProgress `json:"nutrients"`
+}
+
+// DayFood is a food of DayLog, the meal type and the time of day (HH:MM) are
+// empty when unknown
+type DayFood struct {
+	EntryID     int     `json:"entry_id"`
+	Time        string  `json:"time"`
+	FoodID      int     `json:"food_id"`
+	Description string  `json:"description"`
+	MealType    string  `json:"meal_type"`
+	Quantity    float64 `json:"quantity"`
+	Calories    float64 `json:"calories"`
+}
+
+// NutrientProgress is a nutrient of DayLog
+type NutrientProgress struct {
+	Nutrient string   `json:"nutrient"`
+	Name     string   `json:"name"`
+	Unit     string   `json:"unit"`
+	Consumed float64  `json:"consumed"`
+	Target   *float64 `json:"target"`
+}
+
+// NutrientTarget is an entry of target show and the result of target set.
+//
+//	{"nutrient": "protein", "name": "Protein", "unit": "g", "target": 120}
+type NutrientTarget struct {
+	Nutrient string   `json:"nutrient"`
+	Name     string   `json:"name"`
+	Unit     string   `json:"unit"`
+	Target   *float64 `json:"target"`
+}
+
+// dateOnly trims the time the driver appends to DATE columns
+func dateOnly(date interface{}) string {
+	s := fmt.Sprint(date)
+	if len(s) > len("2006-01-02") {
+		return s[:len("2006-01-02")]
+	}
+	return s
+}
+
+// round rounds a value to the given number of decimals for stable output
+func round(value float64, decimals int) float64 {
+	scale := math.Pow10(decimals)
+	return math.Round(value*scale) / scale
+}
+
+// optional returns a pointer to value for the nullable fields of the results
+func optional(value float64) *float64 {
+	return &value
+}
+
+// Alias is the result of alias and an entry of alias list, the definition is
+// omitted by unalias.
+//
+//	{"name": "bf", "definition": "add meal 4"}
+type Alias struct {
+	Name       string `json:"name"`
+	Definition string `json:"definition,omitempty"`
+}
+
+// Token is an API token, the result of token create and an entry of token
+// list. The scopes are comma separated, the times RFC 3339 in the time zone of
+// the user. The token itself is only given by token create, last_used_at is
+// omitted when the token was never used. The tokens given by login are named
+// login and have the admin scope.
+//
+//	{"id": 4, "name": "backup", "scopes": "history:read", "created_at": "2024-01-02T10:00:00+01:00", "expires_at": "2024-04-01T10:00:00+02:00", "token": "gtk_..."}
+type Token struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	Scopes     string `json:"scopes"`
+	CreatedAt  string `json:"created_at"`
+	ExpiresAt  string `json:"expires_at"`
+	LastUsedAt string `json:"last_used_at,omitempty"`
+	Token      string `json:"token,omitempty"`
+}
+
+// RevokedToken is the result of token revoke.
+//
+//	{"id": 4}
+type RevokedToken struct {
+	ID int `json:"id"`
+}
+
+// ActiveDate is the result of date, the day the commands use without --date.
+//
+//	{"date": "2024-01-02", "today": false}
+type ActiveDate struct {
+	Date  string `json:"date"`
+	Today bool   `json:"today"`
+}
+
+// Undone is the result of undo, the change it reverted.
+//
+//	{"change": "add meal 4"}
+type Undone struct {
+	Change string `json:"change"`
+}
+
+// SessionInfo is an entry of sessions. The kind is repl, ssh or http, the
+// times RFC 3339 in the time zone of the user.
+//
+//	{"id": "http-4", "kind": "http", "opened": "2024-01-02T10:00:00+01:00", "last_used": "2024-01-02T10:05:00+01:00", "current": false}
+type SessionInfo struct {
+	ID       string `json:"id"`
+	Kind     string `json:"kind"`
+	Opened   string `json:"opened"`
+	LastUsed string `json:"last_used"`
+	Current  bool   `json:"current"`
+}
+
+// SSHKey is a public key of the SSH server, the result of sshkey add and an
+// entry of sshkey list. The times are RFC 3339 in the time zone of the user,
+// last_used_at is omitted when the key was never used.
+//
+//	{"id": 2, "name": "laptop", "fingerprint": "SHA256:nThbg6kXUpJWGl7E1IGOCspRomTxdCARLviKw6E5SY8", "created_at": "2024-01-02T10:00:00+01:00"}
+type SSHKey struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Fingerprint string `json:"fingerprint"`
+	CreatedAt   string `json:"created_at"`
+	LastUsedAt  string `json:"last_used_at,omitempty"`
+}
+
+// RemovedSSHKey is the result of sshkey remove.
+//
+//	{"id": 2}
+type RemovedSSHKey struct {
+	ID int `json:"id"`
+}
+
+// CoachLink is an entry of client list and coach list: the other user of the
+// link, a client or a coach. The times are RFC 3339 in the time zone of the
+// user, accepted_at is omitted while the invitation is pending.
+//
+//	{"user_id": 3, "username": "jane", "firstname": "Jane", "lastname": "Doe", "invited_at": "2024-01-02T10:00:00+01:00", "accepted_at": "2024-01-02T12:00:00+01:00"}
+type CoachLink struct {
+	UserID     int    `json:"user_id"`
+	Username   string `json:"username,omitempty"`
+	Firstname  string `json:"firstname"`
+	Lastname   string `json:"lastname"`
+	InvitedAt  string `json:"invited_at"`
+	AcceptedAt string `json:"accepted_at,omitempty"`
+}
+
+// CoachInvitation is the result of client invite, client remove, coach
+// accept and coach revoke.
+//
+//	{"coach_id": 2, "client_id": 3}
+type CoachInvitation struct {
+	CoachID  int `json:"coach_id"`
+	ClientID int `json:"client_id"`
+}
+
+// Account is an entry of admin users, locked_until is RFC 3339 in the time
+// zone of the user and omitted when the account is not locked.
+//
+//	{"id": 3, "username": "jane", "email": "jane@example.com", "firstname": "Jane", "lastname": "Doe", "role": "coach"}
+type Account struct {
+	ID          int    `json:"id"`
+	Username    string `json:"username,omitempty"`
+	Email       string `json:"email,omitempty"`
+	Firstname   string `json:"firstname"`
+	Lastname    string `json:"lastname"`
+	Role        string `json:"role"`
+	LockedUntil string `json:"locked_until,omitempty"`
+}
+
+// Role is the result of admin role.
+//
+//	{"user_id": 3, "role": "coach"}
+type Role struct {
+	UserID int    `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+// Unlocked is the result of admin unlock.
+//
+//	{"user_id": 3}
+type Unlocked struct {
+	UserID int `json:"user_id"`
+}
+
+// ResetToken is the result of admin reset: the one-time token setting the
+// credentials of a user, expires_at is RFC 3339 in the time zone of the
+// session user.
+//
+//	{"user_id": 3, "token": "gtr_...", "expires_at": "2024-01-03T10:00:00+01:00"}
+type ResetToken struct {
+	UserID    int    `json:"user_id"`
+	Token     string `json:"token"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// AuditEntry is an entry of audit: a change of a row of a table. The action
+// is insert, update or delete, changed_at is RFC 3339 in the time zone of the
+// user. old_values is omitted for an insert and new_values for a delete, the
+// secrets are only marked as changed. actor_id is omitted for the changes made
+// without a logged in user, such as the logins.
+//
+//	{"id": 81, "changed_at": "2024-01-02T10:00:00+01:00", "actor_id": 1, "actor": "john", "session_id": "repl-1", "user_id": 1, "table": "food_history", "row_id": "12", "action": "update", "old_values": {"quantity": 100}, "new_values": {"quantity": 150}}
+type AuditEntry struct {
+	ID        int64                  `json:"id"`
+	ChangedAt string                 `json:"changed_at"`
+	ActorID   int                    `json:"actor_id,omitempty"`
+	Actor     string                 `json:"actor,omitempty"`
+	SessionID string                 `json:"session_id,omitempty"`
+	UserID    int                    `json:"user_id,omitempty"`
+	Table     string                 `json:"table"`
+	RowID     string                 `json:"row_id"`
+	Action    string                 `json:"action"`
+	OldValues map[string]interface{} `json:"old_values,omitempty"`
+	NewValues map[string]interface{} `json:"new_values,omitempty"`
+}
+
+// TrashItem is an entry of trash list, and the result of trash restore,
+// delete meal and delete day. id is the ID given to trash restore, the kind
+// is food, meal or day. food_id, quantity and date are given for the
+// entries of the food history, name for the meals and days.
+//
+//	{"id": "food:12", "kind": "food", "row_id": 12, "food_id": 171705, "quantity": 150, "date": "2024-01-02", "deleted_at": "2024-01-03T09:00:00+01:00"}
+type TrashItem struct {
+	ID        string  `json:"id"`
+	Kind      string  `json:"kind"`
+	RowID     int     `json:"row_id"`
+	Name      string  `json:"name,omitempty"`
+	FoodID    int     `json:"food_id,omitempty"`
+	Quantity  float64 `json:"quantity,omitempty"`
+	Date      string  `json:"date,omitempty"`
+	DeletedAt string  `json:"deleted_at,omitempty"`
+}
+
+// PurgedTrash is the result of trash purge.
+//
+//	{"purged": 3}
+type PurgedTrash struct {
+	Purged int64 `json:"purged"`
+}
+
+// ConfigSetting is an entry of config show: a setting, its value with the
+// secrets redacted and where it comes from, default, file, env or flag, from
+// the file, variable or flag.
+//
+//	{"key": "db_url", "value": "postgres://john:xxxxx@db:5432/gotracker?sslmode=require", "source": "env", "from": "GOTRACKER_DB_URL"}
+type ConfigSetting struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+	From   string `json:"from,omitempty"`
+}
@@ -0,0 +1,231 @@
+package commands
+
+import (
+	"gotracker/cli"
+	suser "gotracker/structs"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Kinds of sessions, after the interface the commands come from
+const (
+	SessionREPL = "repl" // The interactive CLI, a script or a single command
+	SessionSSH  = "ssh"  // A connection of the SSH server
+	SessionHTTP = "http" // The requests of the API made with one token
+)
+
+// maxUndo is how many changes a session remembers for undo
+const maxUndo = 20
+
+// Change is a change of the data made by a command, which Undo reverts
+type Change struct {
+	Description string
+	Undo        func(ctx *Context) error
+}
+
+// Session is the state of a connection running commands: its user, the day
+// the commands work on and the changes it can undo. ActiveDate is the day
+// the commands use without --date, today when empty.
+//
+// A session runs one command at a time: the interfaces hold Lock around each
+// command, so that concurrent requests made with one token wait for each
+// other. Only ID, Kind, Opened, UserID and LastUsed may be read without it.
+type Session struct {
+	ID         string
+	Kind       string
+	Opened     time.Time
+	User       suser.SUser
+	ActiveDate string
+
+	mu       sync.Mutex
+	userID   atomic.Int64
+	lastUsed atomic.Int64
+	changes  []Change
+}
+
+// LoggedIn reports whether a user is logged in
+func (s *Session) LoggedIn() bool {
+	return s.User.ID != 0
+}
+
+// Lock waits for the command running in the session to end and marks the session used
+func (s *Session) Lock() {
+	s.mu.Lock()
+	s.lastUsed.Store(time.Now().UnixNano())
+}
+
+// Unlock lets the next command of the session run
+func (s *Session) Unlock() {
+	s.mu.Unlock()
+}
+
+// UserID returns the ID of the user logged into the session, 0 when nobody is
+func (s *Session) UserID() int {
+	return int(s.userID.Load())
+}
+
+// LastUsed returns when the session last ran a command
+func (s *Session) LastUsed() time.Time {
+	return time.Unix(0, s.lastUsed.Load())
+}
+
+// Start logs a user into the session, forgetting the state of the previous user
+func (s *Session) Start(user suser.SUser) {
+	s.User = user
+	s.userID.Store(int64(user.ID))
+	s.ActiveDate = ""
+	s.changes = nil
+}
+
+// Record remembers a change for undo, the oldest changes are forgotten past maxUndo
+func (s *Session) Record(description string, undo func(ctx *Context) error) {
+	s.changes = append(s.changes, Change{Description: description, Undo: undo})
+	if len(s.changes) > maxUndo {
+		s.changes = s.changes[len(s.changes)-maxUndo:]
+	}
+}
+
+// popChange returns the latest change and forgets it, false when there is none
+func (s *Session) popChange() (Change, bool) {
+	if len(s.changes) == 0 {
+		return Change{}, false
+	}
+	change := s.changes[len(s.changes)-1]
+	s.changes = s.changes[:len(s.changes)-1]
+	return change, true
+}
+
+// SessionManager holds the sessions open at the same time, each with its own
+// user. It is safe for concurrent use.
+type SessionManager struct {
+	mu       sync.Mutex
+	sessions map[string]*Session
+	next     int
+}
+
+// NewSessionManager creates a manager without sessions
+func NewSessionManager() *SessionManager {
+	return &SessionManager{sessions: make(map[string]*Session)}
+}
+
+// Open opens a new session of the given kind, nobody is logged in yet
+func (m *SessionManager) Open(kind string) *Session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.next++
+	return m.add(kind, kind+"-"+strconv.Itoa(m.next))
+}
+
+// Attach returns the session of the given kind and key, such as the ID of
+// the token of a request, opening it when it is not open yet
+func (m *SessionManager) Attach(kind string, key string) *Session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := kind + "-" + key
+	if session, ok := m.sessions[id]; ok {
+		return session
+	}
+	return m.add(kind, id)
+}
+
+// add opens a session under id, m.mu is held
+func (m *SessionManager) add(kind string, id string) *Session {
+	session := &Session{ID: id, Kind: kind, Opened: time.Now()}
+	session.lastUsed.Store(session.Opened.UnixNano())
+	m.sessions[id] = session
+	return session
+}
+
+// Close closes a session, the commands running in it end normally
+func (m *SessionManager) Close(session *Session) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.sessions[session.ID] == session {
+		delete(m.sessions, session.ID)
+	}
+}
+
+// CloseIdle closes the sessions of the given kind unused for idle and
+// returns how many it closed
+func (m *SessionManager) CloseIdle(kind string, idle time.Duration) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	closed := 0
+	for id, session := range m.sessions {
+		// A session running a command is in use
+		if session.Kind != kind || !session.mu.TryLock() {
+			continue
+		}
+		if time.Since(session.LastUsed()) > idle {
+			delete(m.sessions, id)
+			closed++
+		}
+		session.mu.Unlock()
+	}
+	return closed
+}
+
+// Sessions returns the open sessions of a user, by opening time
+func (m *SessionManager) Sessions(userID int) []*Session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var sessions []*Session
+	for _, session := range m.sessions {
+		if session.UserID() == userID {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].Opened.Equal(sessions[j].Opened) {
+			return sessions[i].Opened.Before(sessions[j].Opened)
+		}
+		return sessions[i].ID < sessions[j].ID
+	})
+	return sessions
+}
+
+// undo reverts the latest change of the session
+func undo(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	change, ok := ctx.Session.popChange()
+	if !ok {
+		return nil, ctx.Errorf("nothing to undo in this session")
+	}
+	if err := change.Undo(ctx); err != nil {
+		// Keep the change so that undo can be tried again
+		ctx.Session.changes = append(ctx.Session.changes, change)
+		return nil, ctx.Errorf("error undoing %s: %w", change.Description, err)
+	}
+	return &cli.Result{Data: Undone{Change: change.Description}, Text: ctx.Sprintf("Undone: %s.\n", change.Description)}, nil
+}
+
+// listSessions lists the sessions open with the account of the user
+func listSessions(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	sessions := []*Session{ctx.Session}
+	if ctx.Sessions != nil {
+		sessions = ctx.Sessions.Sessions(ctx.Session.User.ID)
+	}
+	results := make([]SessionInfo, 0, len(sessions))
+	var text strings.Builder
+	ctx.Fprintf(&text, "Sessions:\n")
+	for _, session := range sessions {
+		result := SessionInfo{
+			ID:       session.ID,
+			Kind:     session.Kind,
+			Opened:   session.Opened.In(ctx.Location()).Format(time.RFC3339),
+			LastUsed: session.LastUsed().In(ctx.Location()).Format(time.RFC3339),
+			Current:  session == ctx.Session,
+		}
+		results = append(results, result)
+		lastUsed := session.LastUsed().In(ctx.Location())
+		ctx.Fprintf(&text, " - %s | Opened on %s, last used on %s at %s", session.ID, session.Opened.In(ctx.Location()).Format("2006-01-02"), lastUsed.Format("2006-01-02"), lastUsed.Format("15:04"))
+		if result.Current {
+			ctx.Fprintf(&text, " (this session)")
+		}
+		text.WriteString("\n")
+	}
+	return &cli.Result{Data: results, Text: text.String()}, nil
+}
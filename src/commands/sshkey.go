@@ -0,0 +1,96 @@
+package commands
+
+import (
+	"gotracker/cli"
+	suser "gotracker/structs"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// sshKeyResult returns the result of a key, in the time zone of the session user
+func sshKeyResult(ctx *Context, key suser.SSHKey) SSHKey {
+	result := SSHKey{
+		ID:          key.ID,
+		Name:        key.Name,
+		Fingerprint: key.Fingerprint,
+		CreatedAt:   key.CreatedAt.In(ctx.Location()).Format(time.RFC3339),
+	}
+	if !key.LastUsedAt.IsZero() {
+		result.LastUsedAt = key.LastUsedAt.In(ctx.Location()).Format(time.RFC3339)
+	}
+	return result
+}
+
+// addSSHKey saves a public key of the user, given as a line of
+// authorized_keys, so that the user can connect to gotracker ssh-serve
+func addSSHKey(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	name := strings.TrimSpace(inv.String("name"))
+	if name == "" || len(name) > 50 {
+		return nil, ctx.Errorf("the name of a key must have 1 to 50 characters")
+	}
+	publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(inv.String("public_key")))
+	if err != nil {
+		return nil, ctx.Errorf("invalid public key, expected a line of authorized_keys such as 'ssh-ed25519 AAAA...'")
+	}
+	key := suser.SSHKey{
+		UserID:      ctx.Session.User.ID,
+		Name:        name,
+		Fingerprint: ssh.FingerprintSHA256(publicKey),
+		PublicKey:   strings.TrimSpace(string(ssh.MarshalAuthorizedKey(publicKey))),
+		CreatedAt:   time.Now(),
+	}
+	var added bool
+	key.ID, added, err = ctx.Store.AddSSHKey(key.UserID, key.Name, key.Fingerprint, key.PublicKey)
+	if err != nil {
+		return nil, ctx.Errorf("error saving SSH key: %w", err)
+	}
+	// A key logs in a single user, it cannot be shared
+	if !added {
+		return nil, ctx.Errorf("the key %s is already registered", key.Fingerprint)
+	}
+	return &cli.Result{
+		Data: sshKeyResult(ctx, key),
+		Text: ctx.Sprintf("SSH key '%s' added with ID: %d, fingerprint %s.\n", key.Name, key.ID, key.Fingerprint),
+	}, nil
+}
+
+func listSSHKeys(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	keys, err := ctx.Store.GetSSHKeys(ctx.Session.User.ID)
+	if err != nil {
+		return nil, ctx.Errorf("error fetching SSH keys: %w", err)
+	}
+	if len(keys) == 0 {
+		return &cli.Result{Data: []SSHKey{}, Text: ctx.Sprintf("No SSH keys, add one with 'sshkey add <name> <public_key>'.\n")}, nil
+	}
+	results := make([]SSHKey, 0, len(keys))
+	var text strings.Builder
+	ctx.Fprintf(&text, "SSH keys:\n")
+	for _, key := range keys {
+		results = append(results, sshKeyResult(ctx, key))
+		ctx.Fprintf(&text, " - ID: %d | %s | %s", key.ID, key.Name, key.Fingerprint)
+		if key.LastUsedAt.IsZero() {
+			ctx.Fprintf(&text, ", never used\n")
+		} else {
+			lastUsedAt := key.LastUsedAt.In(ctx.Location())
+			ctx.Fprintf(&text, ", last used on %s at %s\n", lastUsedAt.Format("2006-01-02"), lastUsedAt.Format("15:04"))
+		}
+	}
+	return &cli.Result{Data: results, Text: text.String()}, nil
+}
+
+func removeSSHKey(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	keyID := inv.Int("key_id")
+	removed, err := ctx.Store.DeleteSSHKey(ctx.Session.User.ID, keyID)
+	if err != nil {
+		return nil, ctx.Errorf("error removing SSH key: %w", err)
+	}
+	if !removed {
+		return nil, ctx.Errorf("no SSH key with ID %d", keyID)
+	}
+	return &cli.Result{
+		Data: RemovedSSHKey{ID: keyID},
+		Text: ctx.Sprintf("SSH key %d removed.\n", keyID),
+	}, nil
+}
@@ -0,0 +1,620 @@
+package commands
+
+import (
+	"gotracker/cli"
+	suser "gotracker/structs"
+)
+
+var asianFlag = cli.Flag{Name: "asian", Type: cli.Bool, Usage: "Use the Asian cut-offs"}
+
+var clientArg = []cli.Arg{{Name: "client"}}
+
+var emailFlag = cli.Flag{Name: "email", Usage: "Email address, usable instead of the username to login"}
+
+// Table lists the commands of the application in the order shown by help
+var Table = []Spec{
+	{
+		Name:          "bodyfat",
+		Summary:       "Display the user's body fat percentage",
+		Handler:       bodyFat,
+		RequiresLogin: true,
+	},
+	{
+		Name:          "imc",
+		Summary:       "Display the user's Body Mass Index (IMC) and category",
+		Flags:         []cli.Flag{asianFlag},
+		Handler:       imc,
+		RequiresLogin: true,
+	},
+	{
+		Name:    "trend",
+		Summary: "Show the smoothed weight trend and target projection",
+		Subcommands: []Spec{
+			{
+				Name:          "weight",
+				Summary:       "Smooth the weigh-ins and project the date the target weight is reached",
+				Args:          []cli.Arg{{Name: "window_days", Type: cli.Int, Optional: true}},
+				Handler:       trendWeight,
+				RequiresLogin: true,
+			},
+		},
+	},
+	{
+		Name:          "tdee",
+		Summary:       "Estimate maintenance calories from intake and weight trend",
+		Args:          []cli.Arg{{Name: "days", Type: cli.Int, Optional: true}},
+		Flags:         []cli.Flag{{Name: "apply", Type: cli.Bool, Usage: "Update the calorie target with the estimate"}, dateFlag},
+		Handler:       tdee,
+		RequiresLogin: true,
+	},
+	{
+		Name:    "log",
+		Summary: "Log an exercise or water",
+		Subcommands: []Spec{
+			{
+				Name:    "exercise",
+				Summary: "Log an activity, intensity can be 'light', 'moderate' or 'vigorous'",
+				Args: []cli.Arg{
+					{Name: "activity"},
+					{Name: "minutes", Type: cli.Int},
+					{Name: "intensity", Optional: true},
+				},
+				Flags:         []cli.Flag{dateFlag},
+				Handler:       logExercise,
+				RequiresLogin: true,
+			},
+			{
+				Name:    "water",
+				Summary: "Log water in ml, or as a 'glass' (250 ml) or 'bottle' (500 ml)",
+				Args: []cli.Arg{
+					{Name: "amount"},
+					{Name: "count", Type: cli.Int, Optional: true},
+				},
+				Flags:         []cli.Flag{dateFlag},
+				Handler:       logWater,
+				RequiresLogin: true,
+			},
+		},
+	},
+	{
+		Name:          "today",
+		Summary:       "Show today's foods by meal type and the nutrients eaten against the targets",
+		Flags:         []cli.Flag{dateFlag},
+		Handler:       today,
+		RequiresLogin: true,
+	},
+	{
+		Name:    "target",
+		Summary: "Show or set the daily nutrient targets",
+		Subcommands: []Spec{
+			{
+				Name:          "show",
+				Summary:       "Show the daily nutrient targets",
+				Handler:       showTargets,
+				RequiresLogin: true,
+			},
+			{
+				Name:          "set",
+				Summary:       "Set the daily target of a nutrient, in kcal for calories, mg or g for the others",
+				Args:          []cli.Arg{{Name: "nutrient"}, {Name: "amount", Type: cli.Float}},
+				Handler:       setTarget,
+				RequiresLogin: true,
+			},
+		},
+	},
+	{
+		Name:          "summary",
+		Summary:       "Show today's calories, exercise, net balance against target and hydration",
+		Flags:         []cli.Flag{dateFlag},
+		Handler:       summary,
+		RequiresLogin: true,
+	},
+	{
+		Name:    "search",
+		Summary: "Search for food",
+		Subcommands: []Spec{
+			{
+				Name:    "food",
+				Summary: "Search FoodData Central by name",
+				Args:    []cli.Arg{{Name: "food_name", Rest: true}},
+				Handler: searchFood,
+			},
+		},
+	},
+	{
+		Name:    "report",
+		Summary: "Generate a report and save it to the history",
+		Subcommands: []Spec{
+			{
+				Name:          "imc",
+				Summary:       "Save the current IMC and its category",
+				Flags:         []cli.Flag{asianFlag, dateFlag},
+				Handler:       reportIMC,
+				RequiresLogin: true,
+			},
+			{
+				Name:          "bodyfat",
+				Summary:       "Save the current body fat percentage",
+				Flags:         []cli.Flag{dateFlag},
+				Handler:       reportBodyFat,
+				RequiresLogin: true,
+			},
+			{
+				Name:          "weight",
+				Summary:       "Save the current weight, or a weigh-in in kg",
+				Args:          []cli.Arg{{Name: "weight", Type: cli.Int, Optional: true}},
+				Flags:         []cli.Flag{dateFlag},
+				Handler:       reportWeight,
+				RequiresLogin: true,
+			},
+		},
+	},
+	{
+		Name:    "register",
+		Summary: "Register a new user, the password is asked for",
+		Args: []cli.Arg{
+			{Name: "username"},
+			{Name: "firstname"},
+			{Name: "lastname"},
+			{Name: "age", Type: cli.Int},
+			{Name: "weight", Type: cli.Int},
+			{Name: "height", Type: cli.Int},
+			{Name: "target_weight", Type: cli.Int},
+		},
+		Flags:   []cli.Flag{emailFlag},
+		Handler: register,
+	},
+	{
+		Name:    "login",
+		Summary: "Login with a username or email, the password is asked for",
+		Args:    []cli.Arg{{Name: "username"}},
+		Handler: login,
+	},
+	{
+		Name:          "passwd",
+		Summary:       "Change the password, and the username or email with the flags",
+		Flags:         []cli.Flag{{Name: "username", Usage: "New username"}, emailFlag},
+		Handler:       passwd,
+		RequiresLogin: true,
+	},
+	{
+		Name:    "reset",
+		Summary: "Set a new password with a reset token given by an administrator, and login",
+		Args:    []cli.Arg{{Name: "token", Optional: true}},
+		Flags:   []cli.Flag{{Name: "username", Usage: "New username, required for the accounts without one"}, emailFlag},
+		Handler: reset,
+		Secret:  true,
+	},
+	{
+		Name:          "whoami",
+		Summary:       "Show the logged in user",
+		Handler:       whoami,
+		RequiresLogin: true,
+	},
+	{
+		Name:    "add",
+		Summary: "Add food, meal or day to the food history",
+		Subcommands: []Spec{
+			{
+				Name:          "food",
+				Summary:       "Add a quantity in grams of a food",
+				Args:          []cli.Arg{{Name: "food_id", Type: cli.Int}, {Name: "quantity", Type: cli.Int}},
+				Flags:         []cli.Flag{{Name: "meal", Usage: "Meal type the food is eaten at, e.g. breakfast, lunch or dinner"}, dateFlag, timeFlag},
+				Handler:       addFood,
+				RequiresLogin: true,
+			},
+			{
+				Name:          "meal",
+				Summary:       "Add all the foods of a meal",
+				Args:          []cli.Arg{{Name: "meal_id", Type: cli.Int}},
+				Flags:         []cli.Flag{dateFlag, timeFlag},
+				Handler:       addMeal,
+				RequiresLogin: true,
+			},
+			{
+				Name:          "day",
+				Summary:       "Add all the meals of a day preset",
+				Args:          []cli.Arg{{Name: "day_id", Type: cli.Int}},
+				Flags:         []cli.Flag{dateFlag, timeFlag},
+				Handler:       addDay,
+				RequiresLogin: true,
+			},
+		},
+	},
+	{
+		Name:    "create",
+		Summary: "Create a meal or day",
+		Subcommands: []Spec{
+			{
+				Name:          "meal",
+				Summary:       "Create a meal for every user, administrators only, the type is e.g. breakfast, lunch or dinner",
+				Args:          []cli.Arg{{Name: "meal_name"}, {Name: "meal_type"}},
+				Handler:       createMeal,
+				RequiresLogin: true,
+				RequiresRole:  suser.RoleAdmin,
+			},
+			{
+				Name:          "day",
+				Summary:       "Create a day preset",
+				Args:          []cli.Arg{{Name: "day_name"}},
+				Handler:       createDay,
+				RequiresLogin: true,
+			},
+		},
+	},
+	{
+		Name:    "list",
+		Summary: "List all meals, days or activities",
+		Subcommands: []Spec{
+			{Name: "meal", Summary: "List all meals", Handler: listMeals, RequiresLogin: true},
+			{Name: "day", Summary: "List your day presets", Handler: listDays, RequiresLogin: true},
+			{Name: "activity", Summary: "List the activities of the exercise catalogue", Handler: listActivities},
+		},
+	},
+	{
+		Name:    "show",
+		Summary: "Show the foods of a meal or the meals of a day",
+		Subcommands: []Spec{
+			{Name: "meal", Summary: "Show a meal and its foods", Args: []cli.Arg{{Name: "meal_id", Type: cli.Int}}, Handler: showMeal, RequiresLogin: true},
+			{Name: "day", Summary: "Show a day preset and its meals", Args: []cli.Arg{{Name: "day_id", Type: cli.Int}}, Handler: showDay, RequiresLogin: true},
+		},
+	},
+	{
+		Name:    "link",
+		Summary: "Link food to meal or meal to day",
+		Subcommands: []Spec{
+			{
+				Name:          "food_to_meal",
+				Summary:       "Add a quantity in grams of a food to a meal, administrators only",
+				Args:          []cli.Arg{{Name: "food_id", Type: cli.Int}, {Name: "meal_id", Type: cli.Int}, {Name: "quantity", Type: cli.Int}},
+				Handler:       linkFoodToMeal,
+				RequiresLogin: true,
+				RequiresRole:  suser.RoleAdmin,
+			},
+			{
+				Name:          "meal_to_day",
+				Summary:       "Add a number of servings of a meal to a day preset",
+				Args:          []cli.Arg{{Name: "meal_id", Type: cli.Int}, {Name: "day_id", Type: cli.Int}, {Name: "quantity", Type: cli.Int}},
+				Handler:       linkMealToDay,
+				RequiresLogin: true,
+			},
+		},
+	},
+	{
+		Name:    "history",
+		Summary: "View food, weight, IMC, body fat, exercise or water history",
+		Subcommands: []Spec{
+			{Name: "food", Summary: "View the food history", Handler: historyFood, RequiresLogin: true},
+			{Name: "weight", Summary: "View the weight history", Handler: historyWeight, RequiresLogin: true},
+			{Name: "imc", Summary: "View the IMC history", Handler: historyIMC, RequiresLogin: true},
+			{Name: "bodyfat", Summary: "View the body fat history", Handler: historyBodyFat, RequiresLogin: true},
+			{Name: "exercise", Summary: "View the exercise history", Handler: historyExercise, RequiresLogin: true},
+			{Name: "water", Summary: "View the water drunk per day", Handler: historyWater, RequiresLogin: true},
+		},
+	},
+	{
+		Name:    "delete",
+		Summary: "Move an entry of the food history, a meal or a day to the trash",
+		Subcommands: []Spec{
+			{
+				Name:          "food",
+				Summary:       "Delete an entry of the food history",
+				Args:          []cli.Arg{{Name: "entry_id", Type: cli.Int}},
+				Handler:       deleteFood,
+				RequiresLogin: true,
+			},
+			{
+				Name:          "meal",
+				Summary:       "Delete a meal, for every user, administrators only",
+				Args:          []cli.Arg{{Name: "meal_id", Type: cli.Int}},
+				Handler:       deleteMeal,
+				RequiresLogin: true,
+				RequiresRole:  suser.RoleAdmin,
+			},
+			{
+				Name:          "day",
+				Summary:       "Delete one of your day presets",
+				Args:          []cli.Arg{{Name: "day_id", Type: cli.Int}},
+				Handler:       deleteDay,
+				RequiresLogin: true,
+			},
+		},
+	},
+	{
+		Name:    "trash",
+		Summary: "List, restore or purge the deleted entries, meals and days",
+		Subcommands: []Spec{
+			{
+				Name:          "list",
+				Summary:       "List the items of the trash, the latest deleted first",
+				Handler:       listTrash,
+				RequiresLogin: true,
+			},
+			{
+				Name:          "restore",
+				Summary:       "Restore an item of the trash, e.g. food:12 or 12 when no other item has this ID",
+				Args:          []cli.Arg{{Name: "id"}},
+				Handler:       restoreTrash,
+				RequiresLogin: true,
+			},
+			{
+				Name:          "purge",
+				Summary:       "Delete the items of the trash for good",
+				Handler:       purgeTrash,
+				RequiresLogin: true,
+			},
+		},
+	},
+	{
+		Name:    "update",
+		Summary: "Update user information or an entry of the food history",
+		Subcommands: []Spec{
+			{
+				Name:          "food",
+				Summary:       "Change the quantity, meal type, date or time of an entry of the food history",
+				Args:          []cli.Arg{{Name: "entry_id", Type: cli.Int}},
+				Flags:         []cli.Flag{{Name: "quantity", Type: cli.Int, Usage: "New quantity in grams"}, {Name: "meal", Usage: "New meal type"}, dateFlag, {Name: "time", Usage: "New time of day, HH:MM"}},
+				Handler:       updateFood,
+				RequiresLogin: true,
+			},
+			{Name: "firstname", Args: []cli.Arg{{Name: "firstname"}}, Handler: updateFirstname, RequiresLogin: true},
+			{Name: "lastname", Args: []cli.Arg{{Name: "lastname"}}, Handler: updateLastname, RequiresLogin: true},
+			{Name: "age", Args: []cli.Arg{{Name: "age", Type: cli.Int}}, Handler: updateAge, RequiresLogin: true},
+			{Name: "weight", Args: []cli.Arg{{Name: "weight", Type: cli.Int}}, Handler: updateWeight, RequiresLogin: true},
+			{Name: "height", Args: []cli.Arg{{Name: "height", Type: cli.Int}}, Handler: updateHeight, RequiresLogin: true},
+			{Name: "target_weight", Args: []cli.Arg{{Name: "target_weight", Type: cli.Int}}, Handler: updateTargetWeight, RequiresLogin: true},
+			{Name: "lang", Summary: "Set the language of the messages, en or fr", Args: []cli.Arg{{Name: "lang"}}, Handler: updateLang, RequiresLogin: true},
+			{Name: "timezone", Summary: "Set the time zone of the dates, e.g. Europe/Paris, or local for the host's", Args: []cli.Arg{{Name: "timezone"}}, Handler: updateTimezone, RequiresLogin: true},
+		},
+	},
+	{
+		Name:          "alias",
+		Summary:       "Define an alias with 'alias <name> = <commands>', show one or list them with 'alias list'",
+		Args:          []cli.Arg{{Name: "alias_name", Optional: true}, {Name: "definition", Optional: true, Rest: true}},
+		Handler:       alias,
+		RequiresLogin: true,
+	},
+	{
+		Name:          "unalias",
+		Summary:       "Delete an alias",
+		Args:          []cli.Arg{{Name: "alias_name"}},
+		Handler:       unalias,
+		RequiresLogin: true,
+	},
+	{
+		Name:    "token",
+		Summary: "Manage the personal access tokens of the API",
+		Subcommands: []Spec{
+			{
+				Name:    "create",
+				Summary: "Create a token for a script or an integration, shown only once",
+				Args:    []cli.Arg{{Name: "name"}},
+				Flags: []cli.Flag{
+					{Name: "scopes", Usage: "Comma separated scopes: history:read (default), log:write, meals:write or admin"},
+					{Name: "days", Type: cli.Int, Usage: "Days before the token expires, 90 by default"},
+				},
+				Handler:       createToken,
+				RequiresLogin: true,
+			},
+			{
+				Name:          "list",
+				Summary:       "List the tokens, their scopes, expiry and last use",
+				Handler:       listTokens,
+				RequiresLogin: true,
+			},
+			{
+				Name:          "revoke",
+				Summary:       "Revoke a token",
+				Args:          []cli.Arg{{Name: "token_id", Type: cli.Int}},
+				Handler:       revokeToken,
+				RequiresLogin: true,
+			},
+		},
+	},
+	{
+		Name:    "sshkey",
+		Summary: "Manage the public keys logging you in on the SSH server",
+		Subcommands: []Spec{
+			{
+				Name:          "add",
+				Summary:       "Add a public key, a line of authorized_keys such as 'ssh-ed25519 AAAA... laptop'",
+				Args:          []cli.Arg{{Name: "name"}, {Name: "public_key", Rest: true}},
+				Handler:       addSSHKey,
+				RequiresLogin: true,
+			},
+			{
+				Name:          "list",
+				Summary:       "List the public keys and their last use",
+				Handler:       listSSHKeys,
+				RequiresLogin: true,
+			},
+			{
+				Name:          "remove",
+				Summary:       "Remove a public key",
+				Args:          []cli.Arg{{Name: "key_id", Type: cli.Int}},
+				Handler:       removeSSHKey,
+				RequiresLogin: true,
+			},
+		},
+	},
+	{
+		Name:          "date",
+		Summary:       "Show or set the day the commands use without --date, 'date today' resets it",
+		Args:          []cli.Arg{{Name: "day", Optional: true}},
+		Handler:       setDate,
+		RequiresLogin: true,
+	},
+	{
+		Name:          "undo",
+		Summary:       "Undo the latest change of the food history made in this session",
+		Handler:       undo,
+		RequiresLogin: true,
+	},
+	{
+		Name:          "sessions",
+		Summary:       "List the sessions open with your account",
+		Handler:       listSessions,
+		RequiresLogin: true,
+	},
+	{
+		Name:    "client",
+		Summary: "Follow the clients who accept you as their coach, read only",
+		Subcommands: []Spec{
+			{
+				Name:          "invite",
+				Summary:       "Invite a user to be your client, who must accept with 'coach accept'",
+				Args:          clientArg,
+				Handler:       inviteClient,
+				RequiresLogin: true,
+				RequiresRole:  suser.RoleCoach,
+			},
+			{
+				Name:          "list",
+				Summary:       "List your clients and the pending invitations",
+				Handler:       listClients,
+				RequiresLogin: true,
+				RequiresRole:  suser.RoleCoach,
+			},
+			{
+				Name:          "remove",
+				Summary:       "Stop coaching a client, or cancel the invitation",
+				Args:          clientArg,
+				Handler:       removeClient,
+				RequiresLogin: true,
+				RequiresRole:  suser.RoleCoach,
+			},
+			{
+				Name:          "summary",
+				Summary:       "Show the calories, exercise and hydration of a client",
+				Args:          clientArg,
+				Flags:         []cli.Flag{dateFlag},
+				Handler:       clientView(summary),
+				RequiresLogin: true,
+				RequiresRole:  suser.RoleCoach,
+			},
+			{
+				Name:          "today",
+				Summary:       "Show the foods of a client by meal type against the targets",
+				Args:          clientArg,
+				Flags:         []cli.Flag{dateFlag},
+				Handler:       clientView(today),
+				RequiresLogin: true,
+				RequiresRole:  suser.RoleCoach,
+			},
+			{
+				Name:          "targets",
+				Summary:       "Show the daily nutrient targets of a client",
+				Args:          clientArg,
+				Handler:       clientView(showTargets),
+				RequiresLogin: true,
+				RequiresRole:  suser.RoleCoach,
+			},
+			{
+				Name:    "history",
+				Summary: "View the histories of a client",
+				Subcommands: []Spec{
+					{Name: "food", Summary: "View the food history of a client", Args: clientArg, Handler: clientView(historyFood), RequiresLogin: true, RequiresRole: suser.RoleCoach},
+					{Name: "weight", Summary: "View the weight history of a client", Args: clientArg, Handler: clientView(historyWeight), RequiresLogin: true, RequiresRole: suser.RoleCoach},
+					{Name: "imc", Summary: "View the IMC history of a client", Args: clientArg, Handler: clientView(historyIMC), RequiresLogin: true, RequiresRole: suser.RoleCoach},
+					{Name: "bodyfat", Summary: "View the body fat history of a client", Args: clientArg, Handler: clientView(historyBodyFat), RequiresLogin: true, RequiresRole: suser.RoleCoach},
+					{Name: "exercise", Summary: "View the exercise history of a client", Args: clientArg, Handler: clientView(historyExercise), RequiresLogin: true, RequiresRole: suser.RoleCoach},
+					{Name: "water", Summary: "View the water drunk per day by a client", Args: clientArg, Handler: clientView(historyWater), RequiresLogin: true, RequiresRole: suser.RoleCoach},
+				},
+			},
+		},
+	},
+	{
+		Name:    "coach",
+		Summary: "Manage the coaches who may read your data",
+		Subcommands: []Spec{
+			{
+				Name:          "list",
+				Summary:       "List your coaches and their pending invitations",
+				Handler:       listCoaches,
+				RequiresLogin: true,
+			},
+			{
+				Name:          "accept",
+				Summary:       "Let a coach who invited you read your summaries, histories and targets",
+				Args:          []cli.Arg{{Name: "coach"}},
+				Handler:       acceptCoach,
+				RequiresLogin: true,
+			},
+			{
+				Name:          "revoke",
+				Summary:       "Withdraw the access of a coach, or decline the invitation",
+				Args:          []cli.Arg{{Name: "coach"}},
+				Handler:       revokeCoach,
+				RequiresLogin: true,
+			},
+		},
+	},
+	{
+		Name:    "admin",
+		Summary: "Manage the users, for the administrators",
+		Subcommands: []Spec{
+			{
+				Name:          "users",
+				Summary:       "List the users, their role and lockout",
+				Handler:       listAccounts,
+				RequiresLogin: true,
+				RequiresRole:  suser.RoleAdmin,
+			},
+			{
+				Name:          "role",
+				Summary:       "Set the role of a user: member, coach or admin",
+				Args:          []cli.Arg{{Name: "user"}, {Name: "role"}},
+				Handler:       setRole,
+				RequiresLogin: true,
+				RequiresRole:  suser.RoleAdmin,
+			},
+			{
+				Name:          "unlock",
+				Summary:       "Unlock a user locked out after failed logins",
+				Args:          []cli.Arg{{Name: "user"}},
+				Handler:       unlockUser,
+				RequiresLogin: true,
+				RequiresRole:  suser.RoleAdmin,
+			},
+			{
+				Name:          "reset",
+				Summary:       "Give a user a one-time token to set a new password, by username, email or ID",
+				Args:          []cli.Arg{{Name: "user"}},
+				Handler:       resetPassword,
+				RequiresLogin: true,
+				RequiresRole:  suser.RoleAdmin,
+			},
+		},
+	},
+	{
+		Name:    "audit",
+		Summary: "Show the latest changes of your data and the changes you made",
+		Flags: []cli.Flag{
+			{Name: "user", Usage: "Username or email of the user whose changes to show, for the administrators"},
+			{Name: "table", Usage: "Only the changes of this table, e.g. food_history"},
+			{Name: "limit", Type: cli.Int, Usage: "Number of changes shown, 50 by default and 500 at most"},
+		},
+		Handler:       audit,
+		RequiresLogin: true,
+	},
+	{
+		Name:    "config",
+		Summary: "Show the configuration of the application",
+		Subcommands: []Spec{
+			{Name: "show", Summary: "Show the settings and where they come from, the secrets redacted", Handler: showConfig},
+		},
+	},
+	{
+		Name:    "details",
+		Summary: "Show details about a food",
+		Args:    []cli.Arg{{Name: "food_id", Type: cli.Int}},
+		Handler: details,
+	},
+	{
+		Name:    "exit",
+		Summary: "Exit the CLI",
+		Handler: func(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+			return nil, ErrExit
+		},
+	},
+}
@@ -0,0 +1,144 @@
+package commands
+
+import (
+	"gotracker/auth"
+	"gotracker/cli"
+	suser "gotracker/structs"
+	"strings"
+	"time"
+)
+
+// DefaultTokenDays is how many days the tokens of token create are valid by default
+const DefaultTokenDays = 90
+
+// parseScopes returns the scopes of a comma separated list, checked against auth.Scopes
+func parseScopes(ctx *Context, value string) ([]string, error) {
+	var scopes []string
+	for _, scope := range strings.Split(value, ",") {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if scope == "" {
+			continue
+		}
+		known := false
+		for _, candidate := range auth.Scopes {
+			known = known || candidate == scope
+		}
+		if !known {
+			return nil, ctx.Errorf("unknown scope '%s', expected %s", scope, strings.Join(auth.Scopes, ", "))
+		}
+		scopes = append(scopes, scope)
+	}
+	if len(scopes) == 0 {
+		return nil, ctx.Errorf("a token needs at least one scope among %s", strings.Join(auth.Scopes, ", "))
+	}
+	return scopes, nil
+}
+
+// tokenResult returns the result of a token, in the time zone of the session user
+func tokenResult(ctx *Context, token suser.APIToken) Token {
+	result := Token{
+		ID:        token.ID,
+		Name:      token.Name,
+		Scopes:    strings.Join(token.Scopes, ","),
+		CreatedAt: token.CreatedAt.In(ctx.Location()).Format(time.RFC3339),
+		ExpiresAt: token.ExpiresAt.In(ctx.Location()).Format(time.RFC3339),
+	}
+	if !token.LastUsedAt.IsZero() {
+		result.LastUsedAt = token.LastUsedAt.In(ctx.Location()).Format(time.RFC3339)
+	}
+	return result
+}
+
+// createToken creates a personal access token for the scripts and
+// integrations using the API. The token is only shown once, the store keeps
+// its hash.
+func createToken(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	name := strings.TrimSpace(inv.String("name"))
+	if name == "" || len(name) > 50 {
+		return nil, ctx.Errorf("the name of a token must have 1 to 50 characters")
+	}
+	scopes := []string{auth.ScopeReadHistory}
+	if inv.Has("scopes") {
+		var err error
+		if scopes, err = parseScopes(ctx, inv.String("scopes")); err != nil {
+			return nil, err
+		}
+	}
+	days := DefaultTokenDays
+	if inv.Has("days") {
+		days = inv.Int("days")
+		if days <= 0 {
+			return nil, ctx.Errorf("the validity must be a positive number of days")
+		}
+	}
+
+	secret, hash, err := auth.NewToken()
+	if err != nil {
+		return nil, ctx.Errorf("error creating token: %w", err)
+	}
+	token := suser.APIToken{
+		UserID:    ctx.Session.User.ID,
+		Name:      name,
+		Scopes:    scopes,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	token.ExpiresAt = token.CreatedAt.AddDate(0, 0, days)
+	token.ID, err = ctx.Store.CreateAPIToken(token.UserID, token.Name, hash, token.Scopes, token.ExpiresAt)
+	if err != nil {
+		return nil, ctx.Errorf("error saving token: %w", err)
+	}
+
+	result := tokenResult(ctx, token)
+	result.Token = secret
+	var text strings.Builder
+	ctx.Fprintf(&text, "Token '%s' created with ID: %d, scopes %s, valid until %s.\n", name, token.ID, result.Scopes, token.ExpiresAt.In(ctx.Location()).Format("2006-01-02"))
+	ctx.Fprintf(&text, "Copy it now, it is not shown again:\n")
+	text.WriteString(secret + "\n")
+	return &cli.Result{Data: result, Text: text.String()}, nil
+}
+
+func listTokens(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	tokens, err := ctx.Store.GetAPITokens(ctx.Session.User.ID)
+	if err != nil {
+		return nil, ctx.Errorf("error fetching tokens: %w", err)
+	}
+	if len(tokens) == 0 {
+		return &cli.Result{Data: []Token{}, Text: ctx.Sprintf("No tokens, create one with 'token create <name> --scopes <scopes>'.\n")}, nil
+	}
+	results := make([]Token, 0, len(tokens))
+	var text strings.Builder
+	ctx.Fprintf(&text, "Tokens:\n")
+	now := time.Now()
+	for _, token := range tokens {
+		results = append(results, tokenResult(ctx, token))
+		expiresAt := token.ExpiresAt.In(ctx.Location()).Format("2006-01-02")
+		ctx.Fprintf(&text, " - ID: %d | %s | Scopes: %s\n", token.ID, token.Name, strings.Join(token.Scopes, ","))
+		if token.ExpiresAt.Before(now) {
+			ctx.Fprintf(&text, "   Expired on %s", expiresAt)
+		} else {
+			ctx.Fprintf(&text, "   Expires on %s", expiresAt)
+		}
+		if token.LastUsedAt.IsZero() {
+			ctx.Fprintf(&text, ", never used\n")
+		} else {
+			lastUsedAt := token.LastUsedAt.In(ctx.Location())
+			ctx.Fprintf(&text, ", last used on %s at %s\n", lastUsedAt.Format("2006-01-02"), lastUsedAt.Format("15:04"))
+		}
+	}
+	return &cli.Result{Data: results, Text: text.String()}, nil
+}
+
+func revokeToken(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	tokenID := inv.Int("token_id")
+	revoked, err := ctx.Store.RevokeAPIToken(ctx.Session.User.ID, tokenID)
+	if err != nil {
+		return nil, ctx.Errorf("error revoking token: %w", err)
+	}
+	if !revoked {
+		return nil, ctx.Errorf("no token with ID %d", tokenID)
+	}
+	return &cli.Result{
+		Data: RevokedToken{ID: tokenID},
+		Text: ctx.Sprintf("Token %d revoked.\n", tokenID),
+	}, nil
+}
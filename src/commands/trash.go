@@ -0,0 +1,191 @@
+package commands
+
+import (
+	"gotracker/cli"
+	suser "gotracker/structs"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// trashKinds are the kinds of the items of the trash: entries of the food
+// history, meals and day presets
+var trashKinds = []string{"food", "meal", "day"}
+
+// trashID returns the ID of an item of the trash given to trash restore
+func trashID(kind string, id int) string {
+	return kind + ":" + strconv.Itoa(id)
+}
+
+// moveToTrash deletes a row of a kind of the trash, false when there is none
+func moveToTrash(store Store, userID int, kind string, id int) (bool, error) {
+	switch kind {
+	case "food":
+		return store.DeleteFoodHistory(userID, id)
+	case "meal":
+		return store.DeleteMeal(userID, id)
+	default:
+		return store.DeleteDayPreset(userID, id)
+	}
+}
+
+// recordTrashed lets the session undo moving a row to the trash
+func recordTrashed(ctx *Context, description string, kind string, id int) {
+	userID := ctx.Session.User.ID
+	ctx.Session.Record(description, func(ctx *Context) error {
+		restored, err := ctx.Store.RestoreTrash(userID, kind, id)
+		if err != nil {
+			return err
+		}
+		if !restored {
+			return ctx.Errorf("%s is no longer in the trash", trashID(kind, id))
+		}
+		return nil
+	})
+}
+
+// trashItemResult returns the result of an item of the trash, in the time zone of the session user
+func trashItemResult(ctx *Context, item suser.TrashItem) TrashItem {
+	return TrashItem{
+		ID:        trashID(item.Kind, item.ID),
+		Kind:      item.Kind,
+		RowID:     item.ID,
+		Name:      item.Name,
+		FoodID:    item.FoodID,
+		Quantity:  item.Quantity,
+		Date:      item.Date,
+		DeletedAt: item.DeletedAt.In(ctx.Location()).Format(time.RFC3339),
+	}
+}
+
+// deleteMeal moves a meal to the trash of the session user, an administrator:
+// the meals are shared and it disappears for every user
+func deleteMeal(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	mealID := inv.Int("meal_id")
+	deleted, err := ctx.Store.DeleteMeal(ctx.Session.User.ID, mealID)
+	if err != nil {
+		return nil, ctx.Errorf("error deleting meal: %w", err)
+	}
+	if !deleted {
+		return nil, ctx.Errorf("no meal with ID %d", mealID)
+	}
+	recordTrashed(ctx, ctx.Sprintf("delete meal %d", mealID), "meal", mealID)
+	return &cli.Result{
+		Data: TrashItem{ID: trashID("meal", mealID), Kind: "meal", RowID: mealID},
+		Text: ctx.Sprintf("Meal %d moved to the trash, 'trash restore %s' brings it back.\n", mealID, trashID("meal", mealID)),
+	}, nil
+}
+
+func deleteDay(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	dayID := inv.Int("day_id")
+	deleted, err := ctx.Store.DeleteDayPreset(ctx.Session.User.ID, dayID)
+	if err != nil {
+		return nil, ctx.Errorf("error deleting day: %w", err)
+	}
+	if !deleted {
+		return nil, ctx.Errorf("you have no day with ID %d", dayID)
+	}
+	recordTrashed(ctx, ctx.Sprintf("delete day %d", dayID), "day", dayID)
+	return &cli.Result{
+		Data: TrashItem{ID: trashID("day", dayID), Kind: "day", RowID: dayID},
+		Text: ctx.Sprintf("Day %d moved to the trash, 'trash restore %s' brings it back.\n", dayID, trashID("day", dayID)),
+	}, nil
+}
+
+func listTrash(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	items, err := ctx.Store.GetTrash(ctx.Session.User.ID)
+	if err != nil {
+		return nil, ctx.Errorf("error fetching trash: %w", err)
+	}
+	if len(items) == 0 {
+		return &cli.Result{Data: []TrashItem{}, Text: ctx.Sprintf("The trash is empty.\n")}, nil
+	}
+
+	results := make([]TrashItem, 0, len(items))
+	var text strings.Builder
+	ctx.Fprintf(&text, "Trash, the latest deleted first:\n")
+	for _, item := range items {
+		results = append(results, trashItemResult(ctx, item))
+		deletedAt := item.DeletedAt.In(ctx.Location()).Format("2006-01-02 15:04")
+		switch item.Kind {
+		case "food":
+			ctx.Fprintf(&text, " - %s | Food ID: %d | Quantity: %.0f g | Date: %s | deleted on %s\n", trashID(item.Kind, item.ID), item.FoodID, item.Quantity, item.Date, deletedAt)
+		case "meal":
+			ctx.Fprintf(&text, " - %s | Meal: %s | deleted on %s\n", trashID(item.Kind, item.ID), item.Name, deletedAt)
+		default:
+			ctx.Fprintf(&text, " - %s | Day: %s | deleted on %s\n", trashID(item.Kind, item.ID), item.Name, deletedAt)
+		}
+	}
+	return &cli.Result{Data: results, Text: text.String()}, nil
+}
+
+// findTrashItem returns the kind and the row ID of an item of the trash
+// given as kind:ID, or as a bare ID when no other item of the trash has it
+func findTrashItem(ctx *Context, id string) (string, int, error) {
+	if kind, rowID, ok := strings.Cut(id, ":"); ok {
+		number, err := strconv.Atoi(rowID)
+		if err != nil || !slices.Contains(trashKinds, kind) {
+			return "", 0, ctx.Errorf("invalid trash ID '%s', expected food:12, meal:4 or day:2 as shown by 'trash list'", id)
+		}
+		return kind, number, nil
+	}
+	number, err := strconv.Atoi(id)
+	if err != nil {
+		return "", 0, ctx.Errorf("invalid trash ID '%s', expected food:12, meal:4 or day:2 as shown by 'trash list'", id)
+	}
+	items, err := ctx.Store.GetTrash(ctx.Session.User.ID)
+	if err != nil {
+		return "", 0, ctx.Errorf("error fetching trash: %w", err)
+	}
+	var matches []string
+	for _, item := range items {
+		if item.ID == number {
+			matches = append(matches, trashID(item.Kind, item.ID))
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", 0, ctx.Errorf("no item %s in the trash", id)
+	case 1:
+		kind, _, _ := strings.Cut(matches[0], ":")
+		return kind, number, nil
+	}
+	return "", 0, ctx.Errorf("several items of the trash have the ID %d, give one of %s", number, strings.Join(matches, ", "))
+}
+
+// restoreTrash takes an item out of the trash, undo moves it back
+func restoreTrash(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	kind, id, err := findTrashItem(ctx, inv.String("id"))
+	if err != nil {
+		return nil, err
+	}
+	restored, err := ctx.Store.RestoreTrash(ctx.Session.User.ID, kind, id)
+	if err != nil {
+		return nil, ctx.Errorf("error restoring %s: %w", trashID(kind, id), err)
+	}
+	if !restored {
+		return nil, ctx.Errorf("no item %s in the trash", trashID(kind, id))
+	}
+	userID := ctx.Session.User.ID
+	ctx.Session.Record(ctx.Sprintf("restore %s", trashID(kind, id)), func(ctx *Context) error {
+		_, err := moveToTrash(ctx.Store, userID, kind, id)
+		return err
+	})
+	return &cli.Result{
+		Data: TrashItem{ID: trashID(kind, id), Kind: kind, RowID: id},
+		Text: ctx.Sprintf("%s restored.\n", trashID(kind, id)),
+	}, nil
+}
+
+// purgeTrash deletes for good the items in the trash of the session user
+func purgeTrash(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	purged, err := ctx.Store.PurgeTrash(ctx.Session.User.ID)
+	if err != nil {
+		return nil, ctx.Errorf("error purging trash: %w", err)
+	}
+	return &cli.Result{
+		Data: PurgedTrash{Purged: purged},
+		Text: ctx.Sprintf("Items deleted for good: %d.\n", purged),
+	}, nil
+}
@@ -0,0 +1,153 @@
+package commands
+
+import (
+	"gotracker/cli"
+	"gotracker/trend"
+	"math"
+	"strings"
+	"time"
+)
+
+// weightPoints returns the weigh-ins of the session user as trend points
+func weightPoints(ctx *Context) ([]trend.Point, error) {
+	dates, weights, err := ctx.Store.GetWeightSeries(ctx.Session.User.ID)
+	if err != nil {
+		return nil, ctx.Errorf("error fetching weight history: %w", err)
+	}
+	points := make([]trend.Point, len(dates))
+	for i := range dates {
+		points[i] = trend.Point{Date: dates[i], Weight: weights[i]}
+	}
+	return points, nil
+}
+
+func trendWeight(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	user := &ctx.Session.User
+	window := trend.DefaultWindow
+	if inv.Has("window_days") {
+		window = inv.Int("window_days")
+	}
+	if window <= 0 {
+		return nil, ctx.Errorf("the window must be a positive number of days")
+	}
+	// Get weigh-ins for the user
+	points, err := weightPoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(points) == 0 {
+		return &cli.Result{Text: ctx.Translate("No weight history found. Use 'report weight' to record a weigh-in.\n")}, nil
+	}
+	weightTrend, err := trend.AnalyzeWeight(points, window, float64(user.TargetWeight))
+	if err != nil {
+		return nil, ctx.Errorf("error computing weight trend: %w", err)
+	}
+	result := WeightTrend{
+		Latest:       round(weightTrend.Latest, 1),
+		Trend:        round(weightTrend.Trend, 1),
+		WeeklyRate:   round(weightTrend.WeeklyRate, 2),
+		WindowDays:   weightTrend.Window,
+		Samples:      weightTrend.Samples,
+		TargetWeight: user.TargetWeight,
+	}
+	var text strings.Builder
+	ctx.Fprintf(&text, "Weight Trend:\n")
+	ctx.Fprintf(&text, " - Latest weigh-in: %.1f kg\n", weightTrend.Latest)
+	ctx.Fprintf(&text, " - Trend weight: %.1f kg\n", weightTrend.Trend)
+	ctx.Fprintf(&text, " - Rate: %+.2f kg/week (last %d days, %d weigh-ins)\n", weightTrend.WeeklyRate, weightTrend.Window, weightTrend.Samples)
+	if weightTrend.TargetDate.IsZero() {
+		ctx.Fprintf(&text, " - Target weight of %d kg is not reached at the current rate\n", user.TargetWeight)
+	} else {
+		result.TargetDate = weightTrend.TargetDate.Format("2006-01-02")
+		ctx.Fprintf(&text, " - Target weight of %d kg projected on %s\n", user.TargetWeight, result.TargetDate)
+	}
+	return &cli.Result{Data: result, Text: text.String()}, nil
+}
+
+// dailyIntake returns the kcal eaten by the session user for each day between from and to
+func dailyIntake(ctx *Context, from string, to string) (map[string]float64, error) {
+	foodsByDay, err := ctx.Store.GetFoodQuantitiesByDay(ctx.Session.User.ID, from, to)
+	if err != nil {
+		return nil, ctx.Errorf("error fetching food history: %w", err)
+	}
+	intake := make(map[string]float64)
+	for date, foods := range foodsByDay {
+		for foodID, quantity := range foods {
+			kcal, err := ctx.FDC.GetFoodEnergy(ctx.RequestContext(), foodID)
+			if ctx.RequestContext().Err() != nil {
+				return nil, ctx.RequestContext().Err()
+			}
+			if err != nil {
+				ctx.Fprintf(ctx.Err, "Skipping food ID %d: %v\n", foodID, err)
+				continue
+			}
+			intake[date] += kcal * quantity / 100
+		}
+	}
+	return intake, nil
+}
+
+func tdee(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	days := trend.DefaultEnergyDays
+	if inv.Has("days") {
+		days = inv.Int("days")
+	}
+	if days < trend.MinEnergyDays || days > trend.MaxEnergyDays {
+		return nil, ctx.Errorf("the period must be between %d and %d days", trend.MinEnergyDays, trend.MaxEnergyDays)
+	}
+
+	// The period ends on the day of the user, --date or the active date of the session
+	day, err := ctx.Date(inv)
+	if err != nil {
+		return nil, err
+	}
+	end, err := time.Parse("2006-01-02", day)
+	if err != nil {
+		return nil, err
+	}
+	start := end.AddDate(0, 0, -days+1)
+
+	// Sum the calories eaten each day of the period
+	intake, err := dailyIntake(ctx, start.Format("2006-01-02"), end.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	points, err := weightPoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	estimate, err := trend.EstimateMaintenance(intake, points, end, days)
+	if err != nil {
+		return nil, ctx.Errorf("cannot estimate maintenance calories: %w", err)
+	}
+	balance := EnergyBalance{
+		AverageIntake: math.Round(estimate.AverageIntake),
+		WeeklyChange:  round(estimate.WeeklyChange, 2),
+		Maintenance:   math.Round(estimate.Maintenance),
+		Confidence:    estimate.Confidence,
+		LoggedDays:    estimate.LoggedDays,
+		Days:          estimate.Days,
+	}
+	var text strings.Builder
+	ctx.Fprintf(&text, "Energy Balance:\n")
+	ctx.Fprintf(&text, " - Average intake: %.0f kcal/day\n", estimate.AverageIntake)
+	ctx.Fprintf(&text, " - Weight trend: %+.2f kg/week\n", estimate.WeeklyChange)
+	ctx.Fprintf(&text, " - Estimated maintenance: %.0f kcal/day\n", estimate.Maintenance)
+	ctx.Fprintf(&text, " - Confidence: %s (%d of %d days logged)\n", ctx.Translate(estimate.Confidence), estimate.LoggedDays, estimate.Days)
+	result := &cli.Result{Data: &balance, Text: text.String()}
+
+	if inv.Bool("apply") {
+		if estimate.Confidence == trend.ConfidenceLow {
+			return result, ctx.Errorf("confidence is too low to update the calorie target, log more days first")
+		}
+		err = ctx.Store.SetTargetCalories(ctx.Session.User.ID, ctx.Today(), balance.Maintenance)
+		if err != nil {
+			return result, ctx.Errorf("error updating calorie target: %w", err)
+		}
+		balance.AppliedTarget = optional(balance.Maintenance)
+		ctx.Fprintf(&text, "Calorie target updated to %.0f kcal.\n", balance.Maintenance)
+		result.Text = text.String()
+	}
+	return result, nil
+}
@@ -0,0 +1,268 @@
+package commands
+
+import (
+	"gotracker/cli"
+	"gotracker/i18n"
+	suser "gotracker/structs"
+	"strings"
+	"time"
+)
+
+func whoami(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	user := &ctx.Session.User
+	var text strings.Builder
+	ctx.Fprintf(&text, "Logged in as %s %s with ID: %d\n", user.Firstname, user.Lastname, user.ID)
+	if user.Username != "" {
+		ctx.Fprintf(&text, " - Username: %s\n", user.Username)
+	}
+	if user.Email != "" {
+		ctx.Fprintf(&text, " - Email: %s\n", user.Email)
+	}
+	ctx.Fprintf(&text, " - Age: %d | Weight: %d kg | Height: %d cm | Target weight: %d kg\n", user.Age, user.Weight, user.Height, user.TargetWeight)
+	if user.Role != "" && user.Role != suser.RoleMember {
+		ctx.Fprintf(&text, " - Role: %s\n", user.Role)
+	}
+	return &cli.Result{Data: userResult(user), Text: text.String()}, nil
+}
+
+func bodyFat(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	bodyFat := ctx.Session.User.GetBodyFat()
+	return &cli.Result{
+		Data: BodyFat{BodyFat: round(bodyFat, 2)},
+		Text: ctx.Sprintf("Body Fat: %.2f%%\n", bodyFat),
+	}, nil
+}
+
+func imc(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	if err := requireHeight(ctx); err != nil {
+		return nil, err
+	}
+	result := imcResult(ctx, inv.Bool("asian"))
+	var text strings.Builder
+	ctx.Fprintf(&text, "IMC: %.2f\n", ctx.Session.User.GetIMC())
+	writeIMCContext(&text, ctx, inv.Bool("asian"))
+	return &cli.Result{Data: result, Text: text.String()}, nil
+}
+
+// requireHeight refuses to compute the IMC of a user whose height is not set,
+// which would give an IMC of 0 and an empty healthy weight range
+func requireHeight(ctx *Context) error {
+	if ctx.Session.User.Height <= 0 {
+		return ctx.Errorf("set your height first with 'update height <cm>'")
+	}
+	return nil
+}
+
+// imcResult returns the IMC of the user with its category and healthy weight range
+func imcResult(ctx *Context, asian bool) IMC {
+	user := &ctx.Session.User
+	if !user.IsAdult() {
+		return IMC{IMC: round(user.GetIMC(), 2), Category: suser.IMCCategoryMinor}
+	}
+	low, high := user.GetHealthyWeightRange(asian)
+	return IMC{
+		IMC:               round(user.GetIMC(), 2),
+		Category:          user.GetIMCCategory(asian),
+		HealthyWeightMin:  optional(round(low, 1)),
+		HealthyWeightMax:  optional(round(high, 1)),
+		DistanceToHealthy: optional(round(user.GetDistanceToHealthyRange(asian), 1)),
+	}
+}
+
+// writeIMCContext writes the IMC category and the healthy weight range of the user
+func writeIMCContext(w *strings.Builder, ctx *Context, asian bool) {
+	user := &ctx.Session.User
+	if !user.IsAdult() {
+		ctx.Fprintf(w, "Category: %s\n", ctx.Translate(suser.IMCCategoryMinor))
+		ctx.Fprintf(w, "Note: adult IMC categories do not apply under 18, use age and sex specific growth charts instead.\n")
+		return
+	}
+	ctx.Fprintf(w, "Category: %s\n", ctx.Translate(user.GetIMCCategory(asian)))
+
+	low, high := user.GetHealthyWeightRange(asian)
+	ctx.Fprintf(w, "Healthy weight range for %d cm: %.1f - %.1f kg\n", user.Height, low, high)
+	distance := user.GetDistanceToHealthyRange(asian)
+	switch {
+	case distance > 0:
+		ctx.Fprintf(w, "You are %.1f kg above the healthy range.\n", distance)
+	case distance < 0:
+		ctx.Fprintf(w, "You are %.1f kg below the healthy range.\n", -distance)
+	default:
+		ctx.Fprintf(w, "You are within the healthy range.\n")
+	}
+}
+
+func reportIMC(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	user := &ctx.Session.User
+	if err := requireHeight(ctx); err != nil {
+		return nil, err
+	}
+	date, err := ctx.Date(inv)
+	if err != nil {
+		return nil, err
+	}
+	// Generate IMC report
+	imc := user.GetIMC()
+	result := imcResult(ctx, inv.Bool("asian"))
+	result.Date = date
+	// Save IMC history to the database
+	err = ctx.Store.CreateIMCHistory(user.ID, result.Date, imc, user.GetIMCCategory(inv.Bool("asian")))
+	if err != nil {
+		return nil, ctx.Errorf("error saving IMC history: %w", err)
+	}
+	var text strings.Builder
+	ctx.Fprintf(&text, "IMC Report: %.2f\n", imc)
+	writeIMCContext(&text, ctx, inv.Bool("asian"))
+	ctx.Fprintf(&text, "IMC history saved successfully.\n")
+	return &cli.Result{Data: result, Text: text.String()}, nil
+}
+
+func reportBodyFat(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	date, err := ctx.Date(inv)
+	if err != nil {
+		return nil, err
+	}
+	// Generate body fat report
+	bodyFat := ctx.Session.User.GetBodyFat()
+	// Save body fat history to the database
+	err = ctx.Store.CreateBodyFatHistory(ctx.Session.User.ID, date, bodyFat)
+	if err != nil {
+		return nil, ctx.Errorf("error saving body fat history: %w", err)
+	}
+	return &cli.Result{
+		Data: BodyFat{Date: date, BodyFat: round(bodyFat, 2)},
+		Text: ctx.Sprintf("Body Fat Report: %.2f%%\nBody fat history saved successfully.\n", bodyFat),
+	}, nil
+}
+
+func reportWeight(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	date, err := ctx.Date(inv)
+	if err != nil {
+		return nil, err
+	}
+	// Generate weight report, a weigh-in given for today becomes the weight of the user
+	weight := ctx.Session.User.Weight
+	if inv.Has("weight") {
+		weight = inv.Int("weight")
+		if weight <= 0 {
+			return nil, ctx.Errorf("the weight must be a positive number of kg")
+		}
+		if date == ctx.Today() {
+			if err := ctx.Store.UpdateUserWeight(ctx.Session.User.ID, weight); err != nil {
+				return nil, ctx.Errorf("error updating weight: %w", err)
+			}
+			ctx.Session.User.Weight = weight
+		}
+	}
+	// Save weight history to the database
+	err = ctx.Store.CreateWeightHistory(ctx.Session.User.ID, date, weight)
+	if err != nil {
+		return nil, ctx.Errorf("error saving weight history: %w", err)
+	}
+	return &cli.Result{
+		Data: Weight{Date: date, Weight: float64(weight)},
+		Text: ctx.Sprintf("Weight Report: %d kg\nWeight history saved successfully.\n", weight),
+	}, nil
+}
+
+// updated returns the result of an update command
+func updated(ctx *Context, format string, a ...interface{}) (*cli.Result, error) {
+	return &cli.Result{Data: userResult(&ctx.Session.User), Text: ctx.Sprintf(format, a...)}, nil
+}
+
+func updateFirstname(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	newFirstname := inv.String("firstname")
+	// Update the user's firstname in the database
+	err := ctx.Store.UpdateUserFirstname(ctx.Session.User.ID, newFirstname)
+	if err != nil {
+		return nil, ctx.Errorf("error updating firstname: %w", err)
+	}
+	ctx.Session.User.Firstname = newFirstname
+	return updated(ctx, "Firstname updated successfully to '%s'.\n", newFirstname)
+}
+
+func updateLastname(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	newLastname := inv.String("lastname")
+	// Update the user's lastname in the database
+	err := ctx.Store.UpdateUserLastname(ctx.Session.User.ID, newLastname)
+	if err != nil {
+		return nil, ctx.Errorf("error updating lastname: %w", err)
+	}
+	ctx.Session.User.Lastname = newLastname
+	return updated(ctx, "Lastname updated successfully to '%s'.\n", newLastname)
+}
+
+func updateAge(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	newAge := inv.Int("age")
+	// Update the user's age in the database
+	err := ctx.Store.UpdateUserAge(ctx.Session.User.ID, newAge)
+	if err != nil {
+		return nil, ctx.Errorf("error updating age: %w", err)
+	}
+	ctx.Session.User.SetAge(newAge)
+	return updated(ctx, "Age updated successfully to %d years.\n", newAge)
+}
+
+func updateWeight(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	newWeight := inv.Int("weight")
+	// Update the user's weight in the database
+	err := ctx.Store.UpdateUserWeight(ctx.Session.User.ID, newWeight)
+	if err != nil {
+		return nil, ctx.Errorf("error updating weight: %w", err)
+	}
+	ctx.Session.User.SetWeight(newWeight)
+	return updated(ctx, "Weight updated successfully to %d kg.\n", newWeight)
+}
+
+func updateHeight(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	newHeight := inv.Int("height")
+	// Update the user's height in the database
+	err := ctx.Store.UpdateUserHeight(ctx.Session.User.ID, newHeight)
+	if err != nil {
+		return nil, ctx.Errorf("error updating height: %w", err)
+	}
+	ctx.Session.User.SetHeight(newHeight)
+	return updated(ctx, "Height updated successfully to %d cm.\n", newHeight)
+}
+
+func updateTargetWeight(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	newTargetWeight := inv.Int("target_weight")
+	// Update the user's target weight in the database
+	err := ctx.Store.UpdateUserTargetWeight(ctx.Session.User.ID, newTargetWeight)
+	if err != nil {
+		return nil, ctx.Errorf("error updating target weight: %w", err)
+	}
+	ctx.Session.User.SetTargetWeight(newTargetWeight)
+	return updated(ctx, "Target weight updated successfully to %d kg.\n", newTargetWeight)
+}
+
+func updateLang(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	lang, err := i18n.Parse(inv.String("lang"))
+	if err != nil {
+		return nil, ctx.Errorf("unsupported language '%s', expected en or fr", inv.String("lang"))
+	}
+	// Update the user's language in the database
+	err = ctx.Store.UpdateUserLang(ctx.Session.User.ID, string(lang))
+	if err != nil {
+		return nil, ctx.Errorf("error updating language: %w", err)
+	}
+	ctx.Session.User.Lang = string(lang)
+	return updated(ctx, "Language updated successfully to '%s'.\n", lang)
+}
+
+func updateTimezone(ctx *Context, inv *cli.Invocation) (*cli.Result, error) {
+	// 'local' goes back to the time zone of the host
+	timezone := inv.String("timezone")
+	if timezone == "local" {
+		timezone = ""
+	} else if _, err := time.LoadLocation(timezone); err != nil || timezone == "" || timezone == "Local" {
+		return nil, ctx.Errorf("unknown time zone '%s', expected e.g. Europe/Paris, UTC or local", timezone)
+	}
+	// Update the user's time zone in the database
+	err := ctx.Store.UpdateUserTimezone(ctx.Session.User.ID, timezone)
+	if err != nil {
+		return nil, ctx.Errorf("error updating time zone: %w", err)
+	}
+	ctx.Session.User.Timezone = timezone
+	return updated(ctx, "Time zone updated successfully to '%s'.\n", ctx.Location())
+}
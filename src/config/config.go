@@ -0,0 +1,397 @@
+// Package config loads the configuration of the application. Each setting
+// comes from, in increasing precedence, its default, the config file, its
+// GOTRACKER_* environment variable and the command line flags.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gotracker/fdcnal"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/lib/pq"
+)
+
+// LegacyPath is the config file read when the user config directory has
+// none, relative to the working directory as before the layered configuration
+const LegacyPath = "vars/config.json"
+
+// SSLModes are the values of db_sslmode supported by the PostgreSQL driver
+var SSLModes = []string{"disable", "require", "verify-ca", "verify-full"}
+
+// Config is the configuration of the application. DBURL is a PostgreSQL URL
+// or key=value connection string, which replaces the other DB settings when
+// set. AuditRetentionDays is how long the audit log keeps the changes and
+// TrashRetentionDays how long the trash keeps the deleted rows, forever when
+// 0. Path is the config file read, empty when none was found.
+type Config struct {
+	DBURL              string
+	DBUser             string
+	DBPassword         string
+	DBHost             string
+	DBPort             string
+	DBName             string
+	DBSSLMode          string
+	FDCKey             string
+	AuditRetentionDays int
+	TrashRetentionDays int
+
+	Path    string
+	sources map[string]Source
+}
+
+// Source tells where the value of a setting comes from: the kind is
+// default, file, env or flag, the name is the file, variable or flag.
+type Source struct {
+	Kind string
+	Name string
+}
+
+// Setting is a setting of the configuration with its value as a string
+type Setting struct {
+	Key    string
+	Value  string
+	Secret bool
+	Source Source
+}
+
+// setting binds a key of the config file to a field of Config, text or number
+type setting struct {
+	key    string
+	text   *string
+	number *int
+	secret bool
+}
+
+// settings returns the settings of c in the order they are shown
+func (c *Config) settings() []setting {
+	return []setting{
+		{key: "db_url", text: &c.DBURL, secret: true},
+		{key: "db_host", text: &c.DBHost},
+		{key: "db_port", text: &c.DBPort},
+		{key: "db_user", text: &c.DBUser},
+		{key: "db_password", text: &c.DBPassword, secret: true},
+		{key: "db_name", text: &c.DBName},
+		{key: "db_sslmode", text: &c.DBSSLMode},
+		{key: "fdc_key", text: &c.FDCKey, secret: true},
+		{key: "audit_retention_days", number: &c.AuditRetentionDays},
+		{key: "trash_retention_days", number: &c.TrashRetentionDays},
+	}
+}
+
+// EnvName returns the environment variable of a setting, e.g. GOTRACKER_DB_URL for db_url
+func EnvName(key string) string {
+	return "GOTRACKER_" + strings.ToUpper(key)
+}
+
+// Default returns the configuration used when nothing else is set: a local
+// database without SSL and the rate limited demo key of FoodData Central
+func Default() *Config {
+	c := &Config{
+		DBUser:             "postgres",
+		DBHost:             "localhost",
+		DBPort:             "5432",
+		DBName:             "gotracker",
+		DBSSLMode:          "disable",
+		FDCKey:             fdcnal.DemoKey,
+		AuditRetentionDays: 365,
+		TrashRetentionDays: 30,
+		sources:            make(map[string]Source),
+	}
+	for _, s := range c.settings() {
+		c.sources[s.key] = Source{Kind: "default"}
+	}
+	return c
+}
+
+// DefaultPath returns the config file under the user config directory,
+// $XDG_CONFIG_HOME/gotracker/config.json or ~/.config/gotracker/config.json
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the config directory: %w", err)
+	}
+	return filepath.Join(dir, "gotracker", "config.json"), nil
+}
+
+// Load builds the configuration from the defaults, the config file, the
+// environment and flags, the values of the flags given by key of the setting.
+// path is the config file, which must exist; when empty it is
+// GOTRACKER_CONFIG, else DefaultPath, else LegacyPath, skipped when missing.
+// The configuration is not validated, see Validate.
+func Load(path string, flags map[string]string) (*Config, error) {
+	c := Default()
+
+	required := true
+	if path == "" {
+		path = os.Getenv("GOTRACKER_CONFIG")
+	}
+	if path == "" {
+		required = false
+		if path, _ = DefaultPath(); path == "" || !exists(path) {
+			path = LegacyPath
+		}
+	}
+	if required || exists(path) {
+		if err := c.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range c.settings() {
+		name := EnvName(s.key)
+		if value, ok := os.LookupEnv(name); ok {
+			if err := c.set(s, value, Source{Kind: "env", Name: name}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, s := range c.settings() {
+		if value := flags[s.key]; value != "" {
+			if err := c.set(s, value, Source{Kind: "flag", Name: "-" + strings.ReplaceAll(s.key, "_", "-")}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return c, nil
+}
+
+// exists reports whether there is a file at path
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// loadFile reads the settings of the JSON config file at path, an unknown
+// key is an error so that a misspelled setting is not silently ignored
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("failed to decode config file %s: %w", path, err)
+	}
+	settings := c.settings()
+	for key, raw := range values {
+		i := indexOf(settings, key)
+		if i < 0 {
+			return fmt.Errorf("unknown setting '%s' in %s", key, path)
+		}
+		s := settings[i]
+		var err error
+		if s.text != nil {
+			err = json.Unmarshal(raw, s.text)
+		} else {
+			err = json.Unmarshal(raw, s.number)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid %s in %s: %w", key, path, err)
+		}
+		c.sources[key] = Source{Kind: "file", Name: path}
+	}
+	c.Path = path
+	return nil
+}
+
+// indexOf returns the index of the setting of key, -1 when there is none
+func indexOf(settings []setting, key string) int {
+	for i, s := range settings {
+		if s.key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// set sets a setting from the text of a variable or a flag
+func (c *Config) set(s setting, value string, source Source) error {
+	if s.text != nil {
+		*s.text = value
+	} else {
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s '%s' (%s %s), expected a number", s.key, value, source.Kind, source.Name)
+		}
+		*s.number = number
+	}
+	c.sources[s.key] = source
+	return nil
+}
+
+// DSN returns the connection string of the database: DBURL when set, else
+// the key=value string of the other DB settings
+func (c *Config) DSN() string {
+	if c.DBURL != "" {
+		return c.DBURL
+	}
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		quote(c.DBHost), quote(c.DBPort), quote(c.DBUser), quote(c.DBPassword), quote(c.DBName), quote(c.DBSSLMode))
+}
+
+// quote quotes a value of a key=value connection string
+func quote(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	return "'" + strings.ReplaceAll(value, "'", `\'`) + "'"
+}
+
+// Validate checks the configuration and returns all its problems at once,
+// each naming the setting and where its value comes from
+func (c *Config) Validate() error {
+	var problems []error
+	invalid := func(key string, format string, a ...interface{}) {
+		problems = append(problems, fmt.Errorf("invalid %s%s: %s", key, c.origin(key), fmt.Sprintf(format, a...)))
+	}
+
+	if c.DBURL != "" {
+		if _, err := pq.NewConnector(c.DBURL); err != nil {
+			invalid("db_url", "%v", err)
+		} else if mode := sslMode(c.DBURL); mode != "" && !slices.Contains(SSLModes, mode) {
+			invalid("db_url", "unsupported sslmode '%s', expected %s", mode, strings.Join(SSLModes, ", "))
+		}
+	} else {
+		for _, key := range []string{"db_host", "db_user", "db_name"} {
+			if c.Value(key) == "" {
+				invalid(key, "it must not be empty")
+			}
+		}
+		if port, err := strconv.Atoi(c.DBPort); err != nil || port < 1 || port > 65535 {
+			invalid("db_port", "'%s' is not a port number", c.DBPort)
+		}
+		if !slices.Contains(SSLModes, c.DBSSLMode) {
+			invalid("db_sslmode", "'%s', expected %s", c.DBSSLMode, strings.Join(SSLModes, ", "))
+		}
+	}
+	if c.FDCKey == "" {
+		invalid("fdc_key", "it must not be empty, sign up for a key at https://fdc.nal.usda.gov/api-key-signup")
+	}
+	if c.AuditRetentionDays < 0 {
+		invalid("audit_retention_days", "%d, expected 0 to keep the changes forever or a number of days", c.AuditRetentionDays)
+	}
+	if c.TrashRetentionDays < 0 {
+		invalid("trash_retention_days", "%d, expected 0 to keep the trash forever or a number of days", c.TrashRetentionDays)
+	}
+	return errors.Join(problems...)
+}
+
+// origin describes where the value of a setting comes from in the errors,
+// empty for a default
+func (c *Config) origin(key string) string {
+	source := c.sources[key]
+	if source.Kind == "default" {
+		return ""
+	}
+	return fmt.Sprintf(" (%s %s)", source.Kind, source.Name)
+}
+
+// sslMode returns the sslmode of a PostgreSQL URL or key=value connection
+// string, empty when it has none
+func sslMode(dsn string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		if u, err := url.Parse(dsn); err == nil {
+			return u.Query().Get("sslmode")
+		}
+		return ""
+	}
+	for _, field := range strings.Fields(dsn) {
+		if value, ok := strings.CutPrefix(field, "sslmode="); ok {
+			return strings.Trim(value, "'")
+		}
+	}
+	return ""
+}
+
+// Value returns the value of a setting as a string, empty for an unknown key
+func (c *Config) Value(key string) string {
+	for _, s := range c.settings() {
+		if s.key == key {
+			if s.text != nil {
+				return *s.text
+			}
+			return strconv.Itoa(*s.number)
+		}
+	}
+	return ""
+}
+
+// Settings returns the settings with their value and source, the secrets
+// redacted: the passwords and keys are masked, the password of db_url removed
+func (c *Config) Settings() []Setting {
+	var settings []Setting
+	for _, s := range c.settings() {
+		setting := Setting{Key: s.key, Value: c.Value(s.key), Secret: s.secret, Source: c.sources[s.key]}
+		if s.secret && setting.Value != "" {
+			if s.key == "db_url" {
+				setting.Value = redactDSN(setting.Value)
+			} else {
+				setting.Value = "********"
+			}
+		}
+		settings = append(settings, setting)
+	}
+	return settings
+}
+
+// secretDSNKeys are the connection parameters masked by redactDSN
+var secretDSNKeys = []string{"password", "sslpassword"}
+
+// redactDSN masks the passwords of a PostgreSQL URL, in its user info or its
+// query, or of a key=value connection string
+func redactDSN(dsn string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "********"
+		}
+		params := strings.Split(u.RawQuery, "&")
+		for i, param := range params {
+			key, _, _ := strings.Cut(param, "=")
+			if name, err := url.QueryUnescape(key); err != nil || slices.Contains(secretDSNKeys, name) {
+				params[i] = key + "=********"
+			}
+		}
+		u.RawQuery = strings.Join(params, "&")
+		return u.Redacted()
+	}
+	var fields []string
+	for rest := strings.TrimSpace(dsn); rest != ""; rest = strings.TrimLeftFunc(rest, unicode.IsSpace) {
+		key, value, ok := strings.Cut(rest, "=")
+		if !ok {
+			fields = append(fields, rest)
+			break
+		}
+		key = strings.TrimSpace(key)
+		value, rest = dsnValue(strings.TrimLeftFunc(value, unicode.IsSpace))
+		if slices.Contains(secretDSNKeys, key) {
+			value = "********"
+		}
+		fields = append(fields, key+"="+value)
+	}
+	return strings.Join(fields, " ")
+}
+
+// dsnValue splits the value at the start of a key=value connection string
+// from the rest: up to the next space, or between single quotes, a backslash
+// escaping the next character
+func dsnValue(s string) (string, string) {
+	quoted := strings.HasPrefix(s, "'")
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case quoted && i > 0 && s[i] == '\'':
+			return s[:i+1], s[i+1:]
+		case !quoted && unicode.IsSpace(rune(s[i])):
+			return s[:i], s[i:]
+		}
+	}
+	return s, ""
+}
@@ -0,0 +1,70 @@
+package exercise
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Intensity levels of an activity
+const (
+	IntensityLight    = "light"
+	IntensityModerate = "moderate"
+	IntensityVigorous = "vigorous"
+)
+
+// DefaultIntensity is used when no intensity is given
+const DefaultIntensity = IntensityModerate
+
+// Activity is an entry of the catalogue with its MET value per intensity
+type Activity struct {
+	Name string
+	MET  map[string]float64
+}
+
+// Catalogue of known activities, MET values from the Compendium of Physical Activities
+var Catalogue = map[string]Activity{
+	"walking":    {Name: "walking", MET: map[string]float64{IntensityLight: 2.5, IntensityModerate: 3.5, IntensityVigorous: 5.0}},
+	"running":    {Name: "running", MET: map[string]float64{IntensityLight: 7.0, IntensityModerate: 9.8, IntensityVigorous: 11.8}},
+	"cycling":    {Name: "cycling", MET: map[string]float64{IntensityLight: 4.0, IntensityModerate: 6.8, IntensityVigorous: 10.0}},
+	"lifting":    {Name: "lifting", MET: map[string]float64{IntensityLight: 3.5, IntensityModerate: 5.0, IntensityVigorous: 6.0}},
+	"swimming":   {Name: "swimming", MET: map[string]float64{IntensityLight: 6.0, IntensityModerate: 8.3, IntensityVigorous: 9.8}},
+	"hiking":     {Name: "hiking", MET: map[string]float64{IntensityLight: 5.3, IntensityModerate: 6.0, IntensityVigorous: 7.8}},
+	"rowing":     {Name: "rowing", MET: map[string]float64{IntensityLight: 4.8, IntensityModerate: 7.0, IntensityVigorous: 8.5}},
+	"yoga":       {Name: "yoga", MET: map[string]float64{IntensityLight: 2.5, IntensityModerate: 3.0, IntensityVigorous: 4.0}},
+	"dancing":    {Name: "dancing", MET: map[string]float64{IntensityLight: 3.0, IntensityModerate: 5.0, IntensityVigorous: 7.3}},
+	"elliptical": {Name: "elliptical", MET: map[string]float64{IntensityLight: 4.6, IntensityModerate: 5.0, IntensityVigorous: 6.8}},
+}
+
+// Lookup returns the activity with the given name
+func Lookup(name string) (Activity, error) {
+	activity, ok := Catalogue[name]
+	if !ok {
+		return Activity{}, fmt.Errorf("unknown activity '%s'", name)
+	}
+	return activity, nil
+}
+
+// Names returns the names of the activities of the catalogue in alphabetical order
+func Names() []string {
+	names := make([]string, 0, len(Catalogue))
+	for name := range Catalogue {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// METFor returns the MET value of the activity for an intensity
+func (a Activity) METFor(intensity string) (float64, error) {
+	met, ok := a.MET[intensity]
+	if !ok {
+		return 0, fmt.Errorf("unknown intensity '%s', can be '%s', '%s' or '%s'", intensity, IntensityLight, IntensityModerate, IntensityVigorous)
+	}
+	return met, nil
+}
+
+// CaloriesBurned returns the kcal spent doing an activity of the given MET
+// for minutes minutes, using kcal = MET × weight (kg) × duration (h)
+func CaloriesBurned(met float64, weight float64, minutes int) float64 {
+	return met * weight * float64(minutes) / 60
+}
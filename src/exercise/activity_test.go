@@ -0,0 +1,62 @@
+package exercise
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestCaloriesBurned(t *testing.T) {
+	tests := []struct {
+		activity  string
+		intensity string
+		weight    float64
+		minutes   int
+		want      float64
+	}{
+		{"walking", IntensityModerate, 80, 60, 280},
+		{"running", IntensityVigorous, 70, 30, 413},
+		{"yoga", IntensityLight, 60, 45, 112.5},
+		{"cycling", IntensityLight, 90, 0, 0},
+	}
+	for _, test := range tests {
+		activity, err := Lookup(test.activity)
+		if err != nil {
+			t.Fatal(err)
+		}
+		met, err := activity.METFor(test.intensity)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := CaloriesBurned(met, test.weight, test.minutes); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("%d minutes of %s %s at %v kg = %v kcal, want %v", test.minutes, test.intensity, test.activity, test.weight, got, test.want)
+		}
+	}
+}
+
+func TestLookupErrors(t *testing.T) {
+	if _, err := Lookup("juggling"); err == nil {
+		t.Error("Lookup of an unknown activity succeeded")
+	}
+	activity, err := Lookup("rowing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := activity.METFor("extreme"); err == nil {
+		t.Error("METFor of an unknown intensity succeeded")
+	}
+}
+
+func TestCatalogue(t *testing.T) {
+	names := Names()
+	if len(names) != len(Catalogue) || !slices.IsSorted(names) {
+		t.Errorf("Names() = %v, want the %d activities sorted", names, len(Catalogue))
+	}
+	for _, name := range names {
+		met := Catalogue[name].MET
+		// Each activity is harder at a higher intensity
+		if !(met[IntensityLight] < met[IntensityModerate] && met[IntensityModerate] < met[IntensityVigorous]) {
+			t.Errorf("MET of %s do not increase with the intensity: %v", name, met)
+		}
+	}
+}
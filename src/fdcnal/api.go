@@ -1,14 +1,21 @@
 package fdcnal
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"net/http"
+	"net/url"
+	"sync"
 )
 
-var apiEndpoint = "https://api.nal.usda.gov/fdc/v1/"
-var apiToken = "fEU6wcmaqSdJrMXeSPXnn19Lph66mtLsUCSEP67j"
+// Endpoint is the URL of the FoodData Central API
+const Endpoint = "https://api.nal.usda.gov/fdc/v1/"
+
+// DemoKey is the key of api.data.gov for trying the API, limited to a few
+// requests per hour, the configuration gives the key of the application
+const DemoKey = "DEMO_KEY"
 
 // Struct to match the expected structure of the API response
 type FoodSearchResponse struct {
@@ -26,64 +33,148 @@ type FoodDetailsResponse struct {
 	Nutrients   []struct {
 		Id       int `json:"id"`
 		Nutrient struct {
-			Name string `json:"name"`
+			Number   string `json:"number"`
+			Name     string `json:"name"`
+			UnitName string `json:"unitName"`
 		}
 		Amount float64 `json:"amount"`
 	} `json:"foodNutrients"`
 }
 
-func GetFoodByName(foodName string) ([]string, error) {
-	// Build the URL
-	url := fmt.Sprintf("%sfoods/search?query=%s&api_key=%s", apiEndpoint, foodName, apiToken)
+// FDC nutrient numbers used by the tracker
+const (
+	NutrientEnergyKcal        = "208"
+	NutrientEnergyKJ          = "268"
+	NutrientEnergyAtwaterGen  = "957"
+	NutrientEnergyAtwaterSpec = "958"
+	NutrientWater             = "255"
+	NutrientProtein           = "203"
+	NutrientFat               = "204"
+	NutrientCarbohydrates     = "205"
+	NutrientFiber             = "291"
+	NutrientSugars            = "269"
+	NutrientCalcium           = "301"
+	NutrientIron              = "303"
+	NutrientPotassium         = "306"
+	NutrientSodium            = "307"
+	NutrientCholesterol       = "601"
+	NutrientTransFat          = "605"
+	NutrientSaturatedFat      = "606"
+)
+
+// Client calls the FoodData Central API
+type Client struct {
+	Endpoint string
+	Token    string
+	HTTP     *http.Client
+
+	// Food details are cached by FDC ID since they never change
+	mu    sync.Mutex
+	foods map[int]*FoodDetailsResponse
+}
+
+// NewClient creates a client for the API at endpoint, which must end with a slash
+func NewClient(endpoint string, token string) *Client {
+	return &Client{
+		Endpoint: endpoint,
+		Token:    token,
+		HTTP:     http.DefaultClient,
+		foods:    make(map[int]*FoodDetailsResponse),
+	}
+}
+
+// DefaultClient is the client used by the package level functions, with the demo key
+var DefaultClient = NewClient(Endpoint, DemoKey)
+
+// get requests path with the query and decodes the JSON response into
+// result, the request is aborted when ctx is cancelled. The key is sent in
+// the X-Api-Key header, out of the URL quoted by the errors of net/http.
+func (c *Client) get(ctx context.Context, path string, query url.Values, result interface{}) error {
+	target := c.Endpoint + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
 
 	// Make the request
-	resp, err := http.Get(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
 	if err != nil {
-		return nil, fmt.Errorf("error making HTTP request: %w", err)
+		return fmt.Errorf("error creating HTTP request: %w", err)
+	}
+	req.Header.Set("X-Api-Key", c.Token)
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return fmt.Errorf("error making HTTP request: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status from FoodData Central: %s", resp.Status)
+	}
+
 	// Read the body
-	body, err := ioutil.ReadAll(resp.Body)
+	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %w", err)
+		return fmt.Errorf("error reading response body: %w", err)
 	}
 
 	// Parse JSON
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("error unmarshalling JSON: %w", err)
+	}
+	return nil
+}
+
+// SearchFood searches foods by name and returns the decoded response
+func (c *Client) SearchFood(ctx context.Context, foodName string) (*FoodSearchResponse, error) {
 	var result FoodSearchResponse
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("error unmarshalling JSON: %w", err)
+	if err := c.get(ctx, "foods/search", url.Values{"query": {foodName}}, &result); err != nil {
+		return nil, err
 	}
+	return &result, nil
+}
 
-	// Extract and return food descriptions
-	var foods []string
-	for _, food := range result.Foods {
-		foods = append(foods, food.Description+" | ID : "+fmt.Sprint(food.FdcId))
+// GetFood returns the decoded details of a food
+func (c *Client) GetFood(ctx context.Context, fdcId int) (*FoodDetailsResponse, error) {
+	c.mu.Lock()
+	cached, ok := c.foods[fdcId]
+	c.mu.Unlock()
+	if ok {
+		return cached, nil
 	}
 
-	return foods, nil
-}
+	var foodDetails FoodDetailsResponse
+	if err := c.get(ctx, fmt.Sprintf("food/%d", fdcId), nil, &foodDetails); err != nil {
+		return nil, fmt.Errorf("food %d: %w", fdcId, err)
+	}
 
-func GetFoodDetails(fdcId string) ([]string, error) {
-	// Build the URL
-	url := fmt.Sprintf("%sfood/%s?api_key=%s", apiEndpoint, fdcId, apiToken)
+	c.mu.Lock()
+	c.foods[fdcId] = &foodDetails
+	c.mu.Unlock()
 
-	// Make the request
-	resp, err := http.Get(url)
+	return &foodDetails, nil
+}
+
+// GetFoodByName searches foods by name
+func (c *Client) GetFoodByName(ctx context.Context, foodName string) ([]string, error) {
+	result, err := c.SearchFood(ctx, foodName)
 	if err != nil {
-		return nil, fmt.Errorf("error making HTTP request: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	// Read the body
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %w", err)
+	// Extract and return food descriptions
+	var foods []string
+	for _, food := range result.Foods {
+		foods = append(foods, food.Description+" | ID : "+fmt.Sprint(food.FdcId))
 	}
 
+	return foods, nil
+}
+
+// GetFoodDetails returns the description and nutrients of a food
+func (c *Client) GetFoodDetails(ctx context.Context, fdcId string) ([]string, error) {
 	var foodDetails FoodDetailsResponse
-	if err := json.Unmarshal(body, &foodDetails); err != nil {
-		return nil, fmt.Errorf("error unmarshalling JSON: %w", err)
+	if err := c.get(ctx, "food/"+url.PathEscape(fdcId), nil, &foodDetails); err != nil {
+		return nil, err
 	}
 
 	// Extract and return food details
@@ -99,27 +190,11 @@ func GetFoodDetails(fdcId string) ([]string, error) {
 	return details, nil
 }
 
-func GetFoodByNameWithFilter(foodName string, dataType string) ([]string, error) {
-	// Build the URL with the dataType filter
-	url := fmt.Sprintf("%sfoods/search?query=%s&dataType=%s&api_key=%s", apiEndpoint, foodName, dataType, apiToken)
-
-	// Make the request
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("error making HTTP request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Read the body
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %w", err)
-	}
-
-	// Parse JSON
+// GetFoodByNameWithFilter searches foods by name within a data type
+func (c *Client) GetFoodByNameWithFilter(ctx context.Context, foodName string, dataType string) ([]string, error) {
 	var result FoodSearchResponse
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("error unmarshalling JSON: %w", err)
+	if err := c.get(ctx, "foods/search", url.Values{"query": {foodName}, "dataType": {dataType}}, &result); err != nil {
+		return nil, err
 	}
 
 	// Extract and return food descriptions
@@ -131,33 +206,20 @@ func GetFoodByNameWithFilter(foodName string, dataType string) ([]string, error)
 	return foods, nil
 }
 
-func GetFoodByBrandOrCategory(foodName, brandOwner, foodCategory string) ([]string, error) {
-	// Build the URL with optional filters
-	url := fmt.Sprintf("%sfoods/search?query=%s&api_key=%s", apiEndpoint, foodName, apiToken)
+// GetFoodByBrandOrCategory searches foods by name with optional brand and category filters
+func (c *Client) GetFoodByBrandOrCategory(ctx context.Context, foodName, brandOwner, foodCategory string) ([]string, error) {
+	// Build the query with optional filters
+	query := url.Values{"query": {foodName}}
 	if brandOwner != "" {
-		url += fmt.Sprintf("&brandOwner=%s", brandOwner)
+		query.Set("brandOwner", brandOwner)
 	}
 	if foodCategory != "" {
-		url += fmt.Sprintf("&foodCategory=%s", foodCategory)
-	}
-
-	// Make the HTTP request
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("error making HTTP request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Read the response body
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %w", err)
+		query.Set("foodCategory", foodCategory)
 	}
 
-	// Parse the JSON
 	var result FoodSearchResponse
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("error unmarshalling JSON: %w", err)
+	if err := c.get(ctx, "foods/search", query, &result); err != nil {
+		return nil, err
 	}
 
 	// Extract and return food descriptions
@@ -169,27 +231,11 @@ func GetFoodByBrandOrCategory(foodName, brandOwner, foodCategory string) ([]stri
 	return foods, nil
 }
 
-func GetFoodDetailsPreciseQuantity(fdcId string, quantityInGrams float64) ([]string, error) {
-	// Build the URL
-	url := fmt.Sprintf("%sfood/%s?api_key=%s", apiEndpoint, fdcId, apiToken)
-
-	// Make the request
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("error making HTTP request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Read the body
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %w", err)
-	}
-
-	// Parse the JSON response
+// GetFoodDetailsPreciseQuantity returns the nutrients of a food scaled to a quantity
+func (c *Client) GetFoodDetailsPreciseQuantity(ctx context.Context, fdcId string, quantityInGrams float64) ([]string, error) {
 	var foodDetails FoodDetailsResponse
-	if err := json.Unmarshal(body, &foodDetails); err != nil {
-		return nil, fmt.Errorf("error unmarshalling JSON: %w", err)
+	if err := c.get(ctx, "food/"+url.PathEscape(fdcId), nil, &foodDetails); err != nil {
+		return nil, err
 	}
 
 	// Extract and scale food details
@@ -207,3 +253,78 @@ func GetFoodDetailsPreciseQuantity(fdcId string, quantityInGrams float64) ([]str
 
 	return details, nil
 }
+
+// GetFoodNutrients returns the nutrient amounts per 100g of a food keyed by FDC nutrient number
+func (c *Client) GetFoodNutrients(ctx context.Context, fdcId int) (map[string]float64, error) {
+	foodDetails, err := c.GetFood(ctx, fdcId)
+	if err != nil {
+		return nil, err
+	}
+
+	nutrients := make(map[string]float64)
+	for _, nutrient := range foodDetails.Nutrients {
+		nutrients[nutrient.Nutrient.Number] = nutrient.Amount
+	}
+	return nutrients, nil
+}
+
+// GetFoodEnergy returns the energy of a food in kcal per 100g
+func (c *Client) GetFoodEnergy(ctx context.Context, fdcId int) (float64, error) {
+	nutrients, err := c.GetFoodNutrients(ctx, fdcId)
+	if err != nil {
+		return 0, err
+	}
+
+	// Foundation foods only report Atwater energy, branded foods only kJ sometimes
+	for _, number := range []string{NutrientEnergyKcal, NutrientEnergyAtwaterSpec, NutrientEnergyAtwaterGen} {
+		if kcal, ok := nutrients[number]; ok {
+			return kcal, nil
+		}
+	}
+	if kj, ok := nutrients[NutrientEnergyKJ]; ok {
+		return kj / 4.184, nil
+	}
+
+	return 0, fmt.Errorf("no energy value for food %d", fdcId)
+}
+
+// GetFoodWater returns the water content of a food in g (about ml) per 100g
+func (c *Client) GetFoodWater(ctx context.Context, fdcId int) (float64, error) {
+	nutrients, err := c.GetFoodNutrients(ctx, fdcId)
+	if err != nil {
+		return 0, err
+	}
+	return nutrients[NutrientWater], nil
+}
+
+func GetFoodByName(foodName string) ([]string, error) {
+	return DefaultClient.GetFoodByName(context.Background(), foodName)
+}
+
+func GetFoodDetails(fdcId string) ([]string, error) {
+	return DefaultClient.GetFoodDetails(context.Background(), fdcId)
+}
+
+func GetFoodByNameWithFilter(foodName string, dataType string) ([]string, error) {
+	return DefaultClient.GetFoodByNameWithFilter(context.Background(), foodName, dataType)
+}
+
+func GetFoodByBrandOrCategory(foodName, brandOwner, foodCategory string) ([]string, error) {
+	return DefaultClient.GetFoodByBrandOrCategory(context.Background(), foodName, brandOwner, foodCategory)
+}
+
+func GetFoodDetailsPreciseQuantity(fdcId string, quantityInGrams float64) ([]string, error) {
+	return DefaultClient.GetFoodDetailsPreciseQuantity(context.Background(), fdcId, quantityInGrams)
+}
+
+func GetFoodNutrients(fdcId int) (map[string]float64, error) {
+	return DefaultClient.GetFoodNutrients(context.Background(), fdcId)
+}
+
+func GetFoodEnergy(fdcId int) (float64, error) {
+	return DefaultClient.GetFoodEnergy(context.Background(), fdcId)
+}
+
+func GetFoodWater(fdcId int) (float64, error) {
+	return DefaultClient.GetFoodWater(context.Background(), fdcId)
+}
@@ -2,4 +2,10 @@ module gotracker
 
 go 1.24.2
 
-require github.com/lib/pq v1.10.9 // indirect
+require (
+	github.com/lib/pq v1.10.9
+	golang.org/x/crypto v0.45.0
+	golang.org/x/term v0.37.0
+)
+
+require golang.org/x/sys v0.38.0 // indirect
@@ -0,0 +1,182 @@
+// Package i18n translates the messages of the application and formats
+// numbers and dates for the language of the user.
+//
+// Messages are looked up in the catalogue of the language by their English
+// text, so an untranslated message is shown in English. The leading bullet
+// and the trailing line break of a format are not part of its key: " - Net:
+// %.0f kcal\n" is translated with the key "Net: %.0f kcal".
+package i18n
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Lang is a language of the messages
+type Lang string
+
+const (
+	English Lang = "en"
+	French  Lang = "fr"
+)
+
+// Default is the language used when none is configured
+const Default = English
+
+// Langs returns the supported languages
+func Langs() []Lang {
+	return []Lang{English, French}
+}
+
+// Parse reads a language code such as "fr", "fr-FR" or a locale such as
+// "fr_FR.UTF-8". The C and POSIX locales are English.
+func Parse(value string) (Lang, error) {
+	code := strings.ToLower(value)
+	if i := strings.IndexAny(code, "_-.@"); i >= 0 {
+		code = code[:i]
+	}
+	switch code {
+	case "en", "c", "posix":
+		return English, nil
+	case "fr":
+		return French, nil
+	}
+	return "", fmt.Errorf("unsupported language '%s', expected en or fr", value)
+}
+
+// FromEnv returns the language of the LC_ALL, LC_MESSAGES or LANG environment
+// variables, in that order, or Default when none is set to a supported language
+func FromEnv() Lang {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		if lang, err := Parse(value); err == nil {
+			return lang
+		}
+		return Default
+	}
+	return Default
+}
+
+// catalogues maps the English messages to their translation, per language
+var catalogues = map[Lang]map[string]string{
+	French: french,
+}
+
+// Translate returns the translation of a message, or the message itself when it is not translated
+func (l Lang) Translate(message string) string {
+	catalogue := catalogues[l]
+	if catalogue == nil {
+		return message
+	}
+	// Keep the indentation, bullet and line breaks around the key
+	key := strings.TrimLeft(message, " ")
+	if strings.HasPrefix(key, "- ") {
+		key = key[2:]
+	}
+	prefix := message[:len(message)-len(key)]
+	trimmed := strings.TrimRight(key, "\n")
+	suffix := key[len(trimmed):]
+	if translation, ok := catalogue[trimmed]; ok {
+		return prefix + translation + suffix
+	}
+	return message
+}
+
+// Sprintf translates format and formats the numbers and dates of the arguments for the language
+func (l Lang) Sprintf(format string, a ...interface{}) string {
+	return fmt.Sprintf(l.Translate(format), l.args(a)...)
+}
+
+// Fprintf writes a translated message to w
+func (l Lang) Fprintf(w io.Writer, format string, a ...interface{}) (int, error) {
+	return fmt.Fprintf(w, l.Translate(format), l.args(a)...)
+}
+
+// Errorf returns an error with a translated message, %w wraps errors as with fmt.Errorf
+func (l Lang) Errorf(format string, a ...interface{}) error {
+	return fmt.Errorf(l.Translate(format), l.args(a)...)
+}
+
+// isoDate matches the dates formatted for the store
+var isoDate = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+
+// args localizes the arguments of a message: numbers use the decimal
+// separator of the language and YYYY-MM-DD dates its date format
+func (l Lang) args(a []interface{}) []interface{} {
+	if l != French {
+		return a
+	}
+	localized := make([]interface{}, len(a))
+	for i, arg := range a {
+		switch value := arg.(type) {
+		case float64:
+			localized[i] = number{value}
+		case float32:
+			localized[i] = number{float64(value)}
+		case string:
+			if isoDate.MatchString(value) {
+				localized[i] = l.Date(value)
+			} else {
+				localized[i] = value
+			}
+		case time.Time:
+			localized[i] = value.Format("02/01/2006")
+		default:
+			localized[i] = arg
+		}
+	}
+	return localized
+}
+
+// Date formats a YYYY-MM-DD date for the language, DD/MM/YYYY in French
+func (l Lang) Date(date string) string {
+	if l != French {
+		return date
+	}
+	parsed, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	return parsed.Format("02/01/2006")
+}
+
+// Number formats a number with the given decimals and the decimal separator of the language
+func (l Lang) Number(value float64, decimals int) string {
+	formatted := strconv.FormatFloat(value, 'f', decimals, 64)
+	if l == French {
+		formatted = strings.Replace(formatted, ".", ",", 1)
+	}
+	return formatted
+}
+
+// number is a float formatted with a decimal comma
+type number struct {
+	value float64
+}
+
+// Format formats the number with the verb, width and precision of the format
+// and replaces the decimal point by a comma
+func (n number) Format(f fmt.State, verb rune) {
+	spec := "%"
+	for _, flag := range "+-# 0" {
+		if f.Flag(int(flag)) {
+			spec += string(flag)
+		}
+	}
+	if width, ok := f.Width(); ok {
+		spec += strconv.Itoa(width)
+	}
+	if precision, ok := f.Precision(); ok {
+		spec += "." + strconv.Itoa(precision)
+	}
+	spec += string(verb)
+	io.WriteString(f, strings.Replace(fmt.Sprintf(spec, n.value), ".", ",", 1))
+}
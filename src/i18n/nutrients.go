@@ -0,0 +1,64 @@
+package i18n
+
+// nutrientNames translates the canonical FoodData Central nutrient names, by nutrient number
+var nutrientNames = map[Lang]map[string]string{
+	French: {
+		"203": "Protéines",
+		"204": "Lipides totaux",
+		"205": "Glucides (par différence)",
+		"207": "Cendres",
+		"208": "Énergie",
+		"209": "Amidon",
+		"210": "Saccharose",
+		"211": "Glucose",
+		"212": "Fructose",
+		"213": "Lactose",
+		"221": "Alcool éthylique",
+		"255": "Eau",
+		"262": "Caféine",
+		"268": "Énergie",
+		"269": "Sucres totaux",
+		"291": "Fibres alimentaires",
+		"301": "Calcium, Ca",
+		"303": "Fer, Fe",
+		"304": "Magnésium, Mg",
+		"305": "Phosphore, P",
+		"306": "Potassium, K",
+		"307": "Sodium, Na",
+		"309": "Zinc, Zn",
+		"312": "Cuivre, Cu",
+		"315": "Manganèse, Mn",
+		"317": "Sélénium, Se",
+		"318": "Vitamine A, UI",
+		"320": "Vitamine A, ER",
+		"321": "Bêta-carotène",
+		"323": "Vitamine E (alpha-tocophérol)",
+		"324": "Vitamine D (D2 + D3), UI",
+		"328": "Vitamine D (D2 + D3)",
+		"401": "Vitamine C",
+		"404": "Thiamine (B1)",
+		"405": "Riboflavine (B2)",
+		"406": "Niacine (B3)",
+		"410": "Acide pantothénique (B5)",
+		"415": "Vitamine B6",
+		"417": "Folates totaux",
+		"418": "Vitamine B12",
+		"421": "Choline totale",
+		"430": "Vitamine K (phylloquinone)",
+		"601": "Cholestérol",
+		"605": "Acides gras trans",
+		"606": "Acides gras saturés",
+		"645": "Acides gras mono-insaturés",
+		"646": "Acides gras polyinsaturés",
+	},
+}
+
+// Nutrient returns the name of a FoodData Central nutrient in the language,
+// name is the English name returned by the API and is kept when the nutrient
+// is not in the table
+func (l Lang) Nutrient(number string, name string) string {
+	if translated, ok := nutrientNames[l][number]; ok {
+		return translated
+	}
+	return name
+}
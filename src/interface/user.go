@@ -13,6 +13,7 @@ type IUser interface {
 	GetHeight() int
 	GetBodyFat() float64
 	GetIMC() float64
+	GetIMCCategory(asian bool) string
 	GetTargetWeight() int
 
 	Create(id int, firstname string, lastname string, age int, weight int, height int) IUser
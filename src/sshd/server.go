@@ -0,0 +1,295 @@
+// Package sshd serves the interactive CLI over SSH. The public key of a
+// connection logs in the user who added it with sshkey add, and each
+// connection runs the commands of the table in its own session, so that the
+// users need neither a shell account nor the password of the database.
+package sshd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"gotracker/cli"
+	"gotracker/commands"
+	"gotracker/fdcnal"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// handshakeTimeout bounds the key exchange and authentication of a connection
+const handshakeTimeout = 30 * time.Second
+
+// Exit statuses of the commands, the same as the CLI
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+// Server serves the CLI over the data layer and the FoodData Central client.
+// Log receives the internal errors, which are not shown to the clients. The
+// connections open their sessions in Sessions.
+type Server struct {
+	Store    commands.Store
+	FDC      *fdcnal.Client
+	Log      io.Writer
+	Sessions *commands.SessionManager
+
+	config *ssh.ServerConfig
+}
+
+// NewServer creates a server over store and fdc identified by hostKey, logging to log
+func NewServer(store commands.Store, fdc *fdcnal.Client, hostKey ssh.Signer, log io.Writer) *Server {
+	s := &Server{Store: store, FDC: fdc, Log: log, Sessions: commands.NewSessionManager()}
+	s.config = &ssh.ServerConfig{PublicKeyCallback: s.authenticate}
+	s.config.AddHostKey(hostKey)
+	return s
+}
+
+// authenticate accepts the public keys added with sshkey add. It is called
+// before the client proves that it holds the key, the use of the key is
+// recorded once the handshake succeeds.
+func (s *Server) authenticate(conn ssh.ConnMetadata, publicKey ssh.PublicKey) (*ssh.Permissions, error) {
+	fingerprint := ssh.FingerprintSHA256(publicKey)
+	key, found, err := s.Store.FindSSHKey(fingerprint)
+	if err != nil {
+		fmt.Fprintf(s.Log, "SSH: %v\n", err)
+		return nil, errors.New("failed to check the key")
+	}
+	if !found {
+		return nil, fmt.Errorf("unknown public key %s", fingerprint)
+	}
+	return &ssh.Permissions{Extensions: map[string]string{
+		"key-id":  strconv.Itoa(key.ID),
+		"user-id": strconv.Itoa(key.UserID),
+	}}, nil
+}
+
+// ListenAndServe serves the CLI on addr until ctx is cancelled, which closes
+// the open connections
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() { listener.Close() })
+	defer stop()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.serveConn(ctx, conn)
+		}()
+	}
+}
+
+// serveConn authenticates a connection and serves its session channels
+func (s *Server) serveConn(ctx context.Context, netConn net.Conn) {
+	stop := context.AfterFunc(ctx, func() { netConn.Close() })
+	defer stop()
+
+	netConn.SetDeadline(time.Now().Add(handshakeTimeout))
+	conn, channels, requests, err := ssh.NewServerConn(netConn, s.config)
+	if err != nil {
+		// Failed handshakes are usual on a public port, they are not logged
+		netConn.Close()
+		return
+	}
+	defer conn.Close()
+	netConn.SetDeadline(time.Time{})
+	go ssh.DiscardRequests(requests)
+
+	userID, _ := strconv.Atoi(conn.Permissions.Extensions["user-id"])
+	keyID, _ := strconv.Atoi(conn.Permissions.Extensions["key-id"])
+	if err := s.Store.UseSSHKey(keyID); err != nil {
+		fmt.Fprintf(s.Log, "SSH: %v\n", err)
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "only session channels are supported")
+			continue
+		}
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.serveChannel(ctx, userID, channel, channelRequests)
+		}()
+	}
+}
+
+// serveChannel runs the REPL of a shell request or the command of an exec
+// request, as in 'ssh host today', and sends its exit status
+func (s *Server) serveChannel(ctx context.Context, userID int, channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer channel.Close()
+	terminal := false
+	for request := range requests {
+		var status uint32
+		switch request.Type {
+		case "pty-req":
+			terminal = true
+			request.Reply(true, nil)
+			continue
+		case "env", "window-change":
+			request.Reply(true, nil)
+			continue
+		case "shell":
+			request.Reply(true, nil)
+			go ssh.DiscardRequests(requests)
+			status = s.shell(ctx, userID, channel, terminal)
+		case "exec":
+			var payload struct{ Command string }
+			if err := ssh.Unmarshal(request.Payload, &payload); err != nil {
+				request.Reply(false, nil)
+				continue
+			}
+			request.Reply(true, nil)
+			go ssh.DiscardRequests(requests)
+			status = s.exec(ctx, userID, channel, terminal, payload.Command)
+		default:
+			request.Reply(false, nil)
+			continue
+		}
+		channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+		return
+	}
+}
+
+// context opens the session of a channel with the user of its key and
+// returns its context and registry. The session must be closed after use.
+func (s *Server) context(ctx context.Context, userID int, channel ssh.Channel, terminal bool) (*commands.Context, *cli.Registry, error) {
+	user, err := s.Store.GetUser(userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get key user: %w", err)
+	}
+	cmdCtx := &commands.Context{
+		Ctx:      ctx,
+		Store:    s.Store,
+		FDC:      s.FDC,
+		Session:  s.Sessions.Open(commands.SessionSSH),
+		Sessions: s.Sessions,
+		Out:      channel,
+		Err:      channel.Stderr(),
+	}
+	// A terminal in raw mode needs \r\n, and shows a single stream
+	if terminal {
+		cmdCtx.Out = crlfWriter{channel}
+		cmdCtx.Err = cmdCtx.Out
+	}
+	cmdCtx.Session.Start(*user)
+	return cmdCtx, commands.NewRegistry(cmdCtx), nil
+}
+
+// shell runs the REPL until exit or the end of the input. A terminal gets
+// the line editor with completion and an in-memory history.
+func (s *Server) shell(ctx context.Context, userID int, channel ssh.Channel, terminal bool) uint32 {
+	cmdCtx, registry, err := s.context(ctx, userID, channel, terminal)
+	if err != nil {
+		fmt.Fprintf(s.Log, "SSH: %v\n", err)
+		return exitFailure
+	}
+	defer s.Sessions.Close(cmdCtx.Session)
+
+	var reader cli.LineReader
+	if terminal {
+		editor := &cli.Editor{In: channel, Out: channel, Prompt: cli.Prompt}
+		editor.Complete = commands.Completer(registry, cmdCtx)
+		editor.History, _ = cli.LoadHistory("")
+		editor.History.Skip = registry.Secret
+		reader = editor
+	} else {
+		reader = cli.NewPlainReader(channel, cmdCtx.Out, "")
+	}
+	cmdCtx.ReadPassword = reader.(cli.PasswordReader).ReadPassword
+
+	user := cmdCtx.Session.User
+	cmdCtx.Fprintf(cmdCtx.Out, "Welcome %s %s, type 'help' for the commands and 'exit' to leave.\n", user.Firstname, user.Lastname)
+	for {
+		line, err := reader.ReadLine()
+		if errors.Is(err, cli.ErrInterrupted) {
+			continue
+		}
+		if err != nil {
+			// End of the input or closed connection
+			return exitOK
+		}
+		err = execute(cmdCtx, func() error { return registry.Execute(line) })
+		if errors.Is(err, commands.ErrExit) {
+			return exitOK
+		}
+		report(cmdCtx, err)
+	}
+}
+
+// exec runs a command line and returns its exit status
+func (s *Server) exec(ctx context.Context, userID int, channel ssh.Channel, terminal bool, line string) uint32 {
+	cmdCtx, registry, err := s.context(ctx, userID, channel, terminal)
+	if err != nil {
+		fmt.Fprintf(s.Log, "SSH: %v\n", err)
+		return exitFailure
+	}
+	defer s.Sessions.Close(cmdCtx.Session)
+	return report(cmdCtx, execute(cmdCtx, func() error { return registry.Execute(line) }))
+}
+
+// execute runs a command in the session of ctx
+func execute(ctx *commands.Context, run func() error) error {
+	ctx.Session.Lock()
+	defer ctx.Session.Unlock()
+	return run()
+}
+
+// report prints the error of a command like the CLI and returns the exit status
+func report(ctx *commands.Context, err error) uint32 {
+	if err == nil || errors.Is(err, cli.ErrEmptyCommand) || errors.Is(err, commands.ErrExit) {
+		return exitOK
+	}
+	ctx.Fprintf(ctx.Err, "Error: %s\n", ctx.Translate(err.Error()))
+	var usageErr *cli.UsageError
+	var unknownErr *cli.UnknownCommandError
+	switch {
+	case errors.As(err, &usageErr):
+		if usage := usageErr.Usage(); usage != "" {
+			fmt.Fprintln(ctx.Err, usage)
+		}
+		return exitUsage
+	case errors.As(err, &unknownErr):
+		return exitUsage
+	default:
+		return exitFailure
+	}
+}
+
+// crlfWriter ends the lines with \r\n, a terminal in raw mode does not go
+// back to the start of the line on \n
+type crlfWriter struct {
+	w io.Writer
+}
+
+func (c crlfWriter) Write(p []byte) (int, error) {
+	if _, err := c.w.Write(bytes.ReplaceAll(p, []byte("\n"), []byte("\r\n"))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,18 @@
+package suser
+
+// Volumes in ml of the water shorthands
+const (
+	GlassML  = 250
+	BottleML = 500
+)
+
+// Hydration target factors: 35 ml per kg of body weight plus 500 ml per hour of exercise
+const (
+	WaterPerKgML           = 35
+	WaterPerExerciseHourML = 500
+)
+
+// GetHydrationTarget returns the daily water target of the user in ml
+func (u *SUser) GetHydrationTarget(exerciseMinutes int) int {
+	return u.Weight*WaterPerKgML + exerciseMinutes*WaterPerExerciseHourML/60
+}
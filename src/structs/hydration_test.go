@@ -0,0 +1,24 @@
+package suser
+
+import "testing"
+
+func TestGetHydrationTarget(t *testing.T) {
+	tests := []struct {
+		weight  int
+		minutes int
+		want    int
+	}{
+		{70, 0, 2450},
+		{70, 60, 2950},
+		{70, 30, 2700},
+		{80, 45, 3175},
+		{60, 1, 2108},
+		{0, 0, 0},
+	}
+	for _, test := range tests {
+		user := SUser{Weight: test.weight}
+		if got := user.GetHydrationTarget(test.minutes); got != test.want {
+			t.Errorf("target at %d kg with %d minutes of exercise = %d ml, want %d", test.weight, test.minutes, got, test.want)
+		}
+	}
+}
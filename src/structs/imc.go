@@ -0,0 +1,99 @@
+package suser
+
+import "math"
+
+// AdultAge is the age from which adult IMC categories apply
+const AdultAge = 18
+
+// IMCCategoryMinor is the category given to users under AdultAge
+const IMCCategoryMinor = "Not applicable (under 18)"
+
+// IMCCategory is a named IMC range, Min included and Max excluded
+type IMCCategory struct {
+	Name string
+	Min  float64
+	Max  float64
+}
+
+// WHO international classification of adult IMC
+var WHOCategories = []IMCCategory{
+	{Name: "Severe thinness", Min: 0, Max: 16},
+	{Name: "Moderate thinness", Min: 16, Max: 17},
+	{Name: "Mild thinness", Min: 17, Max: 18.5},
+	{Name: "Normal", Min: 18.5, Max: 25},
+	{Name: "Pre-obese", Min: 25, Max: 30},
+	{Name: "Obese class I", Min: 30, Max: 35},
+	{Name: "Obese class II", Min: 35, Max: 40},
+	{Name: "Obese class III", Min: 40, Max: math.Inf(1)},
+}
+
+// WHO expert consultation cut-offs for Asian populations
+var AsianCategories = []IMCCategory{
+	{Name: "Underweight", Min: 0, Max: 18.5},
+	{Name: "Normal", Min: 18.5, Max: 23},
+	{Name: "Overweight (increased risk)", Min: 23, Max: 27.5},
+	{Name: "Obese (high risk)", Min: 27.5, Max: math.Inf(1)},
+}
+
+// IMCCategories returns the classification to use
+func IMCCategories(asian bool) []IMCCategory {
+	if asian {
+		return AsianCategories
+	}
+	return WHOCategories
+}
+
+// ClassifyIMC returns the name of the adult category an IMC falls in
+func ClassifyIMC(imc float64, asian bool) string {
+	for _, category := range IMCCategories(asian) {
+		if imc >= category.Min && imc < category.Max {
+			return category.Name
+		}
+	}
+	return ""
+}
+
+// HealthyIMCRange returns the bounds of the "Normal" category
+func HealthyIMCRange(asian bool) (float64, float64) {
+	for _, category := range IMCCategories(asian) {
+		if category.Name == "Normal" {
+			return category.Min, category.Max
+		}
+	}
+	return 0, 0
+}
+
+// IsAdult reports whether adult IMC categories apply to the user
+func (u *SUser) IsAdult() bool {
+	return u.Age >= AdultAge
+}
+
+// GetIMCCategory returns the IMC category of the user
+func (u *SUser) GetIMCCategory(asian bool) string {
+	if !u.IsAdult() {
+		return IMCCategoryMinor
+	}
+	return ClassifyIMC(u.GetIMC(), asian)
+}
+
+// GetHealthyWeightRange returns the weights in kg giving a normal IMC for the
+// user's height, 0 - 0 when the height is not set
+func (u *SUser) GetHealthyWeightRange(asian bool) (float64, float64) {
+	low, high := HealthyIMCRange(asian)
+	squaredHeight := math.Pow(float64(u.Height)/100, 2)
+	return low * squaredHeight, high * squaredHeight
+}
+
+// GetDistanceToHealthyRange returns the kg to lose (positive) or gain (negative)
+// to reach the healthy weight range, 0 when already inside it
+func (u *SUser) GetDistanceToHealthyRange(asian bool) float64 {
+	low, high := u.GetHealthyWeightRange(asian)
+	weight := float64(u.Weight)
+	switch {
+	case weight < low:
+		return weight - low
+	case weight >= high:
+		return weight - high
+	}
+	return 0
+}
@@ -0,0 +1,85 @@
+package suser
+
+import "testing"
+
+func TestClassifyIMC(t *testing.T) {
+	tests := []struct {
+		imc   float64
+		asian bool
+		want  string
+	}{
+		{0, false, "Severe thinness"},
+		{15.99, false, "Severe thinness"},
+		{16, false, "Moderate thinness"},
+		{17, false, "Mild thinness"},
+		{18.49, false, "Mild thinness"},
+		{18.5, false, "Normal"},
+		{24.99, false, "Normal"},
+		{25, false, "Pre-obese"},
+		{30, false, "Obese class I"},
+		{35, false, "Obese class II"},
+		{40, false, "Obese class III"},
+		{80, false, "Obese class III"},
+		{-1, false, ""},
+		{0, true, "Underweight"},
+		{18.49, true, "Underweight"},
+		{18.5, true, "Normal"},
+		{22.99, true, "Normal"},
+		{23, true, "Overweight (increased risk)"},
+		{27.49, true, "Overweight (increased risk)"},
+		{27.5, true, "Obese (high risk)"},
+		{80, true, "Obese (high risk)"},
+	}
+	for _, test := range tests {
+		if got := ClassifyIMC(test.imc, test.asian); got != test.want {
+			t.Errorf("ClassifyIMC(%v, asian %v) = %q, want %q", test.imc, test.asian, got, test.want)
+		}
+	}
+}
+
+func TestHealthyIMCRange(t *testing.T) {
+	tests := []struct {
+		asian     bool
+		low, high float64
+	}{
+		{false, 18.5, 25},
+		{true, 18.5, 23},
+	}
+	for _, test := range tests {
+		if low, high := HealthyIMCRange(test.asian); low != test.low || high != test.high {
+			t.Errorf("HealthyIMCRange(asian %v) = %v - %v, want %v - %v", test.asian, low, high, test.low, test.high)
+		}
+	}
+}
+
+func TestGetDistanceToHealthyRange(t *testing.T) {
+	// At 2 m, the healthy range is 74 - 100 kg, 74 - 92 kg with the Asian cut-offs
+	tests := []struct {
+		weight int
+		asian  bool
+		want   float64
+	}{
+		{60, false, -14},
+		{73, false, -1},
+		{74, false, 0},
+		{99, false, 0},
+		{100, false, 0},
+		{101, false, 1},
+		{73, true, -1},
+		{74, true, 0},
+		{91, true, 0},
+		{92, true, 0},
+		{100, true, 8},
+	}
+	for _, test := range tests {
+		user := SUser{Age: 30, Height: 200, Weight: test.weight}
+		if got := user.GetDistanceToHealthyRange(test.asian); got != test.want {
+			t.Errorf("distance of %d kg at 200 cm (asian %v) = %v, want %v", test.weight, test.asian, got, test.want)
+		}
+	}
+
+	user := SUser{Age: 30, Weight: 70}
+	if low, high := user.GetHealthyWeightRange(false); low != 0 || high != 0 {
+		t.Errorf("healthy range without a height = %v - %v, want 0 - 0", low, high)
+	}
+}
@@ -0,0 +1,31 @@
+package suser
+
+import "time"
+
+// Meal is a named set of foods, typed e.g. breakfast, lunch or dinner
+type Meal struct {
+	ID   int
+	Name string
+	Type string
+}
+
+// DayPreset is a named set of meals logged together
+type DayPreset struct {
+	ID     int
+	UserID int
+	Name   string
+}
+
+// TrashItem is a deleted row kept in the trash of a user until it is
+// restored or purged. Kind is food for an entry of the food history, with
+// its FoodID, Quantity and Date, meal or day for a meal or a day preset,
+// with its Name.
+type TrashItem struct {
+	Kind      string
+	ID        int
+	Name      string
+	FoodID    int
+	Quantity  float64
+	Date      string
+	DeletedAt time.Time
+}
@@ -3,16 +3,82 @@ package suser
 import (
 	iuser "gotracker/interface"
 	"math"
+	"time"
 )
 
 type SUser struct {
 	ID           int
+	Username     string // Empty for the accounts created before the credentials
+	Email        string
 	Firstname    string
 	Lastname     string
 	Age          int
 	Weight       int
 	Height       int
 	TargetWeight int
+	Lang         string // Preferred language of the messages, empty for the default
+	Timezone     string // IANA time zone of the dates, empty for the local zone of the host
+	Role         string // RoleMember, RoleCoach or RoleAdmin
+}
+
+// Roles of the users: coaches follow the clients who accept them, the
+// administrators manage the users and may also coach
+const (
+	RoleMember = "member"
+	RoleCoach  = "coach"
+	RoleAdmin  = "admin"
+)
+
+// Roles are the roles a user may have
+var Roles = []string{RoleMember, RoleCoach, RoleAdmin}
+
+// HasRole reports whether the user has a role, administrators have them all
+func (u SUser) HasRole(role string) bool {
+	return u.Role == role || u.Role == RoleAdmin || role == RoleMember
+}
+
+// Account is a user as listed to the administrators
+type Account struct {
+	ID          int
+	Username    string
+	Email       string
+	Firstname   string
+	Lastname    string
+	Role        string
+	LockedUntil time.Time // Zero when the account is not locked
+}
+
+// CoachClient links a coach to a client. The client consents by accepting
+// the invitation of the coach, the coach may read the data of the client from
+// then on. Username, Firstname and Lastname are those of the other user: the
+// client in the lists of a coach, the coach in the lists of a client.
+type CoachClient struct {
+	CoachID    int
+	ClientID   int
+	Username   string
+	Firstname  string
+	Lastname   string
+	InvitedAt  time.Time
+	AcceptedAt time.Time // Zero until the client accepts
+}
+
+// AuditEntry is a change of a row recorded by the audit log. ActorID is the
+// user who made it, 0 for the changes made without a logged in user, such as
+// the logins, or outside the application, and
+// UserID the owner of the data, 0 for the shared meals. OldValues is nil for
+// an insert, NewValues for a delete.
+type AuditEntry struct {
+	ID        int64
+	ChangedAt time.Time
+	ActorID   int
+	Actor     string // Username of the actor
+	SessionID string
+	UserID    int
+	Table     string
+	RowID     string
+	Action    string // insert, update or delete
+	OldValues map[string]interface{}
+	NewValues map[string]interface{}
 }
 
 // GetBodyFat returns the BodyFat value of the user
@@ -90,3 +156,37 @@ func (u *SUser) Create(id int, firstname string, lastname string, age int, weigh
 	u.Height = height
 	return u
 }
+
+// Credentials are the password and the login lockout of a user, PasswordHash
+// is empty for the accounts created before the credentials
+type Credentials struct {
+	UserID       int
+	Username     string
+	PasswordHash string
+	FailedLogins int
+	LockedUntil  time.Time // Zero when the account is not locked
+}
+
+// APIToken gives access to the routes of its scopes of the API as a user
+// until it expires, only the hash of the token is stored
+type APIToken struct {
+	ID         int
+	UserID     int
+	Name       string
+	Scopes     []string
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	LastUsedAt time.Time // Zero when the token was never used
+}
+
+// SSHKey is a public key logging in its user on the SSH server, PublicKey is
+// in the authorized_keys format
+type SSHKey struct {
+	ID          int
+	UserID      int
+	Name        string
+	Fingerprint string
+	PublicKey   string
+	CreatedAt   time.Time
+	LastUsedAt  time.Time // Zero when the key was never used
+}
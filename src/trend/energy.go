@@ -0,0 +1,90 @@
+package trend
+
+import (
+	"errors"
+	"time"
+)
+
+// KcalPerKg is the usual energy equivalent of one kilogram of body weight
+const KcalPerKg = 7700
+
+// Estimation period bounds in days
+const (
+	MinEnergyDays     = 14
+	MaxEnergyDays     = 28
+	DefaultEnergyDays = 21
+)
+
+// Confidence levels of an energy estimate
+const (
+	ConfidenceLow    = "low"
+	ConfidenceMedium = "medium"
+	ConfidenceHigh   = "high"
+)
+
+// EnergyEstimate is the maintenance calories back-calculated from intake and weight trend
+type EnergyEstimate struct {
+	Maintenance     float64 // Estimated daily energy expenditure in kcal
+	AverageIntake   float64 // Average kcal eaten on logged days
+	WeeklyChange    float64 // Change of the weight trend in kg per week
+	Days            int     // Length of the period
+	LoggedDays      int     // Days of the period with at least one food logged
+	Confidence      string
+	ConfidenceRatio float64 // Share of the period that was logged
+}
+
+// EstimateMaintenance computes the maintenance calories over the days days ending
+// at end. Intake maps a "2006-01-02" date to the kcal eaten that day; days missing
+// from the map are considered not logged rather than fasted.
+func EstimateMaintenance(intake map[string]float64, weights []Point, end time.Time, days int) (*EnergyEstimate, error) {
+	if days <= 0 {
+		return nil, errors.New("period must be a positive number of days")
+	}
+	start := end.AddDate(0, 0, -days+1)
+
+	var total float64
+	var logged int
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		if kcal, ok := intake[day.Format("2006-01-02")]; ok && kcal > 0 {
+			total += kcal
+			logged++
+		}
+	}
+	if logged == 0 {
+		return nil, errors.New("no food logged in the period")
+	}
+
+	// The trend is smoothed over the whole history so it is settled by the start of the period
+	smoothed := Smooth(DailyAverage(weights), DefaultAlpha)
+	var period []Point
+	for _, p := range smoothed {
+		if !p.Date.Before(start) && !p.Date.After(end) {
+			period = append(period, p)
+		}
+	}
+	if len(period) < 2 {
+		return nil, errors.New("at least two weigh-ins are needed in the period")
+	}
+	slope, _ := Regression(period)
+
+	estimate := &EnergyEstimate{
+		AverageIntake:   total / float64(logged),
+		WeeklyChange:    slope * 7,
+		Days:            days,
+		LoggedDays:      logged,
+		ConfidenceRatio: float64(logged) / float64(days),
+	}
+	// A surplus shows up as weight gain, so maintenance is what was eaten minus it
+	estimate.Maintenance = estimate.AverageIntake - slope*KcalPerKg
+
+	switch {
+	case estimate.ConfidenceRatio >= 0.85 && days >= DefaultEnergyDays:
+		estimate.Confidence = ConfidenceHigh
+	case estimate.ConfidenceRatio >= 0.6:
+		estimate.Confidence = ConfidenceMedium
+	default:
+		estimate.Confidence = ConfidenceLow
+	}
+
+	return estimate, nil
+}
@@ -0,0 +1,109 @@
+package trend
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// dailyWeights returns a weigh-in on each of the first n days of 2024,
+// starting at 90 kg and changing by slope kg per day
+func dailyWeights(n int, slope float64) []Point {
+	points := make([]Point, n)
+	for i := range points {
+		points[i] = Point{day(i + 1), 90 + slope*float64(i)}
+	}
+	return points
+}
+
+// intakeOn returns the intake of kcal on each of the days from first to last
+func intakeOn(first int, last int, kcal float64) map[string]float64 {
+	intake := make(map[string]float64)
+	for n := first; n <= last; n++ {
+		intake[day(n).Format("2006-01-02")] = kcal
+	}
+	return intake
+}
+
+func TestEstimateMaintenance(t *testing.T) {
+	// Losing 0.1 kg a day while eating 2000 kcal is a deficit of 770 kcal a day
+	estimate, err := EstimateMaintenance(intakeOn(40, 60, 2000), dailyWeights(60, -0.1), day(60), 21)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(estimate.Maintenance-2770) > 5 {
+		t.Errorf("maintenance = %.1f kcal, want 2770", estimate.Maintenance)
+	}
+	if math.Abs(estimate.WeeklyChange+0.7) > 0.01 {
+		t.Errorf("weekly change = %.3f kg, want -0.7", estimate.WeeklyChange)
+	}
+	if estimate.AverageIntake != 2000 || estimate.LoggedDays != 21 || estimate.Days != 21 || estimate.Confidence != ConfidenceHigh {
+		t.Errorf("estimate = %+v, want 2000 kcal on 21 of 21 days with a high confidence", estimate)
+	}
+}
+
+func TestEstimateMaintenanceStableWeight(t *testing.T) {
+	// Days not logged or logged empty are left out of the average
+	intake := intakeOn(1, 14, 1800)
+	for n := 1; n <= 14; n += 2 {
+		intake[day(n).Format("2006-01-02")] = 2200
+	}
+	intake[day(7).Format("2006-01-02")] = 0
+	delete(intake, day(8).Format("2006-01-02"))
+	estimate, err := EstimateMaintenance(intake, dailyWeights(14, 0), day(14), 14)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (6*2200 + 6*1800) / 12.0; estimate.Maintenance != want || estimate.AverageIntake != want {
+		t.Errorf("maintenance %.1f, intake %.1f, want %.1f", estimate.Maintenance, estimate.AverageIntake, want)
+	}
+	if estimate.LoggedDays != 12 {
+		t.Errorf("logged days = %d, want 12", estimate.LoggedDays)
+	}
+}
+
+func TestEstimateMaintenanceConfidence(t *testing.T) {
+	tests := []struct {
+		days   int
+		logged int
+		want   string
+	}{
+		{21, 21, ConfidenceHigh},
+		{21, 18, ConfidenceHigh},
+		{21, 17, ConfidenceMedium},
+		{14, 14, ConfidenceMedium},
+		{21, 13, ConfidenceMedium},
+		{21, 12, ConfidenceLow},
+	}
+	for _, test := range tests {
+		estimate, err := EstimateMaintenance(intakeOn(31-test.logged, 30, 2000), dailyWeights(30, 0), day(30), test.days)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if estimate.Confidence != test.want {
+			t.Errorf("%d of %d days logged: confidence %s, want %s", test.logged, test.days, estimate.Confidence, test.want)
+		}
+	}
+}
+
+func TestEstimateMaintenanceErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		intake  map[string]float64
+		weights []Point
+		days    int
+		want    string
+	}{
+		{"no period", intakeOn(1, 30, 2000), dailyWeights(30, 0), 0, "period must be a positive number of days"},
+		{"no food", map[string]float64{}, dailyWeights(30, 0), 21, "no food logged in the period"},
+		{"food before the period", intakeOn(1, 9, 2000), dailyWeights(30, 0), 21, "no food logged in the period"},
+		{"one weigh-in", intakeOn(10, 30, 2000), []Point{{day(1), 80}, {day(5), 80}, {day(30), 80}}, 21, "at least two weigh-ins are needed in the period"},
+		{"no weigh-ins", intakeOn(10, 30, 2000), nil, 21, "at least two weigh-ins are needed in the period"},
+	}
+	for _, test := range tests {
+		_, err := EstimateMaintenance(test.intake, test.weights, day(30), test.days)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: error %v, want %q", test.name, err, test.want)
+		}
+	}
+}
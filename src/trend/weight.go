@@ -0,0 +1,157 @@
+package trend
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+// DefaultAlpha is the smoothing factor used by The Hacker's Diet: each day the
+// trend moves 10% of the way towards the new weigh-in
+const DefaultAlpha = 0.1
+
+// DefaultWindow is the number of days used for the regression when none is given
+const DefaultWindow = 14
+
+// Point is a single dated weight value
+type Point struct {
+	Date   time.Time
+	Weight float64
+}
+
+// WeightTrend is the result of a weight trend analysis
+type WeightTrend struct {
+	Latest     float64   // Last raw weigh-in
+	Trend      float64   // Last smoothed value
+	WeeklyRate float64   // Change of the trend in kg per week
+	Window     int       // Regression window in days
+	Samples    int       // Number of days with a weigh-in inside the window
+	TargetDate time.Time // Zero when the target is not reached at the current rate
+}
+
+// DailyAverage sorts the points by date and merges weigh-ins made on the same day
+func DailyAverage(points []Point) []Point {
+	sorted := make([]Point, len(points))
+	copy(sorted, points)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Date.Before(sorted[j].Date)
+	})
+
+	var days []Point
+	var count int
+	for _, p := range sorted {
+		day := time.Date(p.Date.Year(), p.Date.Month(), p.Date.Day(), 0, 0, 0, 0, p.Date.Location())
+		if len(days) > 0 && days[len(days)-1].Date.Equal(day) {
+			last := &days[len(days)-1]
+			count++
+			last.Weight += (p.Weight - last.Weight) / float64(count)
+			continue
+		}
+		days = append(days, Point{Date: day, Weight: p.Weight})
+		count = 1
+	}
+	return days
+}
+
+// Smooth returns the exponentially weighted moving average of the points,
+// alpha being applied once per day: after a gap of n days without weigh-in
+// the trend moves 1-(1-alpha)^n of the way towards the next one, as if the
+// missing days had repeated it
+func Smooth(points []Point, alpha float64) []Point {
+	smoothed := make([]Point, len(points))
+	for i, p := range points {
+		if i == 0 {
+			smoothed[i] = p
+			continue
+		}
+		prev := smoothed[i-1]
+		days := math.Round(p.Date.Sub(prev.Date).Hours() / 24)
+		effective := alpha
+		if days > 1 {
+			effective = 1 - math.Pow(1-alpha, days)
+		}
+		smoothed[i] = Point{Date: p.Date, Weight: prev.Weight + effective*(p.Weight-prev.Weight)}
+	}
+	return smoothed
+}
+
+// Regression fits a least squares line through the points and returns its
+// slope in kg per day and its value at the first point
+func Regression(points []Point) (slope float64, intercept float64) {
+	if len(points) == 0 {
+		return 0, 0
+	}
+	if len(points) == 1 {
+		return 0, points[0].Weight
+	}
+
+	origin := points[0].Date
+	var sumX, sumY, sumXY, sumXX float64
+	for _, p := range points {
+		x := p.Date.Sub(origin).Hours() / 24
+		sumX += x
+		sumY += p.Weight
+		sumXY += x * p.Weight
+		sumXX += x * x
+	}
+	n := float64(len(points))
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0, sumY / n
+	}
+	slope = (n*sumXY - sumX*sumY) / denominator
+	intercept = (sumY - slope*sumX) / n
+	return slope, intercept
+}
+
+// ProjectTargetDate returns the date the target weight is reached when moving
+// from current at rate kg per day, or the zero time if it is never reached
+func ProjectTargetDate(from time.Time, current float64, target float64, ratePerDay float64) time.Time {
+	remaining := target - current
+	if remaining == 0 {
+		return from
+	}
+	if ratePerDay == 0 || math.Signbit(remaining) != math.Signbit(ratePerDay) {
+		return time.Time{}
+	}
+	days := math.Ceil(remaining / ratePerDay)
+	return from.AddDate(0, 0, int(days))
+}
+
+// AnalyzeWeight smooths the weigh-ins, fits a regression over the last window
+// days of the trend and projects when the target weight will be reached
+func AnalyzeWeight(points []Point, window int, target float64) (*WeightTrend, error) {
+	if len(points) == 0 {
+		return nil, errors.New("no weight history to analyze")
+	}
+	if window <= 0 {
+		return nil, errors.New("window must be a positive number of days")
+	}
+
+	days := DailyAverage(points)
+	smoothed := Smooth(days, DefaultAlpha)
+	last := smoothed[len(smoothed)-1]
+
+	// Keep only the trend values inside the regression window
+	start := last.Date.AddDate(0, 0, -window+1)
+	var recent []Point
+	for _, p := range smoothed {
+		if !p.Date.Before(start) {
+			recent = append(recent, p)
+		}
+	}
+	slope, _ := Regression(recent)
+
+	result := &WeightTrend{
+		Latest:     days[len(days)-1].Weight,
+		Trend:      last.Weight,
+		WeeklyRate: slope * 7,
+		Window:     window,
+		Samples:    len(recent),
+	}
+	if target > 0 {
+		result.TargetDate = ProjectTargetDate(last.Date, last.Weight, target, slope)
+	}
+	return result, nil
+}
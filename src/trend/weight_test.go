@@ -0,0 +1,61 @@
+package trend
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// day returns the date of the nth day of January 2024
+func day(n int) time.Time {
+	return time.Date(2024, time.January, n, 0, 0, 0, 0, time.UTC)
+}
+
+func TestSmoothDaily(t *testing.T) {
+	points := []Point{{day(1), 80}, {day(2), 90}, {day(3), 90}}
+	smoothed := Smooth(points, 0.1)
+	want := []float64{80, 81, 81.9}
+	for i, p := range smoothed {
+		if math.Abs(p.Weight-want[i]) > 1e-9 {
+			t.Errorf("day %d: trend %v, want %v", i+1, p.Weight, want[i])
+		}
+		if !p.Date.Equal(points[i].Date) {
+			t.Errorf("day %d: date %v, want %v", i+1, p.Date, points[i].Date)
+		}
+	}
+}
+
+func TestSmoothGap(t *testing.T) {
+	// A weigh-in after a gap moves the trend as much as the same weight
+	// repeated every day of the gap
+	gap := Smooth([]Point{{day(1), 80}, {day(6), 90}}, 0.1)
+	daily := Smooth([]Point{{day(1), 80}, {day(2), 90}, {day(3), 90}, {day(4), 90}, {day(5), 90}, {day(6), 90}}, 0.1)
+	if got, want := gap[1].Weight, daily[5].Weight; math.Abs(got-want) > 1e-9 {
+		t.Errorf("trend after a gap of 5 days = %v, want %v", got, want)
+	}
+}
+
+func TestSmoothEmpty(t *testing.T) {
+	if smoothed := Smooth(nil, DefaultAlpha); len(smoothed) != 0 {
+		t.Errorf("Smooth(nil) = %v, want no points", smoothed)
+	}
+}
+
+func TestDailyAverage(t *testing.T) {
+	points := []Point{
+		{day(2).Add(20 * time.Hour), 81},
+		{day(1).Add(8 * time.Hour), 80},
+		{day(2).Add(7 * time.Hour), 82},
+		{day(2).Add(12 * time.Hour), 83},
+	}
+	days := DailyAverage(points)
+	if len(days) != 2 {
+		t.Fatalf("DailyAverage returned %d days, want 2", len(days))
+	}
+	if !days[0].Date.Equal(day(1)) || days[0].Weight != 80 {
+		t.Errorf("first day = %v, want 80 on %v", days[0], day(1))
+	}
+	if !days[1].Date.Equal(day(2)) || math.Abs(days[1].Weight-82) > 1e-9 {
+		t.Errorf("second day = %v, want 82 on %v", days[1], day(2))
+	}
+}
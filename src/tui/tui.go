@@ -0,0 +1,418 @@
+// Package tui is the full-screen dashboard of the day. It draws the results of
+// the same commands as the CLI, run through the registry, so the dashboard and
+// the REPL always agree.
+package tui
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"gotracker/cli"
+	"gotracker/commands"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// Terminal control sequences
+const (
+	altScreenOn  = "\x1b[?1049h"
+	altScreenOff = "\x1b[?1049l"
+	cursorHide   = "\x1b[?25l"
+	cursorShow   = "\x1b[?25h"
+	styleReverse = "\x1b[7m"
+	styleBold    = "\x1b[1m"
+	styleRed     = "\x1b[31m"
+	styleDim     = "\x1b[2m"
+	styleReset   = "\x1b[0m"
+)
+
+// errQuit ends the dashboard
+var errQuit = errors.New("quit")
+
+// line is a line of the screen, style applies to the whole line
+type line struct {
+	text  string
+	style string
+}
+
+type dashboard struct {
+	ctx      *commands.Context
+	registry *cli.Registry
+	out      io.Writer
+	fd       int
+	keys     <-chan rune
+	keyErrs  <-chan error
+
+	day      *commands.DayLog
+	summary  *commands.Summary
+	results  []commands.FoodMatch
+	selected int
+	query    string
+	prompt   string // Prompt being edited, empty when browsing
+	status   string
+	warnings bytes.Buffer // Warnings written by the commands
+}
+
+// Run shows the dashboard on the terminal until q, Ctrl-C or the cancellation
+// of the application context. The session of ctx must be logged in.
+func Run(ctx *commands.Context, registry *cli.Registry, in *os.File, out io.Writer) error {
+	fd := int(in.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("failed to switch the terminal to raw mode: %w", err)
+	}
+	defer term.Restore(fd, state)
+	fmt.Fprint(out, altScreenOn)
+	defer fmt.Fprint(out, cursorShow+altScreenOff)
+
+	d := &dashboard{ctx: ctx, registry: registry, out: out, fd: fd}
+
+	// Warnings of the commands would be drawn over the screen, show them in the status line
+	errOut := ctx.Err
+	ctx.Err = &d.warnings
+	defer func() { ctx.Err = errOut }()
+
+	// Keys are read in the background to stop on signals while waiting for one
+	keys, keyErrs, done := make(chan rune), make(chan error, 1), make(chan struct{})
+	defer close(done)
+	d.keys, d.keyErrs = keys, keyErrs
+	editor := &cli.Editor{In: in, Out: out}
+	go func() {
+		for {
+			key, err := editor.ReadKey()
+			if err != nil {
+				keyErrs <- err
+				return
+			}
+			select {
+			case keys <- key:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	d.refresh()
+	for {
+		d.draw()
+		key, err := d.readKey()
+		if err == nil {
+			err = d.handle(key)
+		}
+		if errors.Is(err, errQuit) || errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// readKey waits for a key, errQuit is returned when the application stops
+func (d *dashboard) readKey() (rune, error) {
+	select {
+	case key := <-d.keys:
+		return key, nil
+	case err := <-d.keyErrs:
+		return 0, err
+	case <-d.ctx.RequestContext().Done():
+		return 0, errQuit
+	}
+}
+
+// handle applies a key of the dashboard
+func (d *dashboard) handle(key rune) error {
+	switch key {
+	case 'q', cli.KeyCtrlC, cli.KeyCtrlD:
+		return errQuit
+	case '/':
+		query, err := d.readPrompt(d.ctx.Translate("Search: "), d.query)
+		if err != nil || query == "" {
+			return err
+		}
+		d.search(query)
+	case cli.KeyUp, cli.KeyCtrlP, 'k':
+		if d.selected > 0 {
+			d.selected--
+		}
+	case cli.KeyDown, cli.KeyCtrlN, 'j':
+		if d.selected < len(d.results)-1 {
+			d.selected++
+		}
+	case cli.KeyEnter, cli.KeyLineFeed:
+		if len(d.results) > 0 {
+			return d.addSelected()
+		}
+	case 'w':
+		if _, err := d.run("log", "water", "glass"); err == nil {
+			d.status = d.ctx.Translate("Logged a glass of water (250 ml).")
+		}
+		d.refresh()
+	case 'r':
+		d.status = ""
+		d.refresh()
+	}
+	return nil
+}
+
+// run runs a command line, the error and the warnings of the command go to the status line
+func (d *dashboard) run(words ...string) (*cli.Result, error) {
+	result, err := d.registry.Run(words)
+	if err != nil {
+		d.status = d.ctx.Sprintf("Error: %s", d.ctx.Translate(err.Error()))
+	} else if warnings := strings.TrimSpace(d.warnings.String()); warnings != "" {
+		lines := strings.Split(warnings, "\n")
+		d.status = lines[len(lines)-1]
+	}
+	d.warnings.Reset()
+	return result, err
+}
+
+// refresh reloads the foods of the day and the summary
+func (d *dashboard) refresh() {
+	if result, err := d.run("today"); err == nil {
+		if day, ok := result.Data.(commands.DayLog); ok {
+			d.day = &day
+		}
+	}
+	if result, err := d.run("summary"); err == nil {
+		if summary, ok := result.Data.(commands.Summary); ok {
+			d.summary = &summary
+		}
+	}
+}
+
+func (d *dashboard) search(query string) {
+	d.query = query
+	d.status = d.ctx.Translate("Searching FoodData Central...")
+	d.draw()
+	result, err := d.run("search", "food", query)
+	if err != nil {
+		return
+	}
+	d.results, _ = result.Data.([]commands.FoodMatch)
+	d.selected = 0
+	d.status = d.ctx.Sprintf("%d foods found for '%s'.", len(d.results), query)
+}
+
+// addSelected asks the quantity and the meal type of the selected food and logs it
+func (d *dashboard) addSelected() error {
+	food := d.results[d.selected]
+	quantity, err := d.readPrompt(d.ctx.Sprintf("Quantity of %s (g): ", food.Description), "100")
+	if err != nil || quantity == "" {
+		return err
+	}
+	if _, err := strconv.Atoi(quantity); err != nil {
+		d.status = d.ctx.Translate("Error: the quantity must be a whole number of grams")
+		return nil
+	}
+	meal, err := d.readPrompt(d.ctx.Translate("Meal type: "), defaultMealType(d.ctx.Now()))
+	if err != nil {
+		return err
+	}
+
+	words := []string{"add", "food", strconv.Itoa(food.FoodID), quantity}
+	if meal = strings.TrimSpace(meal); meal != "" {
+		words = append(words, "--meal", meal)
+	}
+	if _, err := d.run(words...); err == nil {
+		d.status = d.ctx.Sprintf("Logged %s g of %s.", quantity, food.Description)
+	}
+	d.refresh()
+	return nil
+}
+
+// defaultMealType returns the meal type usually eaten at a time of day
+func defaultMealType(now time.Time) string {
+	switch hour := now.Hour(); {
+	case hour < 11:
+		return "breakfast"
+	case hour < 15:
+		return "lunch"
+	case hour < 18:
+		return "snack"
+	default:
+		return "dinner"
+	}
+}
+
+// readPrompt edits a value on the prompt line, an empty value is returned when
+// the prompt is cancelled with Ctrl-C
+func (d *dashboard) readPrompt(label string, value string) (string, error) {
+	input := []rune(value)
+	defer func() { d.prompt = "" }()
+	for {
+		d.prompt = label + string(input)
+		d.draw()
+		key, err := d.readKey()
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case key == cli.KeyEnter || key == cli.KeyLineFeed:
+			return strings.TrimSpace(string(input)), nil
+		case key == cli.KeyCtrlC || key == cli.KeyCtrlG:
+			return "", nil
+		case key == cli.KeyDelete || key == cli.KeyBackspace:
+			if len(input) > 0 {
+				input = input[:len(input)-1]
+			}
+		case key == cli.KeyCtrlU:
+			input = nil
+		case key < cli.KeyUp && key >= ' ':
+			input = append(input, key)
+		}
+	}
+}
+
+// draw redraws the whole screen
+func (d *dashboard) draw() {
+	width, height, err := term.GetSize(d.fd)
+	if err != nil {
+		width, height = 80, 24
+	}
+
+	name := strings.TrimSpace(d.ctx.Session.User.Firstname + " " + d.ctx.Session.User.Lastname)
+	lines := []line{{text: fmt.Sprintf(" GoTracker | %s | %s", name, d.ctx.Today()), style: styleReverse}}
+	lines = append(lines, d.foodLines(width)...)
+	lines = append(lines, d.progressLines(width)...)
+
+	// The search pane takes the remaining space, the last two lines are the status and the keys
+	lines = append(lines, line{}, line{text: d.ctx.Translate("Search"), style: styleBold})
+	if d.prompt != "" {
+		lines = append(lines, line{text: "  " + d.prompt})
+	} else if d.query != "" {
+		lines = append(lines, line{text: d.ctx.Sprintf("  Results for '%s'", d.query), style: styleDim})
+	} else {
+		lines = append(lines, line{text: d.ctx.Translate("  Press / to search FoodData Central"), style: styleDim})
+	}
+	promptRow := len(lines)
+	lines = append(lines, d.resultLines(height-len(lines)-2)...)
+
+	var screen strings.Builder
+	screen.WriteString(cursorHide + "\x1b[H")
+	for i := 0; i < height-2; i++ {
+		if i < len(lines) {
+			screen.WriteString(render(lines[i], width))
+		}
+		screen.WriteString("\x1b[K\r\n")
+	}
+	screen.WriteString(render(line{text: " " + d.status}, width) + "\x1b[K\r\n")
+	keys := d.ctx.Translate(" / search  up/down select  enter log food  w glass of water  r refresh  q quit")
+	screen.WriteString(render(line{text: keys, style: styleReverse}, width) + "\x1b[K")
+	if d.prompt != "" && promptRow <= height-2 {
+		// Leave the cursor at the end of the prompt
+		fmt.Fprintf(&screen, "\x1b[%d;%dH%s", promptRow, min(len([]rune(d.prompt))+3, width), cursorShow)
+	}
+	fmt.Fprint(d.out, screen.String())
+}
+
+// foodLines lists the foods of the day by meal type
+func (d *dashboard) foodLines(width int) []line {
+	lines := []line{{}, {text: d.ctx.Translate("Foods"), style: styleBold}}
+	if d.day == nil || len(d.day.Foods) == 0 {
+		return append(lines, line{text: d.ctx.Translate("  No food logged today, press / to search one."), style: styleDim})
+	}
+	descWidth := max(width-28, 10)
+	types, groups := commands.GroupByMeal(d.day.Foods)
+	for _, mealType := range types {
+		lines = append(lines, line{text: "  " + d.ctx.Translate(commands.MealLabel(mealType))})
+		for _, food := range groups[mealType] {
+			description := truncate(food.Description, descWidth)
+			lines = append(lines, line{text: d.ctx.Sprintf("    %-*s %6.0f g %6.0f kcal", descWidth, description, food.Quantity, food.Calories)})
+		}
+	}
+	return lines
+}
+
+// progressLines shows the nutrients against their targets, the water drunk and the exercise
+func (d *dashboard) progressLines(width int) []line {
+	lines := []line{{}, {text: d.ctx.Translate("Targets"), style: styleBold}}
+	barWidth := min(max(width-60, 10), 40)
+	if d.day != nil {
+		for _, nutrient := range d.day.Nutrients {
+			text := fmt.Sprintf("  %-14s ", d.ctx.Translate(nutrient.Name))
+			if nutrient.Target == nil {
+				text += bar(0, barWidth) + d.ctx.Sprintf(" %.1f %s (no target)", nutrient.Consumed, nutrient.Unit)
+				lines = append(lines, line{text: text})
+				continue
+			}
+			fraction := 0.0
+			if *nutrient.Target > 0 {
+				fraction = nutrient.Consumed / *nutrient.Target
+			}
+			text += bar(fraction, barWidth) + d.ctx.Sprintf(" %.1f / %.1f %s (%.0f%%)", nutrient.Consumed, *nutrient.Target, nutrient.Unit, fraction*100)
+			style := ""
+			if fraction > 1 {
+				style = styleRed
+			}
+			lines = append(lines, line{text: text, style: style})
+		}
+	}
+	if d.summary != nil {
+		fraction := 0.0
+		if d.summary.HydrationTarget > 0 {
+			fraction = float64(d.summary.Hydration) / float64(d.summary.HydrationTarget)
+		}
+		lines = append(lines, line{text: fmt.Sprintf("  %-14s ", d.ctx.Translate("Water")) + bar(fraction, barWidth) +
+			fmt.Sprintf(" %d / %d ml (%d%%)", d.summary.Hydration, d.summary.HydrationTarget, d.summary.HydrationPercent)})
+		lines = append(lines, line{text: fmt.Sprintf("  %-14s ", d.ctx.Translate("Exercise")) + d.ctx.Sprintf("%.0f kcal burned, net intake %.0f kcal", d.summary.Exercise, d.summary.Net)})
+	}
+	return lines
+}
+
+// resultLines lists the search results in at most rows lines, scrolled to the selection
+func (d *dashboard) resultLines(rows int) []line {
+	rows = max(rows, 1)
+	start := 0
+	if d.selected >= rows {
+		start = d.selected - rows + 1
+	}
+	var lines []line
+	for i := start; i < len(d.results) && i < start+rows; i++ {
+		food := d.results[i]
+		text := fmt.Sprintf("    %-8d %s (%s)", food.FoodID, food.Description, food.DataType)
+		if i == d.selected {
+			lines = append(lines, line{text: "  > " + text[4:], style: styleReverse})
+			continue
+		}
+		lines = append(lines, line{text: text})
+	}
+	return lines
+}
+
+// bar draws a progress bar of fraction, capped to a full bar
+func bar(fraction float64, width int) string {
+	filled := int(min(max(fraction, 0), 1) * float64(width))
+	return "[" + strings.Repeat("#", filled) + strings.Repeat("-", width-filled) + "]"
+}
+
+// truncate shortens text to width runes, marking the cut with an ellipsis
+func truncate(text string, width int) string {
+	runes := []rune(text)
+	if len(runes) <= width {
+		return text
+	}
+	if width <= 1 {
+		return string(runes[:width])
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+// render fits a line to the screen width, styled lines are padded so that
+// their style covers the whole width
+func render(l line, width int) string {
+	text := truncate(l.text, width)
+	if l.style == "" {
+		return text
+	}
+	if l.style == styleReverse {
+		text += strings.Repeat(" ", width-len([]rune(text)))
+	}
+	return l.style + text + styleReset
+}
@@ -0,0 +1,218 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	suser "gotracker/structs"
+)
+
+// ErrForbidden is returned when the actor of a store may not access the data
+// of a user, the commands and the API recognise it
+var ErrForbidden = errors.New("permission denied")
+
+// ErrLastAdmin is returned when a role change would leave no administrator
+var ErrLastAdmin = errors.New("the last administrator cannot lose the admin role")
+
+// Access is what an actor asks to do with the data of a user
+type Access int
+
+const (
+	OwnAccess   Access = iota // Change the data, only the user itself may
+	ReadAccess                // Read the data, the user and the coaches it accepted may
+	CoachAccess               // Follow clients, the user itself if it is a coach or an administrator
+	AdminAccess               // Manage the users, the administrators may
+)
+
+// CheckAccess returns ErrForbidden unless the actor may access the data of userID
+func CheckAccess(db Querier, actorID int, userID int, access Access) error {
+	var allowed bool
+	var err error
+	switch access {
+	case OwnAccess:
+		allowed = actorID == userID
+	case ReadAccess:
+		allowed = actorID == userID
+		if !allowed {
+			// A coach demoted to member loses its clients
+			err = db.QueryRow(`
+				SELECT EXISTS (
+					SELECT 1
+					FROM coach_client cc
+					JOIN users u ON u.id = cc.coach_id
+					WHERE cc.coach_id = $1 AND cc.client_id = $2
+						AND cc.accepted_at IS NOT NULL
+						AND u.role IN ('coach', 'admin')
+				)
+			`, actorID, userID).Scan(&allowed)
+		}
+	case CoachAccess:
+		if actorID == userID {
+			err = db.QueryRow(`
+				SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND role IN ('coach', 'admin'))
+			`, actorID).Scan(&allowed)
+		}
+	case AdminAccess:
+		err = db.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND role = 'admin')
+		`, actorID).Scan(&allowed)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check permissions: %w", err)
+	}
+	if !allowed {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// InviteClient invites a user to be followed by a coach, false when the coach
+// already invited or follows the user
+func InviteClient(db Querier, coachID int, clientID int) (bool, error) {
+	result, err := db.Exec(`
+		INSERT INTO coach_client (coach_id, client_id)
+		VALUES ($1, $2)
+		ON CONFLICT (coach_id, client_id) DO NOTHING
+	`, coachID, clientID)
+	if err != nil {
+		return false, fmt.Errorf("failed to invite client: %w", err)
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to invite client: %w", err)
+	}
+	return count > 0, nil
+}
+
+// AcceptCoach records the consent of a client to be followed by a coach,
+// false when the coach has no pending invitation for the client
+func AcceptCoach(db Querier, clientID int, coachID int) (bool, error) {
+	result, err := db.Exec(`
+		UPDATE coach_client
+		SET accepted_at = NOW()
+		WHERE coach_id = $1 AND client_id = $2 AND accepted_at IS NULL
+	`, coachID, clientID)
+	if err != nil {
+		return false, fmt.Errorf("failed to accept coach: %w", err)
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to accept coach: %w", err)
+	}
+	return count > 0, nil
+}
+
+// EndCoaching deletes the link between a coach and a client, or the
+// invitation, false when there is none
+func EndCoaching(db Querier, coachID int, clientID int) (bool, error) {
+	result, err := db.Exec(`
+		DELETE FROM coach_client
+		WHERE coach_id = $1 AND client_id = $2
+	`, coachID, clientID)
+	if err != nil {
+		return false, fmt.Errorf("failed to end coaching: %w", err)
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to end coaching: %w", err)
+	}
+	return count > 0, nil
+}
+
+// scanCoachClient reads a row of coach_client joined with the other user
+func scanCoachClient(rows *sql.Rows) (suser.CoachClient, error) {
+	var link suser.CoachClient
+	var acceptedAt sql.NullTime
+	err := rows.Scan(&link.CoachID, &link.ClientID, &link.Username, &link.Firstname, &link.Lastname, &link.InvitedAt, &acceptedAt)
+	if err != nil {
+		return suser.CoachClient{}, err
+	}
+	link.AcceptedAt = acceptedAt.Time
+	return link, nil
+}
+
+// getCoachClients returns the links of a user with the other users, joined
+// on the column of the other side of the link
+func getCoachClients(db Querier, userID int, column string, other string) ([]suser.CoachClient, error) {
+	rows, err := db.Query(`
+		SELECT cc.coach_id, cc.client_id, COALESCE(u.username, ''), u.firstname, u.lastname, cc.invited_at, cc.accepted_at
+		FROM coach_client cc
+		JOIN users u ON u.id = cc.`+other+`
+		WHERE cc.`+column+` = $1
+		ORDER BY cc.accepted_at IS NULL, u.lastname, u.firstname, u.id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get coaching links: %w", err)
+	}
+	defer rows.Close()
+
+	var links []suser.CoachClient
+	for rows.Next() {
+		link, err := scanCoachClient(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan coaching link: %w", err)
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+// GetClients returns the clients of a coach and its pending invitations, the accepted first
+func GetClients(db Querier, coachID int) ([]suser.CoachClient, error) {
+	return getCoachClients(db, coachID, "coach_id", "client_id")
+}
+
+// GetCoaches returns the coaches of a client and their pending invitations, the accepted first
+func GetCoaches(db Querier, clientID int) ([]suser.CoachClient, error) {
+	return getCoachClients(db, clientID, "client_id", "coach_id")
+}
+
+// GetAccounts returns all the users, by ID
+func GetAccounts(db Querier) ([]suser.Account, error) {
+	rows, err := db.Query(`
+		SELECT id, COALESCE(username, ''), COALESCE(email, ''), firstname, lastname, role, locked_until
+		FROM users
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+	defer rows.Close()
+
+	var accounts []suser.Account
+	for rows.Next() {
+		var account suser.Account
+		var lockedUntil sql.NullTime
+		if err := rows.Scan(&account.ID, &account.Username, &account.Email, &account.Firstname, &account.Lastname, &account.Role, &lockedUntil); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		account.LockedUntil = lockedUntil.Time
+		accounts = append(accounts, account)
+	}
+	return accounts, rows.Err()
+}
+
+// SetUserRole changes the role of a user, false when there is no such user.
+// The last administrator keeps the admin role.
+func SetUserRole(db Querier, userID int, role string) (bool, error) {
+	var found, lastAdmin bool
+	err := db.QueryRow(`
+		WITH updated AS (
+			UPDATE users
+			SET role = $2
+			WHERE id = $1
+				AND ($2 = 'admin' OR role <> 'admin'
+					OR EXISTS (SELECT 1 FROM users WHERE role = 'admin' AND id <> $1))
+			RETURNING id
+		)
+		SELECT EXISTS (SELECT 1 FROM users WHERE id = $1),
+			NOT EXISTS (SELECT 1 FROM updated)
+	`, userID, role).Scan(&found, &lastAdmin)
+	if err != nil {
+		return false, fmt.Errorf("failed to set user role: %w", err)
+	}
+	if found && lastAdmin {
+		return false, ErrLastAdmin
+	}
+	return found, nil
+}
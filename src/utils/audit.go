@@ -0,0 +1,228 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	suser "gotracker/structs"
+	"time"
+)
+
+// AuditedTables are the tables whose changes the audit log records
+var AuditedTables = []string{
+	"users", "body_fat_history", "imc_history", "weight_history", "meal", "meal_food",
+	"food_history", "day_preset", "day_preset_meal", "target", "exercise_history",
+	"water_history", "alias", "api_token", "ssh_key", "coach_client",
+}
+
+// migrateAudit creates the audit log and the triggers recording the changes
+// of the audited tables. The store sets gotracker.actor and gotracker.session
+// in the transaction of each change, they are NULL for the changes made
+// without a logged in user, such as the logins, and outside the application.
+func migrateAudit(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS audit_log (
+			id BIGSERIAL PRIMARY KEY,
+			changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			actor_id INT,
+			session_id VARCHAR(50),
+			user_id INT,
+			table_name VARCHAR(50) NOT NULL,
+			row_id VARCHAR(50) NOT NULL,
+			action VARCHAR(10) NOT NULL,
+			old_values JSONB,
+			new_values JSONB
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create audit_log table: %w", err)
+	}
+	_, err = db.Exec(`
+		CREATE INDEX IF NOT EXISTS audit_log_user_id ON audit_log (user_id, changed_at)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create audit_log index: %w", err)
+	}
+
+	// The secrets are not copied to the log, a changed secret is only marked
+	// as changed. An update touching nothing but the last use of a token or
+	// a key is not a change of the data.
+	_, err = db.Exec(`
+		CREATE OR REPLACE FUNCTION audit_change() RETURNS trigger AS $$
+		DECLARE
+			old_row JSONB;
+			new_row JSONB;
+			old_values JSONB;
+			new_values JSONB;
+			data JSONB;
+			secret TEXT;
+		BEGIN
+			IF TG_OP <> 'INSERT' THEN
+				old_row := to_jsonb(OLD) - 'last_used_at';
+				old_values := old_row - 'password_hash' - 'token_hash';
+			END IF;
+			IF TG_OP <> 'DELETE' THEN
+				new_row := to_jsonb(NEW) - 'last_used_at';
+				new_values := new_row - 'password_hash' - 'token_hash';
+			END IF;
+			IF TG_OP = 'UPDATE' THEN
+				IF old_row = new_row THEN
+					RETURN NULL;
+				END IF;
+				FOREACH secret IN ARRAY ARRAY['password_hash', 'token_hash'] LOOP
+					IF old_row->secret IS DISTINCT FROM new_row->secret THEN
+						new_values := new_values || jsonb_build_object(secret, 'changed'::TEXT);
+					END IF;
+				END LOOP;
+			END IF;
+			data := COALESCE(new_row, old_row);
+			INSERT INTO audit_log (actor_id, session_id, user_id, table_name, row_id, action, old_values, new_values)
+			VALUES (
+				NULLIF(current_setting('gotracker.actor', true), '')::INT,
+				NULLIF(current_setting('gotracker.session', true), ''),
+				CASE TG_TABLE_NAME
+					WHEN 'users' THEN (data->>'id')::INT
+					WHEN 'coach_client' THEN (data->>'client_id')::INT
+					ELSE (data->>'user_id')::INT
+				END,
+				TG_TABLE_NAME,
+				COALESCE(data->>'id', (data->>'coach_id') || '-' || (data->>'client_id')),
+				lower(TG_OP),
+				old_values,
+				new_values
+			);
+			RETURN NULL;
+		END
+		$$ LANGUAGE plpgsql
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create audit function: %w", err)
+	}
+	for _, table := range AuditedTables {
+		_, err = db.Exec(`
+			CREATE OR REPLACE TRIGGER audit_change
+			AFTER INSERT OR UPDATE OR DELETE ON ` + table + `
+			FOR EACH ROW EXECUTE FUNCTION audit_change()
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to create audit trigger on %s: %w", table, err)
+		}
+	}
+
+	// The log is append-only. The expired entries are deleted by
+	// audit_log_purge, which runs as the owner of the table and raises the
+	// purge flag for its own DELETE only: a client setting the flag itself
+	// is refused unless it is the owner, which may drop the table anyway.
+	_, err = db.Exec(`
+		CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+		BEGIN
+			IF TG_OP = 'DELETE' AND current_setting('gotracker.audit_purge', true) = 'on'
+				AND current_user = (SELECT pg_get_userbyid(relowner) FROM pg_class WHERE oid = TG_RELID) THEN
+				RETURN OLD;
+			END IF;
+			RAISE EXCEPTION 'the audit log is append-only';
+		END
+		$$ LANGUAGE plpgsql
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create audit_log protection: %w", err)
+	}
+	_, err = db.Exec(`
+		CREATE OR REPLACE TRIGGER audit_log_append_only
+		BEFORE UPDATE OR DELETE ON audit_log
+		FOR EACH ROW EXECUTE FUNCTION audit_log_append_only()
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create audit_log protection: %w", err)
+	}
+	_, err = db.Exec(`
+		CREATE OR REPLACE TRIGGER audit_log_no_truncate
+		BEFORE TRUNCATE ON audit_log
+		FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only()
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create audit_log protection: %w", err)
+	}
+	_, err = db.Exec(`
+		CREATE OR REPLACE FUNCTION audit_log_purge(cutoff TIMESTAMPTZ) RETURNS BIGINT AS $$
+		DECLARE
+			purged BIGINT;
+		BEGIN
+			PERFORM set_config('gotracker.audit_purge', 'on', true);
+			DELETE FROM audit_log WHERE changed_at < cutoff;
+			GET DIAGNOSTICS purged = ROW_COUNT;
+			PERFORM set_config('gotracker.audit_purge', 'off', true);
+			RETURN purged;
+		END
+		$$ LANGUAGE plpgsql SECURITY DEFINER SET search_path FROM CURRENT
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create audit_log purge: %w", err)
+	}
+	_, err = db.Exec(`REVOKE ALL ON FUNCTION audit_log_purge(TIMESTAMPTZ) FROM PUBLIC`)
+	if err != nil {
+		return fmt.Errorf("failed to create audit_log purge: %w", err)
+	}
+	return nil
+}
+
+// GetAuditLog returns the latest changes of the data of a user and the
+// changes it made, the latest first. table filters the entries when not
+// empty, limit bounds their number.
+func GetAuditLog(db Querier, userID int, table string, limit int) ([]suser.AuditEntry, error) {
+	rows, err := db.Query(`
+		SELECT a.id, a.changed_at, COALESCE(a.actor_id, 0), COALESCE(u.username, ''), COALESCE(a.session_id, ''),
+			COALESCE(a.user_id, 0), a.table_name, a.row_id, a.action, a.old_values, a.new_values
+		FROM audit_log a
+		LEFT JOIN users u ON u.id = a.actor_id
+		WHERE (a.user_id = $1 OR a.actor_id = $1)
+			AND ($2 = '' OR a.table_name = $2)
+		ORDER BY a.changed_at DESC, a.id DESC
+		LIMIT $3
+	`, userID, table, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit log: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []suser.AuditEntry
+	for rows.Next() {
+		var entry suser.AuditEntry
+		var oldValues, newValues []byte
+		err := rows.Scan(&entry.ID, &entry.ChangedAt, &entry.ActorID, &entry.Actor, &entry.SessionID,
+			&entry.UserID, &entry.Table, &entry.RowID, &entry.Action, &oldValues, &newValues)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		if err := decodeValues(oldValues, &entry.OldValues); err != nil {
+			return nil, err
+		}
+		if err := decodeValues(newValues, &entry.NewValues); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// decodeValues decodes the JSONB values of a row, nil stays nil
+func decodeValues(data []byte, values *map[string]interface{}) error {
+	if data == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, values); err != nil {
+		return fmt.Errorf("failed to decode audit values: %w", err)
+	}
+	return nil
+}
+
+// PurgeAuditLog deletes the entries older than retention through
+// audit_log_purge and returns their number
+func PurgeAuditLog(db *sql.DB, retention time.Duration) (int64, error) {
+	var purged int64
+	err := db.QueryRow(`SELECT audit_log_purge($1)`, time.Now().Add(-retention)).Scan(&purged)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge audit log: %w", err)
+	}
+	return purged, nil
+}
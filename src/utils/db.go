@@ -2,49 +2,25 @@ package db
 
 import (
 	"database/sql"
-	"encoding/json"
 	"fmt"
-	"os"
+	suser "gotracker/structs"
+	"strings"
+	"time"
 
 	_ "github.com/lib/pq" // PostgreSQL driver
 )
 
-type Config struct {
-	DBUser     string `json:"db_user"`
-	DBPassword string `json:"db_password"`
-	DBHost     string `json:"db_host"`
-	DBPort     string `json:"db_port"`
-	DBName     string `json:"db_name"`
+// Querier runs the queries of the data layer: the *sql.DB of the reads or
+// the *sql.Tx of a change
+type Querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
 }
 
-// LoadConfig reads the configuration from a JSON file
-func LoadConfig() (*Config, error) {
-	file, err := os.Open("./vars/config.json")
-	if err != nil {
-		return nil, fmt.Errorf("failed to open config file: %w", err)
-	}
-	defer file.Close()
-
-	var config Config
-	decoder := json.NewDecoder(file)
-	err = decoder.Decode(&config)
-	if err != nil {
-		return nil, fmt.Errorf("failed to decode config file: %w", err)
-	}
-
-	return &config, nil
-}
-
-// ConnectDB establishes a connection to the postgres database
-func ConnectDB() (*sql.DB, error) {
-	config, err := LoadConfig()
-	if err != nil {
-		return nil, fmt.Errorf("failed to load config: %w", err)
-	}
-
-	dsn := fmt.Sprintf("user=%s dbname=%s sslmode=disable host=%s port=%s password=%s",
-		config.DBUser, config.DBName, config.DBHost, config.DBPort, config.DBPassword)
-
+// ConnectDB establishes a connection to the postgres database of dsn, a URL
+// or a key=value connection string
+func ConnectDB(dsn string) (*sql.DB, error) {
 	db, err := sql.Open("postgres", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
@@ -101,6 +77,14 @@ func Migrate(db *sql.DB) error {
 		return fmt.Errorf("failed to create imc_history table: %w", err)
 	}
 
+	// Add the IMC category to imc_history if it doesn't exist
+	_, err = db.Exec(`
+		ALTER TABLE imc_history ADD COLUMN IF NOT EXISTS category VARCHAR(50)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to add category to imc_history table: %w", err)
+	}
+
 	// Create weight_history table if it doesn't exist
 	_, err = db.Exec(`
 		CREATE TABLE IF NOT EXISTS weight_history (
@@ -153,6 +137,22 @@ func Migrate(db *sql.DB) error {
 		return fmt.Errorf("failed to create food_history table: %w", err)
 	}
 
+	// Add the preferred language to users if it doesn't exist
+	_, err = db.Exec(`
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS lang VARCHAR(10)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to add lang to users table: %w", err)
+	}
+
+	// Add the meal type to food_history if it doesn't exist
+	_, err = db.Exec(`
+		ALTER TABLE food_history ADD COLUMN IF NOT EXISTS meal_type VARCHAR(50)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to add meal_type to food_history table: %w", err)
+	}
+
 	// Create day_preset table if it doesn't exist
 	_, err = db.Exec(`
 		CREATE TABLE IF NOT EXISTS day_preset (
@@ -203,21 +203,197 @@ func Migrate(db *sql.DB) error {
 		return fmt.Errorf("failed to create target table: %w", err)
 	}
 
+	// Create exercise_history table if it doesn't exist
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS exercise_history (
+			id SERIAL PRIMARY KEY,
+			user_id INT REFERENCES users(id),
+			date DATE,
+			activity VARCHAR(50),
+			minutes INT,
+			intensity VARCHAR(20),
+			calories FLOAT
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create exercise_history table: %w", err)
+	}
+
+	// Create water_history table if it doesn't exist
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS water_history (
+			id SERIAL PRIMARY KEY,
+			user_id INT REFERENCES users(id),
+			date DATE,
+			amount INT
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create water_history table: %w", err)
+	}
+
+	// Add the time zone to users if it doesn't exist
+	_, err = db.Exec(`
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone VARCHAR(64)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to add timezone to users table: %w", err)
+	}
+
+	// Add the time of day to food_history if it doesn't exist
+	_, err = db.Exec(`
+		ALTER TABLE food_history ADD COLUMN IF NOT EXISTS time_of_day TIME
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to add time_of_day to food_history table: %w", err)
+	}
+
+	// Add the credentials and the login lockout to users if they don't exist,
+	// accounts created before have none and set them with a reset token
+	_, err = db.Exec(`
+		ALTER TABLE users
+			ADD COLUMN IF NOT EXISTS username VARCHAR(50) UNIQUE,
+			ADD COLUMN IF NOT EXISTS email VARCHAR(255) UNIQUE,
+			ADD COLUMN IF NOT EXISTS password_hash VARCHAR(100),
+			ADD COLUMN IF NOT EXISTS failed_logins INT NOT NULL DEFAULT 0,
+			ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to add credentials to users table: %w", err)
+	}
+
+	// Create alias table if it doesn't exist
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS alias (
+			id SERIAL PRIMARY KEY,
+			user_id INT REFERENCES users(id),
+			name VARCHAR(50),
+			definition TEXT,
+			UNIQUE (user_id, name)
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create alias table: %w", err)
+	}
+
+	// Create api_token table if it doesn't exist, tokens are stored as SHA-256 hashes
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS api_token (
+			id SERIAL PRIMARY KEY,
+			user_id INT REFERENCES users(id),
+			token_hash CHAR(64) UNIQUE,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			expires_at TIMESTAMPTZ NOT NULL
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create api_token table: %w", err)
+	}
+	// The tokens given by login before the scopes reach everything
+	_, err = db.Exec(`
+		ALTER TABLE api_token
+		ADD COLUMN IF NOT EXISTS name VARCHAR(50) NOT NULL DEFAULT 'login',
+		ADD COLUMN IF NOT EXISTS scopes TEXT NOT NULL DEFAULT 'admin',
+		ADD COLUMN IF NOT EXISTS last_used_at TIMESTAMPTZ
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to add scopes to api_token table: %w", err)
+	}
+
+	// Create ssh_key table if it doesn't exist, a public key logs in one user on the SSH server
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS ssh_key (
+			id SERIAL PRIMARY KEY,
+			user_id INT REFERENCES users(id),
+			name VARCHAR(50) NOT NULL,
+			fingerprint VARCHAR(100) UNIQUE NOT NULL,
+			public_key TEXT NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			last_used_at TIMESTAMPTZ
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create ssh_key table: %w", err)
+	}
+
+	// Add the role to users if it doesn't exist, the first user administers the others
+	_, err = db.Exec(`
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(10) NOT NULL DEFAULT 'member'
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to add role to users table: %w", err)
+	}
+	_, err = db.Exec(`
+		UPDATE users
+		SET role = 'admin'
+		WHERE id = (SELECT MIN(id) FROM users)
+			AND NOT EXISTS (SELECT 1 FROM users WHERE role = 'admin')
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to set the first administrator: %w", err)
+	}
+
+	// Create coach_client table if it doesn't exist, accepted_at is set when the client consents
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS coach_client (
+			coach_id INT REFERENCES users(id),
+			client_id INT REFERENCES users(id),
+			invited_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			accepted_at TIMESTAMPTZ,
+			PRIMARY KEY (coach_id, client_id)
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create coach_client table: %w", err)
+	}
+
+	// Add the deletion time to the trashable tables if it doesn't exist, the
+	// meals are shared and keep who deleted them as the owner of the trash
+	for _, table := range trashTables {
+		_, err = db.Exec(`
+			ALTER TABLE ` + table.name + ` ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to add deleted_at to %s table: %w", table.name, err)
+		}
+	}
+	_, err = db.Exec(`
+		ALTER TABLE meal ADD COLUMN IF NOT EXISTS deleted_by INT REFERENCES users(id)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to add deleted_by to meal table: %w", err)
+	}
+
+	// Add the one-time reset token to users if it doesn't exist, stored as a SHA-256 hash
+	_, err = db.Exec(`
+		ALTER TABLE users
+			ADD COLUMN IF NOT EXISTS reset_token_hash CHAR(64) UNIQUE,
+			ADD COLUMN IF NOT EXISTS reset_expires_at TIMESTAMPTZ
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to add reset token to users table: %w", err)
+	}
+
+	// Record the changes of the tables above in the audit log
+	if err := migrateAudit(db); err != nil {
+		return err
+	}
+
 	return nil
 }
 
-func CreateIMCHistory(db *sql.DB, userID int, date string, imc float64) error {
+func CreateIMCHistory(db Querier, userID int, date string, imc float64, category string) error {
 	_, err := db.Exec(`
-		INSERT INTO imc_history (user_id, date, imc)
-		VALUES ($1, $2, $3)
-	`, userID, date, imc)
+		INSERT INTO imc_history (user_id, date, imc, category)
+		VALUES ($1, $2, $3, $4)
+	`, userID, date, imc, category)
 	if err != nil {
 		return fmt.Errorf("failed to insert IMC history: %w", err)
 	}
 	return nil
 }
 
-func CreateBodyFatHistory(db *sql.DB, userID int, date string, bodyFat float64) error {
+func CreateBodyFatHistory(db Querier, userID int, date string, bodyFat float64) error {
 	_, err := db.Exec(`
 		INSERT INTO body_fat_history (user_id, date, body_fat)
 		VALUES ($1, $2, $3)
@@ -228,7 +404,7 @@ func CreateBodyFatHistory(db *sql.DB, userID int, date string, bodyFat float64)
 	return nil
 }
 
-func CreateWeightHistory(db *sql.DB, userID int, date string, weight int) error {
+func CreateWeightHistory(db Querier, userID int, date string, weight int) error {
 	_, err := db.Exec(`
 		INSERT INTO weight_history (user_id, date, weight)
 		VALUES ($1, $2, $3)
@@ -239,49 +415,271 @@ func CreateWeightHistory(db *sql.DB, userID int, date string, weight int) error
 	return nil
 }
 
-func CreateUser(db *sql.DB, firstname string, lastname string, age int, weight int, height int, targetWeight int) (int, error) {
+// CreateUser inserts a user with its credentials, the email may be empty
+func CreateUser(db Querier, username string, email string, passwordHash string, firstname string, lastname string, age int, weight int, height int, targetWeight int) (int, error) {
 	var userID int
 	err := db.QueryRow(`
-		INSERT INTO users (firstname, lastname, age, weight, height, target_weight)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO users (username, email, password_hash, firstname, lastname, age, weight, height, target_weight, role)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, $8, $9,
+			CASE WHEN EXISTS (SELECT 1 FROM users WHERE role = 'admin') THEN 'member' ELSE 'admin' END)
 		RETURNING id
-	`, firstname, lastname, age, weight, height, targetWeight).Scan(&userID)
+	`, username, email, passwordHash, firstname, lastname, age, weight, height, targetWeight).Scan(&userID)
 	if err != nil {
 		return 0, fmt.Errorf("failed to insert user: %w", err)
 	}
 	return userID, nil
 }
 
-func GetUser(db *sql.DB, userID int) (int, string, string, int, int, int, int, error) {
-	var firstname, lastname string
-	var id, age, weight, height, targetWeight int
+func GetUser(db Querier, userID int) (suser.SUser, error) {
+	var user suser.SUser
 	err := db.QueryRow(`
-		SELECT id, firstname, lastname, age, weight, height, target_weight
+		SELECT id, COALESCE(username, ''), COALESCE(email, ''), firstname, lastname, age, weight, height, target_weight, COALESCE(lang, ''), COALESCE(timezone, ''), role
 		FROM users
 		WHERE id = $1
-	`, userID).Scan(&id, &firstname, &lastname, &age, &weight, &height, &targetWeight)
+	`, userID).Scan(&user.ID, &user.Username, &user.Email, &user.Firstname, &user.Lastname, &user.Age, &user.Weight, &user.Height, &user.TargetWeight, &user.Lang, &user.Timezone, &user.Role)
 	if err != nil {
-		return 0, "", "", 0, 0, 0, 0, fmt.Errorf("failed to get user: %w", err)
+		return suser.SUser{}, fmt.Errorf("failed to get user: %w", err)
 	}
-	return id, firstname, lastname, age, weight, height, targetWeight, nil
+	return user, nil
 }
 
-func AddFoodHistory(db *sql.DB, userID int, foodID int, date string, quantity int) error {
+// FindUser returns the ID of the user with the given username or email, 0 if none matches
+func FindUser(db Querier, login string) (int, error) {
+	var userID int
+	err := db.QueryRow(`
+		SELECT id
+		FROM users
+		WHERE username = $1 OR email = $1
+	`, login).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to find user: %w", err)
+	}
+	return userID, nil
+}
+
+// GetCredentials returns the password hash and the login lockout of a user
+func GetCredentials(db Querier, userID int) (suser.Credentials, error) {
+	var credentials suser.Credentials
+	var lockedUntil sql.NullTime
+	err := db.QueryRow(`
+		SELECT id, COALESCE(username, ''), COALESCE(password_hash, ''), failed_logins, locked_until
+		FROM users
+		WHERE id = $1
+	`, userID).Scan(&credentials.UserID, &credentials.Username, &credentials.PasswordHash, &credentials.FailedLogins, &lockedUntil)
+	if err != nil {
+		return suser.Credentials{}, fmt.Errorf("failed to get credentials: %w", err)
+	}
+	credentials.LockedUntil = lockedUntil.Time
+	return credentials, nil
+}
+
+// SetCredentials sets the password hash of a user, and its username and email when they are not empty
+func SetCredentials(db Querier, userID int, username string, email string, passwordHash string) error {
 	_, err := db.Exec(`
-		INSERT INTO food_history (user_id, food_id, date, quantity)
-		VALUES ($1, $2, $3, $4)
-	`, userID, foodID, date, quantity)
+		UPDATE users
+		SET username = COALESCE(NULLIF($1, ''), username),
+			email = COALESCE(NULLIF($2, ''), email),
+			password_hash = $3
+		WHERE id = $4
+	`, username, email, passwordHash, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update credentials: %w", err)
+	}
+	return nil
+}
+
+// RecordLoginAttempt counts a login attempt of a user as failed until
+// ResetFailedLogins clears it, and locks the account for lockout once
+// maxFailures attempts in a row are reached. The count and the lockout check
+// are one statement, so that concurrent attempts cannot all pass the check
+// before any is counted. It returns the credentials to check the password
+// against, and false with the end of the lockout when the account is locked.
+func RecordLoginAttempt(db Querier, userID int, maxFailures int, lockout time.Duration) (suser.Credentials, bool, error) {
+	var credentials suser.Credentials
+	var allowed bool
+	var lockedUntil sql.NullTime
+	err := db.QueryRow(`
+		WITH attempt AS (
+			UPDATE users
+			SET failed_logins = CASE WHEN failed_logins + 1 >= $1 THEN 0 ELSE failed_logins + 1 END,
+				locked_until = CASE WHEN failed_logins + 1 >= $1 THEN NOW() + $2 * INTERVAL '1 second' ELSE NULL END
+			WHERE id = $3 AND (locked_until IS NULL OR locked_until <= NOW())
+			RETURNING id
+		)
+		SELECT id, COALESCE(username, ''), COALESCE(password_hash, ''), failed_logins, locked_until,
+			EXISTS (SELECT 1 FROM attempt)
+		FROM users
+		WHERE id = $3
+	`, maxFailures, lockout.Seconds(), userID).Scan(&credentials.UserID, &credentials.Username, &credentials.PasswordHash, &credentials.FailedLogins, &lockedUntil, &allowed)
+	if err != nil {
+		return suser.Credentials{}, false, fmt.Errorf("failed to record login attempt: %w", err)
+	}
+	credentials.LockedUntil = lockedUntil.Time
+	return credentials, allowed, nil
+}
+
+// ResetFailedLogins clears the failed logins and the lockout of a user after a successful login
+func ResetFailedLogins(db Querier, userID int) error {
+	_, err := db.Exec(`
+		UPDATE users
+		SET failed_logins = 0, locked_until = NULL
+		WHERE id = $1
+	`, userID)
 	if err != nil {
-		return fmt.Errorf("failed to insert food history: %w", err)
+		return fmt.Errorf("failed to reset failed logins: %w", err)
 	}
 	return nil
 }
 
-func GetFoodWithMeal(db *sql.DB, mealID int) ([][2]int, error) {
+// SetResetToken gives a user a reset token valid until expiresAt, replacing
+// the previous one, false when there is no such user
+func SetResetToken(db Querier, userID int, tokenHash string, expiresAt time.Time) (bool, error) {
+	result, err := db.Exec(`
+		UPDATE users
+		SET reset_token_hash = $1, reset_expires_at = $2
+		WHERE id = $3
+	`, tokenHash, expiresAt, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to set reset token: %w", err)
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to set reset token: %w", err)
+	}
+	return count > 0, nil
+}
+
+// FindResetToken returns the ID of the user of an unexpired reset token, 0 if none matches
+func FindResetToken(db Querier, tokenHash string) (int, error) {
+	var userID int
+	err := db.QueryRow(`
+		SELECT id
+		FROM users
+		WHERE reset_token_hash = $1 AND reset_expires_at > NOW()
+	`, tokenHash).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to find reset token: %w", err)
+	}
+	return userID, nil
+}
+
+// ResetCredentials sets the credentials of a user with its reset token, like
+// SetCredentials, and consumes the token and the lockout. It returns false
+// when the token is no longer valid, so that a token is only used once.
+func ResetCredentials(db Querier, userID int, tokenHash string, username string, email string, passwordHash string) (bool, error) {
+	result, err := db.Exec(`
+		UPDATE users
+		SET username = COALESCE(NULLIF($1, ''), username),
+			email = COALESCE(NULLIF($2, ''), email),
+			password_hash = $3,
+			reset_token_hash = NULL,
+			reset_expires_at = NULL,
+			failed_logins = 0,
+			locked_until = NULL
+		WHERE id = $4 AND reset_token_hash = $5 AND reset_expires_at > NOW()
+	`, username, email, passwordHash, userID, tokenHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to reset credentials: %w", err)
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to reset credentials: %w", err)
+	}
+	return count > 0, nil
+}
+
+// AddFoodHistory logs a quantity of a food, mealType and timeOfDay (HH:MM) may be empty
+func AddFoodHistory(db Querier, userID int, foodID int, date string, timeOfDay string, quantity int, mealType string) (int, error) {
+	var entryID int
+	err := db.QueryRow(`
+		INSERT INTO food_history (user_id, food_id, date, time_of_day, quantity, meal_type)
+		VALUES ($1, $2, $3, NULLIF($4, '')::TIME, $5, NULLIF($6, ''))
+		RETURNING id
+	`, userID, foodID, date, timeOfDay, quantity, mealType).Scan(&entryID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert food history: %w", err)
+	}
+	return entryID, nil
+}
+
+// UpdateFoodHistory changes an entry of the food history of a user, an empty
+// date, time of day or meal type and a zero quantity keep the current value.
+// It returns the entry as {id, foodID, date, quantity, mealType, timeOfDay},
+// false when the user has no such entry.
+func UpdateFoodHistory(db Querier, userID int, entryID int, date string, timeOfDay string, quantity int, mealType string) ([6]interface{}, bool, error) {
+	var foodID int
+	var quantityEaten float64
+	var day, meal, timeEaten string
+	err := db.QueryRow(`
+		UPDATE food_history
+		SET date = COALESCE(NULLIF($3, '')::DATE, date),
+			time_of_day = COALESCE(NULLIF($4, '')::TIME, time_of_day),
+			quantity = CASE WHEN $5 > 0 THEN $5 ELSE quantity END,
+			meal_type = COALESCE(NULLIF($6, ''), meal_type)
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+		RETURNING food_id, date, quantity, COALESCE(meal_type, ''), COALESCE(TO_CHAR(time_of_day, 'HH24:MI'), '')
+	`, entryID, userID, date, timeOfDay, quantity, mealType).Scan(&foodID, &day, &quantityEaten, &meal, &timeEaten)
+	if err == sql.ErrNoRows {
+		return [6]interface{}{}, false, nil
+	}
+	if err != nil {
+		return [6]interface{}{}, false, fmt.Errorf("failed to update food history: %w", err)
+	}
+	return [6]interface{}{entryID, foodID, day, quantityEaten, meal, timeEaten}, true, nil
+}
+
+// RestoreFoodHistory sets back the values of an entry of the food history as
+// returned by GetFoodEntry. Unlike UpdateFoodHistory every value is written,
+// an empty time of day or meal type is set back to NULL.
+func RestoreFoodHistory(db Querier, userID int, entryID int, date string, timeOfDay string, quantity float64, mealType string) (bool, error) {
+	result, err := db.Exec(`
+		UPDATE food_history
+		SET date = $3::DATE, time_of_day = NULLIF($4, '')::TIME, quantity = $5, meal_type = NULLIF($6, '')
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+	`, entryID, userID, date, timeOfDay, quantity, mealType)
+	if err != nil {
+		return false, fmt.Errorf("failed to restore food history: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to restore food history: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// GetFoodEntry returns an entry of the food history of a user as {id,
+// foodID, date, quantity, mealType, timeOfDay}, false when the user has no
+// such entry
+func GetFoodEntry(db Querier, userID int, entryID int) ([6]interface{}, bool, error) {
+	var foodID int
+	var quantity float64
+	var day, meal, timeOfDay string
+	err := db.QueryRow(`
+		SELECT food_id, date, quantity, COALESCE(meal_type, ''), COALESCE(TO_CHAR(time_of_day, 'HH24:MI'), '')
+		FROM food_history
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+	`, entryID, userID).Scan(&foodID, &day, &quantity, &meal, &timeOfDay)
+	if err == sql.ErrNoRows {
+		return [6]interface{}{}, false, nil
+	}
+	if err != nil {
+		return [6]interface{}{}, false, fmt.Errorf("failed to get food history entry: %w", err)
+	}
+	return [6]interface{}{entryID, foodID, day, quantity, meal, timeOfDay}, true, nil
+}
+
+func GetFoodWithMeal(db Querier, mealID int) ([][2]int, error) {
 	rows, err := db.Query(`
-		SELECT food_id, quantity
-		FROM meal_food
-		WHERE meal_id = $1
+		SELECT mf.food_id, mf.quantity
+		FROM meal_food mf
+		JOIN meal m ON m.id = mf.meal_id
+		WHERE mf.meal_id = $1 AND m.deleted_at IS NULL
 	`, mealID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get food with meal: %w", err)
@@ -301,12 +699,16 @@ func GetFoodWithMeal(db *sql.DB, mealID int) ([][2]int, error) {
 	return foods, nil
 }
 
-func GetMealWithDayPreset(db *sql.DB, dayPresetID int) ([][2]int, error) {
+// GetMealWithDayPreset returns the meals of a day preset of a user and their
+// servings, none when the user has no such day preset
+func GetMealWithDayPreset(db Querier, userID int, dayPresetID int) ([][2]int, error) {
 	rows, err := db.Query(`
-		SELECT meal_id, quantity
-		FROM day_preset_meal
-		WHERE day_preset_id = $1
-	`, dayPresetID)
+		SELECT dm.meal_id, dm.quantity
+		FROM day_preset_meal dm
+		JOIN day_preset d ON d.id = dm.day_preset_id
+		JOIN meal m ON m.id = dm.meal_id
+		WHERE dm.day_preset_id = $1 AND d.user_id = $2 AND d.deleted_at IS NULL AND m.deleted_at IS NULL
+	`, dayPresetID, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get meal with day preset: %w", err)
 	}
@@ -325,7 +727,7 @@ func GetMealWithDayPreset(db *sql.DB, dayPresetID int) ([][2]int, error) {
 	return meals, nil
 }
 
-func CreateMeal(db *sql.DB, name string, mealType string) (int, error) {
+func CreateMeal(db Querier, name string, mealType string) (int, error) {
 	var mealID int
 	err := db.QueryRow(`
 		INSERT INTO meal (name, type)
@@ -338,7 +740,7 @@ func CreateMeal(db *sql.DB, name string, mealType string) (int, error) {
 	return mealID, nil
 }
 
-func CreateDayPreset(db *sql.DB, userID int, name string) (int, error) {
+func CreateDayPreset(db Querier, userID int, name string) (int, error) {
 	var dayPresetID int
 	err := db.QueryRow(`
 		INSERT INTO day_preset (user_id, name)
@@ -351,53 +753,107 @@ func CreateDayPreset(db *sql.DB, userID int, name string) (int, error) {
 	return dayPresetID, nil
 }
 
-func GetAllMeals(db *sql.DB) ([]string, error) {
+func GetAllMeals(db Querier) ([]suser.Meal, error) {
 	rows, err := db.Query(`
 		SELECT id, name, type
 		FROM meal
+		WHERE deleted_at IS NULL
 	`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all meals: %w", err)
 	}
 	defer rows.Close()
 
-	var meals []string
+	var meals []suser.Meal
 	for rows.Next() {
-		var id int
-		var name, mealType string
-		if err := rows.Scan(&id, &name, &mealType); err != nil {
+		var meal suser.Meal
+		if err := rows.Scan(&meal.ID, &meal.Name, &meal.Type); err != nil {
 			return nil, fmt.Errorf("failed to scan meal: %w", err)
 		}
-		meals = append(meals, fmt.Sprintf("ID: %d | Name: %s | Type: %s", id, name, mealType))
+		meals = append(meals, meal)
 	}
 
 	return meals, nil
 }
 
-func GetAllDays(db *sql.DB) ([]string, error) {
+func GetMeal(db Querier, mealID int) (suser.Meal, error) {
+	var meal suser.Meal
+	err := db.QueryRow(`
+		SELECT id, name, COALESCE(type, '')
+		FROM meal
+		WHERE id = $1 AND deleted_at IS NULL
+	`, mealID).Scan(&meal.ID, &meal.Name, &meal.Type)
+	if err == sql.ErrNoRows {
+		return meal, fmt.Errorf("meal %d not found", mealID)
+	}
+	if err != nil {
+		return meal, fmt.Errorf("failed to get meal: %w", err)
+	}
+	return meal, nil
+}
+
+// GetAllDays returns the day presets of a user
+func GetAllDays(db Querier, userID int) ([]suser.DayPreset, error) {
 	rows, err := db.Query(`
-		SELECT id, name
+		SELECT id, user_id, name
 		FROM day_preset
-	`)
+		WHERE user_id = $1 AND deleted_at IS NULL
+		ORDER BY id
+	`, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all day presets: %w", err)
 	}
 	defer rows.Close()
 
-	var dayPresets []string
+	var dayPresets []suser.DayPreset
 	for rows.Next() {
-		var id int
-		var name string
-		if err := rows.Scan(&id, &name); err != nil {
+		var dayPreset suser.DayPreset
+		if err := rows.Scan(&dayPreset.ID, &dayPreset.UserID, &dayPreset.Name); err != nil {
 			return nil, fmt.Errorf("failed to scan day preset: %w", err)
 		}
-		dayPresets = append(dayPresets, fmt.Sprintf("ID: %d | Name: %s", id, name))
+		dayPresets = append(dayPresets, dayPreset)
 	}
 
 	return dayPresets, nil
 }
 
-func LinkFoodToMeal(db *sql.DB, foodID int, mealID int, quantity int) error {
+// DeleteMeal moves a meal to the trash of the user deleting it, false when
+// there is no such meal
+func DeleteMeal(db Querier, userID int, mealID int) (bool, error) {
+	result, err := db.Exec(`
+		UPDATE meal
+		SET deleted_at = NOW(), deleted_by = $2
+		WHERE id = $1 AND deleted_at IS NULL
+	`, mealID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete meal: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete meal: %w", err)
+	}
+	return deleted > 0, nil
+}
+
+// DeleteDayPreset moves a day preset of a user to the trash, false when the
+// user has no such day preset
+func DeleteDayPreset(db Querier, userID int, dayPresetID int) (bool, error) {
+	result, err := db.Exec(`
+		UPDATE day_preset
+		SET deleted_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+	`, dayPresetID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete day preset: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete day preset: %w", err)
+	}
+	return deleted > 0, nil
+}
+
+func LinkFoodToMeal(db Querier, foodID int, mealID int, quantity int) error {
 	_, err := db.Exec(`
 		INSERT INTO meal_food (meal_id, food_id, quantity)
 		VALUES ($1, $2, $3)
@@ -408,44 +864,113 @@ func LinkFoodToMeal(db *sql.DB, foodID int, mealID int, quantity int) error {
 	return nil
 }
 
-func LinkMealToDayPreset(db *sql.DB, mealID int, dayPresetID int, quantity int) error {
-	_, err := db.Exec(`
+// LinkMealToDayPreset adds servings of a meal to a day preset of a user,
+// false when the user has no such day preset
+func LinkMealToDayPreset(db Querier, userID int, mealID int, dayPresetID int, quantity int) (bool, error) {
+	result, err := db.Exec(`
 		INSERT INTO day_preset_meal (day_preset_id, meal_id, quantity)
-		VALUES ($1, $2, $3)
-	`, dayPresetID, mealID, quantity)
+		SELECT id, $2, $3
+		FROM day_preset
+		WHERE id = $1 AND user_id = $4 AND deleted_at IS NULL
+	`, dayPresetID, mealID, quantity, userID)
 	if err != nil {
-		return fmt.Errorf("failed to link meal to day preset: %w", err)
+		return false, fmt.Errorf("failed to link meal to day preset: %w", err)
 	}
-	return nil
+	linked, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to link meal to day preset: %w", err)
+	}
+	return linked > 0, nil
 }
 
-func GetFoodHistory(db *sql.DB, userID int) ([][4]interface{}, error) {
+// GetFoodHistory returns the foods logged by a user: food ID, date, quantity,
+// entry ID and time of day (HH:MM, empty when unknown)
+func GetFoodHistory(db Querier, userID int) ([][5]interface{}, error) {
 	rows, err := db.Query(`
-		SELECT food_id, date, quantity, id
+		SELECT food_id, date, quantity, id, COALESCE(TO_CHAR(time_of_day, 'HH24:MI'), '')
 		FROM food_history
-		WHERE user_id = $1
+		WHERE user_id = $1 AND deleted_at IS NULL
+		ORDER BY date, time_of_day NULLS LAST, id
 	`, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get food history: %w", err)
 	}
 	defer rows.Close()
 
-	var foodHistory [][4]interface{}
+	var foodHistory [][5]interface{}
 	for rows.Next() {
 		var foodID int
-		var date string
+		var date, timeOfDay string
 		var quantity float64
 		var entryID int
-		if err := rows.Scan(&foodID, &date, &quantity, &entryID); err != nil {
+		if err := rows.Scan(&foodID, &date, &quantity, &entryID, &timeOfDay); err != nil {
 			return nil, fmt.Errorf("failed to scan food history: %w", err)
 		}
-		foodHistory = append(foodHistory, [4]interface{}{foodID, date, quantity, entryID})
+		foodHistory = append(foodHistory, [5]interface{}{foodID, date, quantity, entryID, timeOfDay})
 	}
 
 	return foodHistory, nil
 }
 
-func GetWeightHistory(db *sql.DB, userID int) ([][3]interface{}, error) {
+// GetFoodQuantitiesByDay returns the grams eaten per food for each day between from and to (inclusive)
+func GetFoodQuantitiesByDay(db Querier, userID int, from string, to string) (map[string]map[int]float64, error) {
+	rows, err := db.Query(`
+		SELECT to_char(date, 'YYYY-MM-DD'), food_id, SUM(quantity)
+		FROM food_history
+		WHERE user_id = $1 AND date BETWEEN $2 AND $3 AND deleted_at IS NULL
+		GROUP BY date, food_id
+	`, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get food quantities by day: %w", err)
+	}
+	defer rows.Close()
+
+	days := make(map[string]map[int]float64)
+	for rows.Next() {
+		var date string
+		var foodID int
+		var quantity float64
+		if err := rows.Scan(&date, &foodID, &quantity); err != nil {
+			return nil, fmt.Errorf("failed to scan food quantity: %w", err)
+		}
+		if days[date] == nil {
+			days[date] = make(map[int]float64)
+		}
+		days[date][foodID] = quantity
+	}
+
+	return days, nil
+}
+
+// GetFoodDay returns the foods logged by the user on a date: entry ID, food
+// ID, quantity, meal type and time of day (HH:MM, empty when unknown)
+func GetFoodDay(db Querier, userID int, date string) ([][5]interface{}, error) {
+	rows, err := db.Query(`
+		SELECT id, food_id, quantity, COALESCE(meal_type, ''), COALESCE(TO_CHAR(time_of_day, 'HH24:MI'), '')
+		FROM food_history
+		WHERE user_id = $1 AND date = $2 AND deleted_at IS NULL
+		ORDER BY time_of_day NULLS LAST, id
+	`, userID, date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get food history: %w", err)
+	}
+	defer rows.Close()
+
+	var foods [][5]interface{}
+	for rows.Next() {
+		var entryID, foodID int
+		var quantity float64
+		var mealType, timeOfDay string
+		if err := rows.Scan(&entryID, &foodID, &quantity, &mealType, &timeOfDay); err != nil {
+			return nil, fmt.Errorf("failed to scan food history: %w", err)
+		}
+		foods = append(foods, [5]interface{}{entryID, foodID, quantity, mealType, timeOfDay})
+	}
+
+	return foods, nil
+}
+
+func GetWeightHistory(db Querier, userID int) ([][3]interface{}, error) {
 	rows, err := db.Query(`
 		SELECT date, weight
 		FROM weight_history
@@ -469,7 +994,35 @@ func GetWeightHistory(db *sql.DB, userID int) ([][3]interface{}, error) {
 	return weightHistory, nil
 }
 
-func GetBodyFatHistory(db *sql.DB, userID int) ([][3]interface{}, error) {
+// GetWeightSeries returns the weigh-ins of a user ordered by date
+func GetWeightSeries(db Querier, userID int) ([]time.Time, []float64, error) {
+	rows, err := db.Query(`
+		SELECT date, weight
+		FROM weight_history
+		WHERE user_id = $1
+		ORDER BY date, id
+	`, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get weight series: %w", err)
+	}
+	defer rows.Close()
+
+	var dates []time.Time
+	var weights []float64
+	for rows.Next() {
+		var date time.Time
+		var weight float64
+		if err := rows.Scan(&date, &weight); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan weight series: %w", err)
+		}
+		dates = append(dates, date)
+		weights = append(weights, weight)
+	}
+
+	return dates, weights, nil
+}
+
+func GetBodyFatHistory(db Querier, userID int) ([][3]interface{}, error) {
 	rows, err := db.Query(`
 		SELECT date, body_fat
 		FROM body_fat_history
@@ -493,9 +1046,9 @@ func GetBodyFatHistory(db *sql.DB, userID int) ([][3]interface{}, error) {
 	return bodyFatHistory, nil
 }
 
-func GetIMCHistory(db *sql.DB, userID int) ([][3]interface{}, error) {
+func GetIMCHistory(db Querier, userID int) ([][3]interface{}, error) {
 	rows, err := db.Query(`
-		SELECT date, imc
+		SELECT date, imc, COALESCE(category, '')
 		FROM imc_history
 		WHERE user_id = $1
 	`, userID)
@@ -508,27 +1061,35 @@ func GetIMCHistory(db *sql.DB, userID int) ([][3]interface{}, error) {
 	for rows.Next() {
 		var date string
 		var imc float64
-		if err := rows.Scan(&date, &imc); err != nil {
+		var category string
+		if err := rows.Scan(&date, &imc, &category); err != nil {
 			return nil, fmt.Errorf("failed to scan IMC history: %w", err)
 		}
-		imcHistory = append(imcHistory, [3]interface{}{date, imc})
+		imcHistory = append(imcHistory, [3]interface{}{date, imc, category})
 	}
 
 	return imcHistory, nil
 }
 
-func DeleteFoodHistory(db *sql.DB, entryID int) error {
-	_, err := db.Exec(`
-		DELETE FROM food_history
-		WHERE id = $1
-	`, entryID)
+// DeleteFoodHistory moves an entry of the food history of a user to the
+// trash, false when the user has no such entry
+func DeleteFoodHistory(db Querier, userID int, entryID int) (bool, error) {
+	result, err := db.Exec(`
+		UPDATE food_history
+		SET deleted_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+	`, entryID, userID)
 	if err != nil {
-		return fmt.Errorf("failed to delete food history: %w", err)
+		return false, fmt.Errorf("failed to delete food history: %w", err)
 	}
-	return nil
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete food history: %w", err)
+	}
+	return deleted > 0, nil
 }
 
-func UpdateUserFirstname(db *sql.DB, userID int, firstname string) error {
+func UpdateUserFirstname(db Querier, userID int, firstname string) error {
 	_, err := db.Exec(`
 		UPDATE users
 		SET firstname = $1
@@ -539,7 +1100,7 @@ func UpdateUserFirstname(db *sql.DB, userID int, firstname string) error {
 	}
 	return nil
 }
-func UpdateUserLastname(db *sql.DB, userID int, lastname string) error {
+func UpdateUserLastname(db Querier, userID int, lastname string) error {
 	_, err := db.Exec(`
 		UPDATE users
 		SET lastname = $1
@@ -550,7 +1111,7 @@ func UpdateUserLastname(db *sql.DB, userID int, lastname string) error {
 	}
 	return nil
 }
-func UpdateUserAge(db *sql.DB, userID int, age int) error {
+func UpdateUserAge(db Querier, userID int, age int) error {
 	_, err := db.Exec(`
 		UPDATE users
 		SET age = $1
@@ -561,7 +1122,7 @@ func UpdateUserAge(db *sql.DB, userID int, age int) error {
 	}
 	return nil
 }
-func UpdateUserWeight(db *sql.DB, userID int, weight int) error {
+func UpdateUserWeight(db Querier, userID int, weight int) error {
 	_, err := db.Exec(`
 		UPDATE users
 		SET weight = $1
@@ -572,7 +1133,7 @@ func UpdateUserWeight(db *sql.DB, userID int, weight int) error {
 	}
 	return nil
 }
-func UpdateUserHeight(db *sql.DB, userID int, height int) error {
+func UpdateUserHeight(db Querier, userID int, height int) error {
 	_, err := db.Exec(`
 		UPDATE users
 		SET height = $1
@@ -583,7 +1144,7 @@ func UpdateUserHeight(db *sql.DB, userID int, height int) error {
 	}
 	return nil
 }
-func UpdateUserTargetWeight(db *sql.DB, userID int, targetWeight int) error {
+func UpdateUserTargetWeight(db Querier, userID int, targetWeight int) error {
 	_, err := db.Exec(`
 		UPDATE users
 		SET target_weight = $1
@@ -593,4 +1154,509 @@ func UpdateUserTargetWeight(db *sql.DB, userID int, targetWeight int) error {
 		return fmt.Errorf("failed to update user target weight: %w", err)
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+// UpdateUserLang updates the preferred language of a user, empty to use the default
+func UpdateUserLang(db Querier, userID int, lang string) error {
+	_, err := db.Exec(`
+		UPDATE users
+		SET lang = NULLIF($1, '')
+		WHERE id = $2
+	`, lang, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update user language: %w", err)
+	}
+	return nil
+}
+
+// UpdateUserTimezone sets the IANA time zone of a user, empty for the local zone of the host
+func UpdateUserTimezone(db Querier, userID int, timezone string) error {
+	_, err := db.Exec(`
+		UPDATE users
+		SET timezone = NULLIF($1, '')
+		WHERE id = $2
+	`, timezone, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update user timezone: %w", err)
+	}
+	return nil
+}
+
+// SetTargetCalories updates the calories of the latest target of a user, creating one if needed
+func SetTargetCalories(db Querier, userID int, date string, calories float64) error {
+	return SetTarget(db, userID, date, "calories", calories)
+}
+
+// TargetColumns are the nutrient columns of the target table, calories in kcal,
+// cholesterol, sodium, calcium, iron and potassium in mg and the others in g
+var TargetColumns = []string{
+	"calories", "protein", "carbohydrates", "fat", "saturated_fat", "trans_fat", "cholesterol",
+	"sodium", "fiber", "sugars", "calcium", "iron", "potassium",
+}
+
+func isTargetColumn(column string) bool {
+	for _, c := range TargetColumns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+// targetLock is the class of the advisory locks taken on the targets of a user
+const targetLock = 1
+
+// SetTarget sets a nutrient of the latest target of the user, or creates a
+// target. It takes an advisory lock held until the end of the transaction of
+// db, the store runs it in one.
+func SetTarget(db Querier, userID int, date string, column string, amount float64) error {
+	// The column name cannot be a query parameter, only known columns are accepted
+	if !isTargetColumn(column) {
+		return fmt.Errorf("unknown target nutrient '%s'", column)
+	}
+	// Two sessions setting the first target of a user at once must not both
+	// insert one, the updates of a user wait for each other
+	if _, err := db.Exec(`SELECT pg_advisory_xact_lock($1, $2)`, targetLock, userID); err != nil {
+		return fmt.Errorf("failed to lock targets: %w", err)
+	}
+	result, err := db.Exec(`
+		UPDATE target
+		SET `+column+` = $1
+		WHERE id = (
+			SELECT id FROM target
+			WHERE user_id = $2
+			ORDER BY date DESC, id DESC
+			LIMIT 1
+		)
+	`, amount, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update target %s: %w", column, err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update target %s: %w", column, err)
+	}
+	if updated == 0 {
+		_, err = db.Exec(`
+			INSERT INTO target (user_id, date, `+column+`)
+			VALUES ($1, $2, $3)
+		`, userID, date, amount)
+		if err != nil {
+			return fmt.Errorf("failed to insert target %s: %w", column, err)
+		}
+	}
+	return nil
+}
+
+// GetTargets returns the nutrients set in the latest target of the user, keyed by column
+func GetTargets(db Querier, userID int) (map[string]float64, error) {
+	values := make([]sql.NullFloat64, len(TargetColumns))
+	dest := make([]interface{}, len(TargetColumns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	err := db.QueryRow(`
+		SELECT `+strings.Join(TargetColumns, ", ")+`
+		FROM target
+		WHERE user_id = $1
+		ORDER BY date DESC, id DESC
+		LIMIT 1
+	`, userID).Scan(dest...)
+	targets := make(map[string]float64)
+	if err == sql.ErrNoRows {
+		return targets, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get targets: %w", err)
+	}
+	for i, value := range values {
+		if value.Valid {
+			targets[TargetColumns[i]] = value.Float64
+		}
+	}
+	return targets, nil
+}
+
+// GetTargetCalories returns the calories of the latest target of a user, 0 if none is set
+func GetTargetCalories(db Querier, userID int) (float64, error) {
+	var calories float64
+	err := db.QueryRow(`
+		SELECT calories
+		FROM target
+		WHERE user_id = $1 AND calories IS NOT NULL
+		ORDER BY date DESC, id DESC
+		LIMIT 1
+	`, userID).Scan(&calories)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get target calories: %w", err)
+	}
+	return calories, nil
+}
+
+func AddExerciseHistory(db Querier, userID int, date string, activity string, minutes int, intensity string, calories float64) error {
+	_, err := db.Exec(`
+		INSERT INTO exercise_history (user_id, date, activity, minutes, intensity, calories)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, userID, date, activity, minutes, intensity, calories)
+	if err != nil {
+		return fmt.Errorf("failed to insert exercise history: %w", err)
+	}
+	return nil
+}
+
+func GetExerciseHistory(db Querier, userID int) ([][6]interface{}, error) {
+	rows, err := db.Query(`
+		SELECT date, activity, minutes, intensity, calories, id
+		FROM exercise_history
+		WHERE user_id = $1
+		ORDER BY date, id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exercise history: %w", err)
+	}
+	defer rows.Close()
+
+	var exerciseHistory [][6]interface{}
+	for rows.Next() {
+		var date, activity, intensity string
+		var minutes, entryID int
+		var calories float64
+		if err := rows.Scan(&date, &activity, &minutes, &intensity, &calories, &entryID); err != nil {
+			return nil, fmt.Errorf("failed to scan exercise history: %w", err)
+		}
+		exerciseHistory = append(exerciseHistory, [6]interface{}{date, activity, minutes, intensity, calories, entryID})
+	}
+
+	return exerciseHistory, nil
+}
+
+// GetExerciseTotals returns the minutes of exercise and kcal burned by a user on a day
+func GetExerciseTotals(db Querier, userID int, date string) (int, float64, error) {
+	var minutes int
+	var calories float64
+	err := db.QueryRow(`
+		SELECT COALESCE(SUM(minutes), 0), COALESCE(SUM(calories), 0)
+		FROM exercise_history
+		WHERE user_id = $1 AND date = $2
+	`, userID, date).Scan(&minutes, &calories)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get exercise totals: %w", err)
+	}
+	return minutes, calories, nil
+}
+
+func AddWaterHistory(db Querier, userID int, date string, amount int) error {
+	_, err := db.Exec(`
+		INSERT INTO water_history (user_id, date, amount)
+		VALUES ($1, $2, $3)
+	`, userID, date, amount)
+	if err != nil {
+		return fmt.Errorf("failed to insert water history: %w", err)
+	}
+	return nil
+}
+
+func GetWaterHistory(db Querier, userID int) ([][3]interface{}, error) {
+	rows, err := db.Query(`
+		SELECT date, SUM(amount)
+		FROM water_history
+		WHERE user_id = $1
+		GROUP BY date
+		ORDER BY date
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get water history: %w", err)
+	}
+	defer rows.Close()
+
+	var waterHistory [][3]interface{}
+	for rows.Next() {
+		var date string
+		var amount int
+		if err := rows.Scan(&date, &amount); err != nil {
+			return nil, fmt.Errorf("failed to scan water history: %w", err)
+		}
+		waterHistory = append(waterHistory, [3]interface{}{date, amount})
+	}
+
+	return waterHistory, nil
+}
+
+// GetWaterTotal returns the ml of water logged by a user on a day
+func GetWaterTotal(db Querier, userID int, date string) (int, error) {
+	var amount int
+	err := db.QueryRow(`
+		SELECT COALESCE(SUM(amount), 0)
+		FROM water_history
+		WHERE user_id = $1 AND date = $2
+	`, userID, date).Scan(&amount)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get water total: %w", err)
+	}
+	return amount, nil
+}
+
+// SetAlias creates or replaces an alias of a user
+func SetAlias(db Querier, userID int, name string, definition string) error {
+	_, err := db.Exec(`
+		INSERT INTO alias (user_id, name, definition)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, name) DO UPDATE SET definition = EXCLUDED.definition
+	`, userID, name, definition)
+	if err != nil {
+		return fmt.Errorf("failed to set alias: %w", err)
+	}
+	return nil
+}
+
+// GetAliases returns the name and definition of the aliases of a user, by name
+func GetAliases(db Querier, userID int) ([][2]string, error) {
+	rows, err := db.Query(`
+		SELECT name, definition
+		FROM alias
+		WHERE user_id = $1
+		ORDER BY name
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get aliases: %w", err)
+	}
+	defer rows.Close()
+
+	var aliases [][2]string
+	for rows.Next() {
+		var name, definition string
+		if err := rows.Scan(&name, &definition); err != nil {
+			return nil, fmt.Errorf("failed to scan alias: %w", err)
+		}
+		aliases = append(aliases, [2]string{name, definition})
+	}
+
+	return aliases, nil
+}
+
+// DeleteAlias removes an alias of a user and reports whether it existed
+func DeleteAlias(db Querier, userID int, name string) (bool, error) {
+	result, err := db.Exec(`
+		DELETE FROM alias
+		WHERE user_id = $1 AND name = $2
+	`, userID, name)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete alias: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete alias: %w", err)
+	}
+	return deleted > 0, nil
+}
+
+// CreateAPIToken saves the hash of a token giving access to the API as a
+// user with the given scopes until expiresAt, and deletes the expired tokens
+// of the user. It returns the ID of the token.
+func CreateAPIToken(db Querier, userID int, name string, tokenHash string, scopes []string, expiresAt time.Time) (int, error) {
+	_, err := db.Exec(`
+		DELETE FROM api_token
+		WHERE user_id = $1 AND expires_at < NOW()
+	`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired API tokens: %w", err)
+	}
+	var id int
+	err = db.QueryRow(`
+		INSERT INTO api_token (user_id, name, token_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, userID, name, tokenHash, strings.Join(scopes, ","), expiresAt).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert API token: %w", err)
+	}
+	return id, nil
+}
+
+// scanAPIToken reads a row of api_token
+func scanAPIToken(row interface{ Scan(...interface{}) error }) (suser.APIToken, error) {
+	var token suser.APIToken
+	var scopes string
+	var lastUsedAt sql.NullTime
+	err := row.Scan(&token.ID, &token.UserID, &token.Name, &scopes, &token.CreatedAt, &token.ExpiresAt, &lastUsedAt)
+	if err != nil {
+		return suser.APIToken{}, err
+	}
+	token.Scopes = strings.Split(scopes, ",")
+	token.LastUsedAt = lastUsedAt.Time
+	return token, nil
+}
+
+// UseAPIToken returns the token with the given hash and records that it was
+// used, false when there is none
+func UseAPIToken(db Querier, tokenHash string) (suser.APIToken, bool, error) {
+	token, err := scanAPIToken(db.QueryRow(`
+		UPDATE api_token
+		SET last_used_at = NOW()
+		WHERE token_hash = $1
+		RETURNING id, user_id, name, scopes, created_at, expires_at, last_used_at
+	`, tokenHash))
+	if err == sql.ErrNoRows {
+		return suser.APIToken{}, false, nil
+	}
+	if err != nil {
+		return suser.APIToken{}, false, fmt.Errorf("failed to get API token: %w", err)
+	}
+	return token, true, nil
+}
+
+// GetAPITokens returns the tokens of a user, the newest first
+func GetAPITokens(db Querier, userID int) ([]suser.APIToken, error) {
+	rows, err := db.Query(`
+		SELECT id, user_id, name, scopes, created_at, expires_at, last_used_at
+		FROM api_token
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []suser.APIToken
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API token: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// RevokeAPIToken deletes a token of a user, false when the user has no token with that ID
+func RevokeAPIToken(db Querier, userID int, tokenID int) (bool, error) {
+	result, err := db.Exec(`
+		DELETE FROM api_token
+		WHERE user_id = $1 AND id = $2
+	`, userID, tokenID)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke API token: %w", err)
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke API token: %w", err)
+	}
+	return count > 0, nil
+}
+
+// DeleteAPIToken revokes the token with the given hash
+func DeleteAPIToken(db Querier, tokenHash string) error {
+	_, err := db.Exec(`
+		DELETE FROM api_token
+		WHERE token_hash = $1
+	`, tokenHash)
+	if err != nil {
+		return fmt.Errorf("failed to delete API token: %w", err)
+	}
+	return nil
+}
+
+// AddSSHKey saves a public key of a user, the fingerprint is its SHA-256
+// fingerprint and publicKey its authorized_keys form. The second result is
+// false when the key is already saved.
+func AddSSHKey(db Querier, userID int, name string, fingerprint string, publicKey string) (int, bool, error) {
+	var id int
+	err := db.QueryRow(`
+		INSERT INTO ssh_key (user_id, name, fingerprint, public_key)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (fingerprint) DO NOTHING
+		RETURNING id
+	`, userID, name, fingerprint, publicKey).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to insert SSH key: %w", err)
+	}
+	return id, true, nil
+}
+
+// scanSSHKey reads a row of ssh_key
+func scanSSHKey(row interface{ Scan(...interface{}) error }) (suser.SSHKey, error) {
+	var key suser.SSHKey
+	var lastUsedAt sql.NullTime
+	err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Fingerprint, &key.PublicKey, &key.CreatedAt, &lastUsedAt)
+	if err != nil {
+		return suser.SSHKey{}, err
+	}
+	key.LastUsedAt = lastUsedAt.Time
+	return key, nil
+}
+
+// FindSSHKey returns the key with the given fingerprint, false when there is none
+func FindSSHKey(db Querier, fingerprint string) (suser.SSHKey, bool, error) {
+	key, err := scanSSHKey(db.QueryRow(`
+		SELECT id, user_id, name, fingerprint, public_key, created_at, last_used_at
+		FROM ssh_key
+		WHERE fingerprint = $1
+	`, fingerprint))
+	if err == sql.ErrNoRows {
+		return suser.SSHKey{}, false, nil
+	}
+	if err != nil {
+		return suser.SSHKey{}, false, fmt.Errorf("failed to get SSH key: %w", err)
+	}
+	return key, true, nil
+}
+
+// UseSSHKey records that a key logged in its user
+func UseSSHKey(db Querier, keyID int) error {
+	_, err := db.Exec(`
+		UPDATE ssh_key
+		SET last_used_at = NOW()
+		WHERE id = $1
+	`, keyID)
+	if err != nil {
+		return fmt.Errorf("failed to update SSH key: %w", err)
+	}
+	return nil
+}
+
+// GetSSHKeys returns the keys of a user, the oldest first
+func GetSSHKeys(db Querier, userID int) ([]suser.SSHKey, error) {
+	rows, err := db.Query(`
+		SELECT id, user_id, name, fingerprint, public_key, created_at, last_used_at
+		FROM ssh_key
+		WHERE user_id = $1
+		ORDER BY created_at, id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SSH keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []suser.SSHKey
+	for rows.Next() {
+		key, err := scanSSHKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan SSH key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// DeleteSSHKey deletes a key of a user, false when the user has no key with that ID
+func DeleteSSHKey(db Querier, userID int, keyID int) (bool, error) {
+	result, err := db.Exec(`
+		DELETE FROM ssh_key
+		WHERE user_id = $1 AND id = $2
+	`, userID, keyID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete SSH key: %w", err)
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete SSH key: %w", err)
+	}
+	return count > 0, nil
+}
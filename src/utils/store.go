@@ -0,0 +1,746 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	suser "gotracker/structs"
+	"strconv"
+	"time"
+)
+
+// Store gives access to the data layer through a database connection. It is
+// safe for concurrent use by the sessions, the connection pool of database/sql
+// runs their queries in parallel.
+//
+// A store returned by As acts for a user and checks with CheckAccess that the
+// user may read or change the data of the user ID of each call. The store of
+// NewStore is not bound to a user and only serves the authentication, which
+// finds the user from its credentials, token or SSH key.
+//
+// The changes run in transactions telling the triggers of the audit log the
+// user and the session they are made for.
+type Store struct {
+	db      *sql.DB
+	actor   int
+	session string
+}
+
+// NewStore wraps a database connection in a Store
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// As returns a store acting for the user actorID in the session sessionID,
+// over the same connection
+func (s *Store) As(actorID int, sessionID string) *Store {
+	return &Store{db: s.db, actor: actorID, session: sessionID}
+}
+
+// check returns ErrForbidden unless the actor of the store may access the data of userID
+func (s *Store) check(userID int, access Access) error {
+	if s.actor == 0 {
+		return nil
+	}
+	return CheckAccess(s.db, s.actor, userID, access)
+}
+
+// write runs a change in a transaction, in which the audit triggers read the
+// actor and the session of the store
+func (s *Store) write(change func(db Querier) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	actor := ""
+	if s.actor != 0 {
+		actor = strconv.Itoa(s.actor)
+	}
+	_, err = tx.Exec(`SELECT set_config('gotracker.actor', $1, true), set_config('gotracker.session', $2, true)`, actor, s.session)
+	if err != nil {
+		return fmt.Errorf("failed to set the audit context: %w", err)
+	}
+	if err := change(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// writeValue runs a change returning a value with write
+func writeValue[T any](s *Store, change func(db Querier) (T, error)) (T, error) {
+	var value T
+	err := s.write(func(db Querier) (err error) {
+		value, err = change(db)
+		return err
+	})
+	return value, err
+}
+
+// Close closes the database connection
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// GetUser returns the user with the given ID
+func (s *Store) GetUser(userID int) (*suser.SUser, error) {
+	if err := s.check(userID, ReadAccess); err != nil {
+		return nil, err
+	}
+	user, err := GetUser(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (s *Store) FindUser(login string) (int, error) {
+	return FindUser(s.db, login)
+}
+
+func (s *Store) GetCredentials(userID int) (suser.Credentials, error) {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return suser.Credentials{}, err
+	}
+	return GetCredentials(s.db, userID)
+}
+
+func (s *Store) SetCredentials(userID int, username string, email string, passwordHash string) error {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return err
+	}
+	return s.write(func(db Querier) error {
+		return SetCredentials(db, userID, username, email, passwordHash)
+	})
+}
+
+func (s *Store) RecordLoginAttempt(userID int, maxFailures int, lockout time.Duration) (suser.Credentials, bool, error) {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return suser.Credentials{}, false, err
+	}
+	var credentials suser.Credentials
+	var allowed bool
+	err := s.write(func(db Querier) (err error) {
+		credentials, allowed, err = RecordLoginAttempt(db, userID, maxFailures, lockout)
+		return err
+	})
+	return credentials, allowed, err
+}
+
+func (s *Store) ResetFailedLogins(userID int) error {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return err
+	}
+	return s.write(func(db Querier) error {
+		return ResetFailedLogins(db, userID)
+	})
+}
+
+// SetResetToken may be called by the administrators and by the host CLI
+func (s *Store) SetResetToken(userID int, tokenHash string, expiresAt time.Time) (bool, error) {
+	if err := s.check(userID, AdminAccess); err != nil {
+		return false, err
+	}
+	return writeValue(s, func(db Querier) (bool, error) {
+		return SetResetToken(db, userID, tokenHash, expiresAt)
+	})
+}
+
+func (s *Store) FindResetToken(tokenHash string) (int, error) {
+	return FindResetToken(s.db, tokenHash)
+}
+
+// ResetCredentials is allowed by the reset token itself, which only the user has
+func (s *Store) ResetCredentials(userID int, tokenHash string, username string, email string, passwordHash string) (bool, error) {
+	return writeValue(s, func(db Querier) (bool, error) {
+		return ResetCredentials(db, userID, tokenHash, username, email, passwordHash)
+	})
+}
+
+func (s *Store) CreateIMCHistory(userID int, date string, imc float64, category string) error {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return err
+	}
+	return s.write(func(db Querier) error {
+		return CreateIMCHistory(db, userID, date, imc, category)
+	})
+}
+
+func (s *Store) CreateBodyFatHistory(userID int, date string, bodyFat float64) error {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return err
+	}
+	return s.write(func(db Querier) error {
+		return CreateBodyFatHistory(db, userID, date, bodyFat)
+	})
+}
+
+func (s *Store) CreateWeightHistory(userID int, date string, weight int) error {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return err
+	}
+	return s.write(func(db Querier) error {
+		return CreateWeightHistory(db, userID, date, weight)
+	})
+}
+
+func (s *Store) CreateUser(username string, email string, passwordHash string, firstname string, lastname string, age int, weight int, height int, targetWeight int) (int, error) {
+	return writeValue(s, func(db Querier) (int, error) {
+		return CreateUser(db, username, email, passwordHash, firstname, lastname, age, weight, height, targetWeight)
+	})
+}
+
+func (s *Store) AddFoodHistory(userID int, foodID int, date string, timeOfDay string, quantity int, mealType string) (int, error) {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return 0, err
+	}
+	return writeValue(s, func(db Querier) (int, error) {
+		return AddFoodHistory(db, userID, foodID, date, timeOfDay, quantity, mealType)
+	})
+}
+
+func (s *Store) UpdateFoodHistory(userID int, entryID int, date string, timeOfDay string, quantity int, mealType string) ([6]interface{}, bool, error) {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return [6]interface{}{}, false, err
+	}
+	var value [6]interface{}
+	var found bool
+	err := s.write(func(db Querier) (err error) {
+		value, found, err = UpdateFoodHistory(db, userID, entryID, date, timeOfDay, quantity, mealType)
+		return err
+	})
+	return value, found, err
+}
+
+func (s *Store) RestoreFoodHistory(userID int, entryID int, date string, timeOfDay string, quantity float64, mealType string) (bool, error) {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return false, err
+	}
+	return writeValue(s, func(db Querier) (bool, error) {
+		return RestoreFoodHistory(db, userID, entryID, date, timeOfDay, quantity, mealType)
+	})
+}
+
+func (s *Store) GetFoodEntry(userID int, entryID int) ([6]interface{}, bool, error) {
+	if err := s.check(userID, ReadAccess); err != nil {
+		return [6]interface{}{}, false, err
+	}
+	return GetFoodEntry(s.db, userID, entryID)
+}
+
+func (s *Store) GetFoodDay(userID int, date string) ([][5]interface{}, error) {
+	if err := s.check(userID, ReadAccess); err != nil {
+		return nil, err
+	}
+	return GetFoodDay(s.db, userID, date)
+}
+
+// GetFoodWithMeal returns the foods of a shared meal, which every user may
+// read: userID is the user the foods are read for
+func (s *Store) GetFoodWithMeal(userID int, mealID int) ([][2]int, error) {
+	if err := s.check(userID, ReadAccess); err != nil {
+		return nil, err
+	}
+	return GetFoodWithMeal(s.db, mealID)
+}
+
+func (s *Store) GetMealWithDayPreset(userID int, dayPresetID int) ([][2]int, error) {
+	if err := s.check(userID, ReadAccess); err != nil {
+		return nil, err
+	}
+	return GetMealWithDayPreset(s.db, userID, dayPresetID)
+}
+
+// CreateMeal creates a shared meal for the user creating it, which must be
+// an administrator since the meal is offered to every user
+func (s *Store) CreateMeal(userID int, name string, mealType string) (int, error) {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return 0, err
+	}
+	if err := s.check(userID, AdminAccess); err != nil {
+		return 0, err
+	}
+	return writeValue(s, func(db Querier) (int, error) {
+		return CreateMeal(db, name, mealType)
+	})
+}
+
+func (s *Store) CreateDayPreset(userID int, name string) (int, error) {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return 0, err
+	}
+	return writeValue(s, func(db Querier) (int, error) {
+		return CreateDayPreset(db, userID, name)
+	})
+}
+
+func (s *Store) GetAllMeals(userID int) ([]suser.Meal, error) {
+	if err := s.check(userID, ReadAccess); err != nil {
+		return nil, err
+	}
+	return GetAllMeals(s.db)
+}
+
+func (s *Store) GetMeal(userID int, mealID int) (suser.Meal, error) {
+	if err := s.check(userID, ReadAccess); err != nil {
+		return suser.Meal{}, err
+	}
+	return GetMeal(s.db, mealID)
+}
+
+func (s *Store) GetAllDays(userID int) ([]suser.DayPreset, error) {
+	if err := s.check(userID, ReadAccess); err != nil {
+		return nil, err
+	}
+	return GetAllDays(s.db, userID)
+}
+
+// DeleteMeal moves a shared meal to the trash of the user deleting it, which
+// must be an administrator since the meal disappears for every user
+func (s *Store) DeleteMeal(userID int, mealID int) (bool, error) {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return false, err
+	}
+	if err := s.check(userID, AdminAccess); err != nil {
+		return false, err
+	}
+	return writeValue(s, func(db Querier) (bool, error) {
+		return DeleteMeal(db, userID, mealID)
+	})
+}
+
+func (s *Store) DeleteDayPreset(userID int, dayPresetID int) (bool, error) {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return false, err
+	}
+	return writeValue(s, func(db Querier) (bool, error) {
+		return DeleteDayPreset(db, userID, dayPresetID)
+	})
+}
+
+// LinkFoodToMeal adds a food to a shared meal for the user changing it,
+// which must be an administrator since the meal changes for every user
+func (s *Store) LinkFoodToMeal(userID int, foodID int, mealID int, quantity int) error {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return err
+	}
+	if err := s.check(userID, AdminAccess); err != nil {
+		return err
+	}
+	return s.write(func(db Querier) error {
+		return LinkFoodToMeal(db, foodID, mealID, quantity)
+	})
+}
+
+func (s *Store) LinkMealToDayPreset(userID int, mealID int, dayPresetID int, quantity int) (bool, error) {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return false, err
+	}
+	return writeValue(s, func(db Querier) (bool, error) {
+		return LinkMealToDayPreset(db, userID, mealID, dayPresetID, quantity)
+	})
+}
+
+func (s *Store) GetFoodHistory(userID int) ([][5]interface{}, error) {
+	if err := s.check(userID, ReadAccess); err != nil {
+		return nil, err
+	}
+	return GetFoodHistory(s.db, userID)
+}
+
+func (s *Store) GetFoodQuantitiesByDay(userID int, from string, to string) (map[string]map[int]float64, error) {
+	if err := s.check(userID, ReadAccess); err != nil {
+		return nil, err
+	}
+	return GetFoodQuantitiesByDay(s.db, userID, from, to)
+}
+
+func (s *Store) GetWeightHistory(userID int) ([][3]interface{}, error) {
+	if err := s.check(userID, ReadAccess); err != nil {
+		return nil, err
+	}
+	return GetWeightHistory(s.db, userID)
+}
+
+func (s *Store) GetWeightSeries(userID int) ([]time.Time, []float64, error) {
+	if err := s.check(userID, ReadAccess); err != nil {
+		return nil, nil, err
+	}
+	return GetWeightSeries(s.db, userID)
+}
+
+func (s *Store) GetBodyFatHistory(userID int) ([][3]interface{}, error) {
+	if err := s.check(userID, ReadAccess); err != nil {
+		return nil, err
+	}
+	return GetBodyFatHistory(s.db, userID)
+}
+
+func (s *Store) GetIMCHistory(userID int) ([][3]interface{}, error) {
+	if err := s.check(userID, ReadAccess); err != nil {
+		return nil, err
+	}
+	return GetIMCHistory(s.db, userID)
+}
+
+func (s *Store) DeleteFoodHistory(userID int, entryID int) (bool, error) {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return false, err
+	}
+	return writeValue(s, func(db Querier) (bool, error) {
+		return DeleteFoodHistory(db, userID, entryID)
+	})
+}
+
+func (s *Store) UpdateUserFirstname(userID int, firstname string) error {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return err
+	}
+	return s.write(func(db Querier) error {
+		return UpdateUserFirstname(db, userID, firstname)
+	})
+}
+
+func (s *Store) UpdateUserLastname(userID int, lastname string) error {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return err
+	}
+	return s.write(func(db Querier) error {
+		return UpdateUserLastname(db, userID, lastname)
+	})
+}
+
+func (s *Store) UpdateUserAge(userID int, age int) error {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return err
+	}
+	return s.write(func(db Querier) error {
+		return UpdateUserAge(db, userID, age)
+	})
+}
+
+func (s *Store) UpdateUserWeight(userID int, weight int) error {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return err
+	}
+	return s.write(func(db Querier) error {
+		return UpdateUserWeight(db, userID, weight)
+	})
+}
+
+func (s *Store) UpdateUserHeight(userID int, height int) error {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return err
+	}
+	return s.write(func(db Querier) error {
+		return UpdateUserHeight(db, userID, height)
+	})
+}
+
+func (s *Store) UpdateUserTargetWeight(userID int, targetWeight int) error {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return err
+	}
+	return s.write(func(db Querier) error {
+		return UpdateUserTargetWeight(db, userID, targetWeight)
+	})
+}
+
+func (s *Store) UpdateUserLang(userID int, lang string) error {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return err
+	}
+	return s.write(func(db Querier) error {
+		return UpdateUserLang(db, userID, lang)
+	})
+}
+
+func (s *Store) UpdateUserTimezone(userID int, timezone string) error {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return err
+	}
+	return s.write(func(db Querier) error {
+		return UpdateUserTimezone(db, userID, timezone)
+	})
+}
+
+func (s *Store) SetTargetCalories(userID int, date string, calories float64) error {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return err
+	}
+	return s.write(func(db Querier) error {
+		return SetTargetCalories(db, userID, date, calories)
+	})
+}
+
+func (s *Store) SetTarget(userID int, date string, column string, amount float64) error {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return err
+	}
+	return s.write(func(db Querier) error {
+		return SetTarget(db, userID, date, column, amount)
+	})
+}
+
+func (s *Store) GetTargets(userID int) (map[string]float64, error) {
+	if err := s.check(userID, ReadAccess); err != nil {
+		return nil, err
+	}
+	return GetTargets(s.db, userID)
+}
+
+func (s *Store) GetTargetCalories(userID int) (float64, error) {
+	if err := s.check(userID, ReadAccess); err != nil {
+		return 0, err
+	}
+	return GetTargetCalories(s.db, userID)
+}
+
+func (s *Store) AddExerciseHistory(userID int, date string, activity string, minutes int, intensity string, calories float64) error {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return err
+	}
+	return s.write(func(db Querier) error {
+		return AddExerciseHistory(db, userID, date, activity, minutes, intensity, calories)
+	})
+}
+
+func (s *Store) GetExerciseHistory(userID int) ([][6]interface{}, error) {
+	if err := s.check(userID, ReadAccess); err != nil {
+		return nil, err
+	}
+	return GetExerciseHistory(s.db, userID)
+}
+
+func (s *Store) GetExerciseTotals(userID int, date string) (int, float64, error) {
+	if err := s.check(userID, ReadAccess); err != nil {
+		return 0, 0, err
+	}
+	return GetExerciseTotals(s.db, userID, date)
+}
+
+func (s *Store) AddWaterHistory(userID int, date string, amount int) error {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return err
+	}
+	return s.write(func(db Querier) error {
+		return AddWaterHistory(db, userID, date, amount)
+	})
+}
+
+func (s *Store) GetWaterHistory(userID int) ([][3]interface{}, error) {
+	if err := s.check(userID, ReadAccess); err != nil {
+		return nil, err
+	}
+	return GetWaterHistory(s.db, userID)
+}
+
+func (s *Store) GetWaterTotal(userID int, date string) (int, error) {
+	if err := s.check(userID, ReadAccess); err != nil {
+		return 0, err
+	}
+	return GetWaterTotal(s.db, userID, date)
+}
+
+func (s *Store) SetAlias(userID int, name string, definition string) error {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return err
+	}
+	return s.write(func(db Querier) error {
+		return SetAlias(db, userID, name, definition)
+	})
+}
+
+func (s *Store) GetAliases(userID int) ([][2]string, error) {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return nil, err
+	}
+	return GetAliases(s.db, userID)
+}
+
+func (s *Store) DeleteAlias(userID int, name string) (bool, error) {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return false, err
+	}
+	return writeValue(s, func(db Querier) (bool, error) {
+		return DeleteAlias(db, userID, name)
+	})
+}
+
+func (s *Store) CreateAPIToken(userID int, name string, tokenHash string, scopes []string, expiresAt time.Time) (int, error) {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return 0, err
+	}
+	return writeValue(s, func(db Querier) (int, error) {
+		return CreateAPIToken(db, userID, name, tokenHash, scopes, expiresAt)
+	})
+}
+
+func (s *Store) UseAPIToken(tokenHash string) (suser.APIToken, bool, error) {
+	return UseAPIToken(s.db, tokenHash)
+}
+
+func (s *Store) GetAPITokens(userID int) ([]suser.APIToken, error) {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return nil, err
+	}
+	return GetAPITokens(s.db, userID)
+}
+
+func (s *Store) RevokeAPIToken(userID int, tokenID int) (bool, error) {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return false, err
+	}
+	return writeValue(s, func(db Querier) (bool, error) {
+		return RevokeAPIToken(db, userID, tokenID)
+	})
+}
+
+func (s *Store) DeleteAPIToken(tokenHash string) error {
+	return s.write(func(db Querier) error {
+		return DeleteAPIToken(db, tokenHash)
+	})
+}
+
+func (s *Store) AddSSHKey(userID int, name string, fingerprint string, publicKey string) (int, bool, error) {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return 0, false, err
+	}
+	var keyID int
+	var added bool
+	err := s.write(func(db Querier) (err error) {
+		keyID, added, err = AddSSHKey(db, userID, name, fingerprint, publicKey)
+		return err
+	})
+	return keyID, added, err
+}
+
+func (s *Store) FindSSHKey(fingerprint string) (suser.SSHKey, bool, error) {
+	return FindSSHKey(s.db, fingerprint)
+}
+
+func (s *Store) UseSSHKey(keyID int) error {
+	return UseSSHKey(s.db, keyID)
+}
+
+func (s *Store) GetSSHKeys(userID int) ([]suser.SSHKey, error) {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return nil, err
+	}
+	return GetSSHKeys(s.db, userID)
+}
+
+func (s *Store) DeleteSSHKey(userID int, keyID int) (bool, error) {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return false, err
+	}
+	return writeValue(s, func(db Querier) (bool, error) {
+		return DeleteSSHKey(db, userID, keyID)
+	})
+}
+
+func (s *Store) InviteClient(coachID int, clientID int) (bool, error) {
+	if err := s.check(coachID, CoachAccess); err != nil {
+		return false, err
+	}
+	return writeValue(s, func(db Querier) (bool, error) {
+		return InviteClient(db, coachID, clientID)
+	})
+}
+
+func (s *Store) AcceptCoach(clientID int, coachID int) (bool, error) {
+	if err := s.check(clientID, OwnAccess); err != nil {
+		return false, err
+	}
+	return writeValue(s, func(db Querier) (bool, error) {
+		return AcceptCoach(db, clientID, coachID)
+	})
+}
+
+// EndCoaching may be called by the coach as well as by the client
+func (s *Store) EndCoaching(coachID int, clientID int) (bool, error) {
+	if err := s.check(coachID, OwnAccess); err != nil {
+		if err := s.check(clientID, OwnAccess); err != nil {
+			return false, err
+		}
+	}
+	return writeValue(s, func(db Querier) (bool, error) {
+		return EndCoaching(db, coachID, clientID)
+	})
+}
+
+func (s *Store) GetClients(coachID int) ([]suser.CoachClient, error) {
+	if err := s.check(coachID, OwnAccess); err != nil {
+		return nil, err
+	}
+	return GetClients(s.db, coachID)
+}
+
+func (s *Store) GetCoaches(clientID int) ([]suser.CoachClient, error) {
+	if err := s.check(clientID, OwnAccess); err != nil {
+		return nil, err
+	}
+	return GetCoaches(s.db, clientID)
+}
+
+func (s *Store) GetAccounts() ([]suser.Account, error) {
+	if err := s.check(0, AdminAccess); err != nil {
+		return nil, err
+	}
+	return GetAccounts(s.db)
+}
+
+func (s *Store) SetUserRole(userID int, role string) (bool, error) {
+	if err := s.check(userID, AdminAccess); err != nil {
+		return false, err
+	}
+	return writeValue(s, func(db Querier) (bool, error) {
+		return SetUserRole(db, userID, role)
+	})
+}
+
+func (s *Store) UnlockUser(userID int) error {
+	if err := s.check(userID, AdminAccess); err != nil {
+		return err
+	}
+	return s.write(func(db Querier) error {
+		return ResetFailedLogins(db, userID)
+	})
+}
+
+// GetAuditLog may be called by the user itself and by the administrators
+func (s *Store) GetAuditLog(userID int, table string, limit int) ([]suser.AuditEntry, error) {
+	if err := s.check(userID, OwnAccess); err != nil {
+		if err := s.check(userID, AdminAccess); err != nil {
+			return nil, err
+		}
+	}
+	return GetAuditLog(s.db, userID, table, limit)
+}
+
+func (s *Store) GetTrash(userID int) ([]suser.TrashItem, error) {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return nil, err
+	}
+	return GetTrash(s.db, userID)
+}
+
+func (s *Store) RestoreTrash(userID int, kind string, id int) (bool, error) {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return false, err
+	}
+	return writeValue(s, func(db Querier) (bool, error) {
+		return RestoreTrash(db, userID, kind, id)
+	})
+}
+
+func (s *Store) PurgeTrash(userID int) (int64, error) {
+	if err := s.check(userID, OwnAccess); err != nil {
+		return 0, err
+	}
+	return writeValue(s, func(db Querier) (int64, error) {
+		return PurgeTrash(db, userID)
+	})
+}
@@ -0,0 +1,152 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	suser "gotracker/structs"
+	"time"
+)
+
+// trashTable is a table whose deleted rows go to the trash. owner is the
+// column of the user owning a deleted row, restore the assignments taking a
+// row out of the trash and children the rows referencing a row, deleted with
+// it when the trash is purged.
+type trashTable struct {
+	kind     string
+	name     string
+	owner    string
+	restore  string
+	children []trashChild
+}
+
+// trashChild is a column of a table referencing the rows of a trashable table
+type trashChild struct {
+	table  string
+	column string
+}
+
+// trashTables are the tables with a trash, in the order they are purged
+var trashTables = []trashTable{
+	{kind: "food", name: "food_history", owner: "user_id", restore: "deleted_at = NULL"},
+	{kind: "meal", name: "meal", owner: "deleted_by", restore: "deleted_at = NULL, deleted_by = NULL",
+		children: []trashChild{{"meal_food", "meal_id"}, {"day_preset_meal", "meal_id"}}},
+	{kind: "day", name: "day_preset", owner: "user_id", restore: "deleted_at = NULL",
+		children: []trashChild{{"day_preset_meal", "day_preset_id"}}},
+}
+
+// GetTrash returns the items in the trash of a user, the latest deleted first
+func GetTrash(db Querier, userID int) ([]suser.TrashItem, error) {
+	rows, err := db.Query(`
+		SELECT 'food', id, '', food_id, quantity, COALESCE(TO_CHAR(date, 'YYYY-MM-DD'), ''), deleted_at
+		FROM food_history
+		WHERE user_id = $1 AND deleted_at IS NOT NULL
+		UNION ALL
+		SELECT 'meal', id, COALESCE(name, ''), 0, 0::FLOAT, '', deleted_at
+		FROM meal
+		WHERE deleted_by = $1 AND deleted_at IS NOT NULL
+		UNION ALL
+		SELECT 'day', id, COALESCE(name, ''), 0, 0::FLOAT, '', deleted_at
+		FROM day_preset
+		WHERE user_id = $1 AND deleted_at IS NOT NULL
+		ORDER BY 7 DESC, 1, 2
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trash: %w", err)
+	}
+	defer rows.Close()
+
+	var items []suser.TrashItem
+	for rows.Next() {
+		var item suser.TrashItem
+		if err := rows.Scan(&item.Kind, &item.ID, &item.Name, &item.FoodID, &item.Quantity, &item.Date, &item.DeletedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan trash item: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// RestoreTrash takes an item out of the trash of a user, false when the
+// trash has no such item
+func RestoreTrash(db Querier, userID int, kind string, id int) (bool, error) {
+	table, err := findTrashTable(kind)
+	if err != nil {
+		return false, err
+	}
+	result, err := db.Exec(`
+		UPDATE `+table.name+`
+		SET `+table.restore+`
+		WHERE id = $1 AND `+table.owner+` = $2 AND deleted_at IS NOT NULL
+	`, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to restore %s: %w", table.name, err)
+	}
+	restored, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to restore %s: %w", table.name, err)
+	}
+	return restored > 0, nil
+}
+
+// PurgeTrash deletes for good the items in the trash of a user and returns their number
+func PurgeTrash(db Querier, userID int) (int64, error) {
+	return purgeTrash(db, func(table trashTable) string { return table.owner + " = $1" }, userID)
+}
+
+// PurgeExpiredTrash deletes for good the items of every trash deleted more
+// than retention ago and returns their number
+func PurgeExpiredTrash(db *sql.DB, retention time.Duration) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge trash: %w", err)
+	}
+	defer tx.Rollback()
+	purged, err := purgeTrash(tx, func(trashTable) string { return "deleted_at < $1" }, time.Now().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to purge trash: %w", err)
+	}
+	return purged, nil
+}
+
+// purgeTrash deletes the trashed rows matching the condition of their table
+// on arg, with the rows referencing them, and returns their number
+func purgeTrash(db Querier, condition func(table trashTable) string, arg interface{}) (int64, error) {
+	var purged int64
+	for _, table := range trashTables {
+		where := "deleted_at IS NOT NULL AND " + condition(table)
+		for _, child := range table.children {
+			_, err := db.Exec(`
+				DELETE FROM `+child.table+`
+				WHERE `+child.column+` IN (SELECT id FROM `+table.name+` WHERE `+where+`)
+			`, arg)
+			if err != nil {
+				return 0, fmt.Errorf("failed to purge %s: %w", child.table, err)
+			}
+		}
+		result, err := db.Exec(`
+			DELETE FROM `+table.name+`
+			WHERE `+where, arg)
+		if err != nil {
+			return 0, fmt.Errorf("failed to purge %s: %w", table.name, err)
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to purge %s: %w", table.name, err)
+		}
+		purged += rows
+	}
+	return purged, nil
+}
+
+// findTrashTable returns the table of a kind of trash item
+func findTrashTable(kind string) (trashTable, error) {
+	for _, table := range trashTables {
+		if table.kind == kind {
+			return table, nil
+		}
+	}
+	return trashTable{}, fmt.Errorf("unknown kind of trash item '%s'", kind)
+}
@@ -0,0 +1,474 @@
+// The front end of the tracker. Everything goes through the REST API of the
+// server, with the token given by login kept in the local storage.
+'use strict';
+
+const API = '/api/v1';
+const TOKEN_KEY = 'gotracker.token';
+const MEAL_TYPES = ['breakfast', 'lunch', 'dinner', 'snack'];
+
+const state = {
+  token: localStorage.getItem(TOKEN_KEY),
+  user: null,
+  view: 'today',
+  meals: [],
+  foodNames: new Map(),
+  selectedFood: null,
+  selectedMeal: null,
+  selectedDay: null,
+};
+
+// $ returns the element with the given id
+function $(id) {
+  return document.getElementById(id);
+}
+
+// el creates an element with attributes and children, the strings become text
+function el(tag, attributes, ...children) {
+  const element = document.createElement(tag);
+  for (const [name, value] of Object.entries(attributes || {})) {
+    if (name.startsWith('on')) {
+      element.addEventListener(name.slice(2), value);
+    } else if (value !== false && value !== undefined && value !== null) {
+      element.setAttribute(name, value === true ? '' : value);
+    }
+  }
+  for (const child of children.flat()) {
+    if (child !== null && child !== undefined && child !== false) {
+      element.append(child instanceof Node ? child : String(child));
+    }
+  }
+  return element;
+}
+
+// api sends a request to the API and returns its JSON result, the errors
+// are thrown with the message of the server
+async function api(method, path, fields) {
+  const headers = {'Accept-Language': lang};
+  if (state.token) {
+    headers.Authorization = 'Bearer ' + state.token;
+  }
+  const init = {method, headers};
+  if (fields && (method === 'GET' || method === 'DELETE')) {
+    const query = new URLSearchParams();
+    for (const [name, value] of Object.entries(fields)) {
+      if (value !== '' && value !== undefined && value !== null) {
+        query.set(name, value);
+      }
+    }
+    if ([...query].length > 0) {
+      path += '?' + query;
+    }
+  } else if (fields) {
+    headers['Content-Type'] = 'application/json';
+    init.body = JSON.stringify(fields);
+  }
+
+  const response = await fetch(API + path, init);
+  if (response.status === 401 && state.token) {
+    forget();
+    throw new Error(t('Your session has expired, please login again.'));
+  }
+  if (response.status === 204) {
+    return null;
+  }
+  const data = await response.json().catch(() => ({error: response.statusText}));
+  if (!response.ok) {
+    throw new Error(data.error || response.statusText);
+  }
+  return data;
+}
+
+// all returns every item of a paginated list
+async function all(path, fields) {
+  let items = [];
+  for (let page = 1; ; page++) {
+    const result = await api('GET', path, Object.assign({}, fields, {page, per_page: 200}));
+    items = items.concat(result.items);
+    if (result.items.length === 0 || items.length >= result.total) {
+      return items;
+    }
+  }
+}
+
+// compact returns the fields that are set, the empty inputs are left to the defaults of the API
+function compact(fields) {
+  const result = {};
+  for (const [name, value] of Object.entries(fields)) {
+    if (value !== '' && value !== undefined && value !== null) {
+      result[name] = value;
+    }
+  }
+  return result;
+}
+
+// show displays a message, errors in red, and hides it after a while
+let messageTimer;
+function show(message, isError) {
+  const box = $('message');
+  box.textContent = message;
+  box.className = isError ? 'error' : '';
+  box.hidden = false;
+  clearTimeout(messageTimer);
+  messageTimer = setTimeout(() => { box.hidden = true; }, isError ? 8000 : 3000);
+}
+
+// attempt runs an action and shows its error
+async function attempt(action) {
+  try {
+    await action();
+  } catch (err) {
+    show(err.message, true);
+  }
+}
+
+// foodName returns the description of a food, fetched once
+async function foodName(foodID) {
+  if (!state.foodNames.has(foodID)) {
+    try {
+      const details = await api('GET', `/foods/${foodID}`);
+      state.foodNames.set(foodID, details.description);
+    } catch (err) {
+      return `#${foodID}`;
+    }
+  }
+  return state.foodNames.get(foodID);
+}
+
+// progress returns a progress bar of value against target
+function progress(label, value, target, unit, decimals) {
+  const text = target
+    ? `${formatNumber(value, decimals)} / ${formatNumber(target, decimals)} ${unit} (${formatNumber(value * 100 / target)}%)`
+    : `${formatNumber(value, decimals)} ${unit} (${t('no target')})`;
+  const bar = el('div', {class: 'bar'});
+  if (target) {
+    const fill = el('div', {class: value > target ? 'fill over' : 'fill'});
+    fill.style.width = Math.min(100, value * 100 / target) + '%';
+    bar.append(fill);
+  }
+  return el('div', {class: 'progress'}, el('div', {class: 'progress-label'}, el('span', {}, label), el('span', {}, text)), bar);
+}
+
+// Session
+
+function forget() {
+  state.token = null;
+  state.user = null;
+  localStorage.removeItem(TOKEN_KEY);
+  render();
+}
+
+async function login(form) {
+  const session = await api('POST', '/login', {login: form.login.value, password: form.password.value});
+  state.token = session.token;
+  state.user = session.user;
+  localStorage.setItem(TOKEN_KEY, session.token);
+  form.reset();
+  render();
+}
+
+async function logout() {
+  try {
+    await api('POST', '/logout');
+  } finally {
+    forget();
+  }
+}
+
+// Views
+
+function render() {
+  const loggedIn = Boolean(state.token);
+  $('tabs').hidden = !loggedIn;
+  $('logout').hidden = !loggedIn;
+  $('user').textContent = state.user ? `${state.user.firstname} ${state.user.lastname}` : '';
+  const view = loggedIn ? state.view : 'login';
+  document.querySelectorAll('.view').forEach((section) => {
+    section.hidden = section.id !== 'view-' + view;
+  });
+  document.querySelectorAll('#tabs button').forEach((button) => {
+    button.classList.toggle('active', button.dataset.view === view);
+  });
+  if (loggedIn) {
+    attempt(async () => {
+      if (!state.user) {
+        state.user = await api('GET', '/me');
+        $('user').textContent = `${state.user.firstname} ${state.user.lastname}`;
+      }
+      await views[view]();
+    });
+  }
+}
+
+const views = {
+  today: loadToday,
+  add: loadMealChoices,
+  meals: loadMeals,
+  charts: loadCharts,
+  targets: loadTargets,
+};
+
+// Daily log
+
+async function loadToday() {
+  const date = $('today-date').value;
+  const [log, summary] = await Promise.all([
+    api('GET', '/day-log', {date}),
+    api('GET', '/summary', {date}),
+  ]);
+
+  $('today-summary').replaceChildren(
+    progress(t('Calories'), summary.net, summary.target, 'kcal'),
+    el('p', {}, `${t('Intake')}: ${formatNumber(summary.intake)} kcal · ${t('Exercise')}: ${formatNumber(summary.exercise)} kcal · ${t('Net')}: ${formatNumber(summary.net)} kcal`),
+    progress(t('Hydration'), summary.hydration, summary.hydration_target, 'ml'),
+  );
+
+  $('today-nutrients').replaceChildren(...log.nutrients.map((nutrient) =>
+    progress(nutrient.name, nutrient.consumed, nutrient.target, nutrient.unit, 1)));
+
+  if (log.foods.length === 0) {
+    $('today-foods').replaceChildren(el('p', {class: 'hint'}, t('No food logged on this day.')));
+    return;
+  }
+  const groups = new Map();
+  for (const food of log.foods) {
+    state.foodNames.set(food.food_id, food.description);
+    const type = food.meal_type || 'other';
+    if (!groups.has(type)) {
+      groups.set(type, []);
+    }
+    groups.get(type).push(food);
+  }
+  const order = [...MEAL_TYPES, 'other'];
+  const types = [...groups.keys()].sort((a, b) => (order.indexOf(a) + 1 || 99) - (order.indexOf(b) + 1 || 99));
+  $('today-foods').replaceChildren(...types.map((type) => {
+    const foods = groups.get(type);
+    const calories = foods.reduce((sum, food) => sum + food.calories, 0);
+    return el('div', {class: 'meal-group'},
+      el('h3', {}, `${t(type)} · ${formatNumber(calories)} kcal`),
+      el('table', {},
+        el('tbody', {}, foods.map((food) => el('tr', {},
+          el('td', {class: 'time'}, food.time),
+          el('td', {}, food.description),
+          el('td', {class: 'number'}, `${formatNumber(food.quantity)} g`),
+          el('td', {class: 'number'}, `${formatNumber(food.calories)} kcal`),
+          el('td', {class: 'actions'},
+            el('button', {type: 'button', onclick: () => attempt(() => editEntry(food))}, t('Edit')),
+            el('button', {type: 'button', class: 'danger', onclick: () => attempt(() => deleteEntry(food))}, t('Delete'))),
+        )))));
+  }));
+}
+
+async function editEntry(food) {
+  const quantity = prompt(t('New quantity in grams:'), food.quantity);
+  if (quantity === null || quantity.trim() === '') {
+    return;
+  }
+  await api('PATCH', `/food-history/${food.entry_id}`, {quantity: Number(quantity)});
+  await loadToday();
+}
+
+async function deleteEntry(food) {
+  if (!confirm(t('Delete this entry?'))) {
+    return;
+  }
+  await api('DELETE', `/food-history/${food.entry_id}`);
+  await loadToday();
+}
+
+async function logWater() {
+  await api('POST', '/water', compact({amount: 'glass', date: $('today-date').value}));
+  show(t('Glass of water logged.'));
+  await loadToday();
+}
+
+// Search and add
+
+async function search(form) {
+  const foods = await api('GET', '/foods', {food_name: form.food_name.value, per_page: 25});
+  $('add-form').hidden = true;
+  if (foods.items.length === 0) {
+    $('search-results').replaceChildren(el('li', {class: 'hint'}, t('No food found.')));
+    return;
+  }
+  $('search-results').replaceChildren(...foods.items.map((food) => {
+    state.foodNames.set(food.food_id, food.description);
+    return el('li', {},
+      el('button', {type: 'button', class: 'link', onclick: () => selectFood(food)}, food.description),
+      el('span', {class: 'hint'}, ` ${food.data_type} · #${food.food_id}`));
+  }));
+}
+
+function selectFood(food) {
+  state.selectedFood = food;
+  $('add-food-name').textContent = food.description;
+  $('add-form').hidden = false;
+  $('add-form').quantity.focus();
+}
+
+async function addFood(form) {
+  await api('POST', '/food-history', compact({
+    food_id: state.selectedFood.food_id,
+    quantity: Number(form.quantity.value),
+    meal: form.meal.value,
+    date: form.date.value,
+    time: form.time.value,
+  }));
+  show(t('Added to the food log.'));
+}
+
+async function loadMealChoices() {
+  state.meals = await all('/meals');
+  $('add-meal-select').replaceChildren(...state.meals.map((meal) => el('option', {value: meal.id}, meal.name)));
+}
+
+async function addFoodToMeal() {
+  const mealID = $('add-meal-select').value;
+  if (!mealID) {
+    throw new Error(t('Create a meal first.'));
+  }
+  await api('POST', `/meals/${mealID}/foods`, {
+    food_id: state.selectedFood.food_id,
+    quantity: Number($('add-form').quantity.value),
+  });
+  show(t('Added to the meal.'));
+}
+
+// Meals and day presets
+
+async function loadMeals() {
+  const [meals, days] = await Promise.all([all('/meals'), all('/days')]);
+  state.meals = meals;
+  $('meal-list').replaceChildren(...(meals.length ? meals.map((meal) => el('li', {class: state.selectedMeal === meal.id ? 'selected' : ''},
+    el('button', {type: 'button', class: 'link', onclick: () => attempt(() => showMeal(meal.id))}, meal.name),
+    el('span', {class: 'hint'}, ` ${t(meal.type)} · #${meal.id}`))) : [el('li', {class: 'hint'}, t('No meals yet.'))]));
+  $('day-list').replaceChildren(...(days.length ? days.map((day) => el('li', {class: state.selectedDay === day.id ? 'selected' : ''},
+    el('button', {type: 'button', class: 'link', onclick: () => attempt(() => showDay(day.id))}, day.name),
+    el('span', {class: 'hint'}, ` #${day.id}`))) : [el('li', {class: 'hint'}, t('No day presets yet.'))]));
+  if (state.selectedMeal) {
+    await showMeal(state.selectedMeal);
+  }
+  if (state.selectedDay) {
+    await showDay(state.selectedDay);
+  }
+}
+
+async function showMeal(mealID) {
+  state.selectedMeal = mealID;
+  const meal = await api('GET', `/meals/${mealID}`);
+  const names = await Promise.all(meal.foods.map((food) => foodName(food.food_id)));
+  $('meal-details').replaceChildren(
+    el('h3', {}, `${meal.name} (${t(meal.type)})`),
+    meal.foods.length === 0
+      ? el('p', {class: 'hint'}, t('No foods linked to this meal yet, add some from the food search.'))
+      : el('table', {}, el('tbody', {}, meal.foods.map((food, i) => el('tr', {},
+        el('td', {}, names[i]),
+        el('td', {class: 'number'}, `${formatNumber(food.quantity)} g`))))),
+    el('button', {type: 'button', onclick: () => attempt(() => logPreset('meals', mealID))}, t('Log this meal')),
+  );
+}
+
+async function showDay(dayID) {
+  state.selectedDay = dayID;
+  const day = await api('GET', `/days/${dayID}`);
+  const mealNames = new Map(state.meals.map((meal) => [meal.id, meal.name]));
+  const link = el('form', {class: 'inline', onsubmit: (event) => {
+    event.preventDefault();
+    attempt(async () => {
+      await api('POST', `/days/${dayID}/meals`, {meal_id: Number(link.meal_id.value), quantity: Number(link.quantity.value)});
+      await showDay(dayID);
+    });
+  }},
+  el('select', {name: 'meal_id', 'aria-label': t('Meal')}, state.meals.map((meal) => el('option', {value: meal.id}, meal.name))),
+  el('input', {name: 'quantity', type: 'number', min: 1, step: 1, value: 1, 'aria-label': t('Servings')}),
+  el('button', {type: 'submit', disabled: state.meals.length === 0}, t('Add the meal')));
+
+  $('day-details').replaceChildren(
+    el('h3', {}, day.name),
+    day.meals.length === 0
+      ? el('p', {class: 'hint'}, t('No meals linked to this day yet.'))
+      : el('table', {}, el('tbody', {}, day.meals.map((meal) => el('tr', {},
+        el('td', {}, mealNames.get(meal.meal_id) || `#${meal.meal_id}`),
+        el('td', {class: 'number'}, `× ${meal.quantity}`))))),
+    link,
+    el('button', {type: 'button', onclick: () => attempt(() => logPreset('days', dayID))}, t('Log this day')),
+  );
+}
+
+async function logPreset(kind, id) {
+  await api('POST', `/${kind}/${id}/log`, compact({date: $('today-date').value}));
+  show(t('Logged.'));
+}
+
+async function createMeal(form) {
+  const meal = await api('POST', '/meals', {meal_name: form.meal_name.value, meal_type: form.meal_type.value});
+  form.reset();
+  state.selectedMeal = meal.id;
+  await loadMeals();
+}
+
+async function createDay(form) {
+  const day = await api('POST', '/days', {day_name: form.day_name.value});
+  form.reset();
+  state.selectedDay = day.id;
+  await loadMeals();
+}
+
+// Charts
+
+async function loadCharts() {
+  const [weights, imcs, bodyFats] = await Promise.all([all('/weights'), all('/imc'), all('/bodyfat')]);
+  $('chart-weight').replaceChildren(lineChart(
+    weights.map((entry) => ({date: entry.date, value: entry.weight})),
+    {target: state.user && state.user.target_weight}));
+  $('chart-imc').replaceChildren(lineChart(imcs.map((entry) => ({date: entry.date, value: entry.imc}))));
+  $('chart-bodyfat').replaceChildren(lineChart(bodyFats.map((entry) => ({date: entry.date, value: entry.body_fat}))));
+}
+
+// Targets
+
+async function loadTargets() {
+  const targets = await api('GET', '/targets');
+  $('target-list').replaceChildren(el('table', {},
+    el('thead', {}, el('tr', {}, el('th', {}, t('Nutrient')), el('th', {}, t('Target')), el('th', {}))),
+    el('tbody', {}, targets.map((target) => {
+      const input = el('input', {type: 'number', min: 0, step: 'any', value: target.target === null ? '' : target.target, 'aria-label': target.name});
+      return el('tr', {},
+        el('td', {}, `${target.name} (${target.unit})`),
+        el('td', {}, input),
+        el('td', {}, el('button', {type: 'button', onclick: () => attempt(async () => {
+          await api('PUT', `/targets/${target.nutrient}`, {amount: Number(input.value)});
+          show(t('Saved.'));
+        })}, t('Set'))));
+    }))));
+}
+
+// Events
+
+function onSubmit(id, action) {
+  $(id).addEventListener('submit', (event) => {
+    event.preventDefault();
+    attempt(() => action(event.target));
+  });
+}
+
+translatePage();
+onSubmit('login-form', login);
+onSubmit('search-form', search);
+onSubmit('add-form', addFood);
+onSubmit('meal-form', createMeal);
+onSubmit('day-form', createDay);
+$('logout').addEventListener('click', () => attempt(logout));
+$('add-to-meal').addEventListener('click', () => attempt(addFoodToMeal));
+$('today-date').addEventListener('change', () => attempt(loadToday));
+$('today-reset').addEventListener('click', () => {
+  $('today-date').value = '';
+  attempt(loadToday);
+});
+$('today-water').addEventListener('click', () => attempt(logWater));
+document.querySelectorAll('#tabs button').forEach((button) => {
+  button.addEventListener('click', () => {
+    state.view = button.dataset.view;
+    render();
+  });
+});
+render();
@@ -0,0 +1,92 @@
+// Line charts drawn in SVG, without any library.
+'use strict';
+
+const SVG_NS = 'http://www.w3.org/2000/svg';
+const DAY_MS = 24 * 60 * 60 * 1000;
+
+// svg creates an SVG element with the given attributes
+function svg(tag, attributes, text) {
+  const element = document.createElementNS(SVG_NS, tag);
+  for (const [name, value] of Object.entries(attributes || {})) {
+    element.setAttribute(name, value);
+  }
+  if (text !== undefined) {
+    element.textContent = text;
+  }
+  return element;
+}
+
+// lineChart returns a chart of points {date: 'YYYY-MM-DD', value}, with a
+// dashed line at options.target when it is set
+function lineChart(points, options) {
+  options = options || {};
+  const decimals = options.decimals === undefined ? 1 : options.decimals;
+  const width = 720;
+  const height = 260;
+  const pad = {top: 16, right: 20, bottom: 32, left: 56};
+
+  points = points
+    .map((point) => ({date: point.date, time: Date.parse(point.date), value: point.value}))
+    .filter((point) => !Number.isNaN(point.time))
+    .sort((a, b) => a.time - b.time);
+  if (points.length === 0) {
+    const empty = document.createElement('p');
+    empty.className = 'hint';
+    empty.textContent = t('No data yet.');
+    return empty;
+  }
+
+  let minX = points[0].time;
+  let maxX = points[points.length - 1].time;
+  if (minX === maxX) {
+    minX -= DAY_MS;
+    maxX += DAY_MS;
+  }
+  const values = points.map((point) => point.value);
+  if (options.target) {
+    values.push(options.target);
+  }
+  let minY = Math.min(...values);
+  let maxY = Math.max(...values);
+  const margin = (maxY - minY) * 0.1 || 1;
+  minY -= margin;
+  maxY += margin;
+
+  const x = (time) => pad.left + (time - minX) / (maxX - minX) * (width - pad.left - pad.right);
+  const y = (value) => height - pad.bottom - (value - minY) / (maxY - minY) * (height - pad.top - pad.bottom);
+
+  const chart = svg('svg', {viewBox: `0 0 ${width} ${height}`, class: 'chart', role: 'img'});
+
+  // Horizontal grid with the values
+  for (let i = 0; i <= 4; i++) {
+    const value = minY + (maxY - minY) * i / 4;
+    chart.append(
+      svg('line', {x1: pad.left, x2: width - pad.right, y1: y(value), y2: y(value), class: 'grid'}),
+      svg('text', {x: pad.left - 6, y: y(value) + 4, 'text-anchor': 'end'}, formatNumber(value, decimals)),
+    );
+  }
+
+  // Dates of the first, middle and last points
+  const labelled = new Set([0, Math.floor((points.length - 1) / 2), points.length - 1]);
+  labelled.forEach((index) => {
+    const point = points[index];
+    chart.append(svg('text', {x: x(point.time), y: height - 10, 'text-anchor': 'middle'}, formatDate(point.date)));
+  });
+
+  if (options.target) {
+    chart.append(
+      svg('line', {x1: pad.left, x2: width - pad.right, y1: y(options.target), y2: y(options.target), class: 'target'}),
+      svg('text', {x: width - pad.right, y: y(options.target) - 6, 'text-anchor': 'end', class: 'target-label'},
+        t('target') + ' ' + formatNumber(options.target, decimals)),
+    );
+  }
+
+  const line = points.map((point) => `${x(point.time).toFixed(1)},${y(point.value).toFixed(1)}`).join(' ');
+  chart.append(svg('polyline', {points: line, class: 'line'}));
+  for (const point of points) {
+    const dot = svg('circle', {cx: x(point.time), cy: y(point.value), r: 3.5, class: 'dot'});
+    dot.append(svg('title', {}, `${formatDate(point.date)}: ${formatNumber(point.value, decimals)}`));
+    chart.append(dot);
+  }
+  return chart;
+}
@@ -0,0 +1,109 @@
+// Translations of the front end. The messages of the API are already
+// translated by the server from the Accept-Language header.
+'use strict';
+
+const lang = (navigator.language || 'en').toLowerCase().startsWith('fr') ? 'fr' : 'en';
+
+const fr = {
+  'Today': "Aujourd'hui",
+  'Add food': 'Ajouter un aliment',
+  'Meals and days': 'Repas et journées',
+  'Charts': 'Graphiques',
+  'Targets': 'Objectifs',
+  'Logout': 'Déconnexion',
+  'Login': 'Connexion',
+  'Username or email': "Nom d'utilisateur ou e-mail",
+  'Password': 'Mot de passe',
+  'New users register with the CLI: gotracker register.': "Les nouveaux utilisateurs s'inscrivent avec le CLI : gotracker register.",
+  'Date': 'Date',
+  'Time': 'Heure',
+  '+ Glass of water': "+ Verre d'eau",
+  'Summary': 'Bilan',
+  'Target progress': 'Progression des objectifs',
+  'Foods': 'Aliments',
+  'Search FoodData Central': 'Rechercher dans FoodData Central',
+  'Search': 'Rechercher',
+  'Results': 'Résultats',
+  'Quantity (g)': 'Quantité (g)',
+  'Meal type': 'Type de repas',
+  'Guess from the time': "Selon l'heure",
+  'breakfast': 'petit-déjeuner',
+  'lunch': 'déjeuner',
+  'dinner': 'dîner',
+  'snack': 'collation',
+  'other': 'autre',
+  'Add to the food log': "Ajouter à l'historique",
+  'Add to the meal': 'Ajouter au repas',
+  'Meals': 'Repas',
+  'Day presets': 'Journées types',
+  'Name': 'Nom',
+  'Create': 'Créer',
+  'Weight (kg)': 'Poids (kg)',
+  'IMC': 'IMC',
+  'Body fat (%)': 'Graisse corporelle (%)',
+  'Daily targets': 'Objectifs journaliers',
+  'Intake': 'Apports',
+  'Exercise': 'Exercice',
+  'Net': 'Net',
+  'Calories': 'Calories',
+  'Hydration': 'Hydratation',
+  'no target': "pas d'objectif",
+  'No food logged on this day.': 'Aucun aliment enregistré ce jour-là.',
+  'Quantity': 'Quantité',
+  'Edit': 'Modifier',
+  'Delete': 'Supprimer',
+  'New quantity in grams:': 'Nouvelle quantité en grammes :',
+  'Delete this entry?': 'Supprimer cette entrée ?',
+  'No food found.': 'Aucun aliment trouvé.',
+  'Create a meal first.': "Créez d'abord un repas.",
+  'Added to the food log.': "Ajouté à l'historique.",
+  'Added to the meal.': 'Ajouté au repas.',
+  'No meals yet.': 'Aucun repas pour le moment.',
+  'No day presets yet.': 'Aucune journée type pour le moment.',
+  'No foods linked to this meal yet, add some from the food search.': 'Aucun aliment lié à ce repas, ajoutez-en depuis la recherche.',
+  'No meals linked to this day yet.': 'Aucun repas lié à cette journée pour le moment.',
+  'Food': 'Aliment',
+  'Meal': 'Repas',
+  'Servings': 'Portions',
+  'Add the meal': 'Ajouter le repas',
+  'Log this meal': 'Enregistrer ce repas',
+  'Log this day': 'Enregistrer cette journée',
+  'Logged.': 'Enregistré.',
+  'Saved.': 'Enregistré.',
+  'No data yet.': 'Aucune donnée pour le moment.',
+  'target': 'objectif',
+  'Nutrient': 'Nutriment',
+  'Target': 'Objectif',
+  'Set': 'Définir',
+  'Glass of water logged.': "Verre d'eau enregistré.",
+  'Your session has expired, please login again.': 'Votre session a expiré, reconnectez-vous.',
+};
+
+// t translates a message of the front end
+function t(message) {
+  return lang === 'fr' && fr[message] !== undefined ? fr[message] : message;
+}
+
+// formatNumber writes a number with the decimal separator of the language
+function formatNumber(value, decimals) {
+  return Number(value).toLocaleString(lang, {minimumFractionDigits: 0, maximumFractionDigits: decimals || 0});
+}
+
+// formatDate writes a YYYY-MM-DD date, DD/MM/YYYY in French
+function formatDate(date) {
+  if (lang !== 'fr' || !/^\d{4}-\d{2}-\d{2}$/.test(date)) {
+    return date;
+  }
+  return date.slice(8, 10) + '/' + date.slice(5, 7) + '/' + date.slice(0, 4);
+}
+
+// translatePage translates the elements of the page marked with data-t
+function translatePage() {
+  document.documentElement.lang = lang;
+  document.querySelectorAll('[data-t]').forEach((element) => {
+    element.textContent = t(element.textContent.trim());
+  });
+  document.querySelectorAll('[data-t-placeholder]').forEach((element) => {
+    element.placeholder = t(element.placeholder);
+  });
+}